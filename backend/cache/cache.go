@@ -1919,6 +1919,16 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 	return do(ctx)
 }
 
+// ConnectionLimit returns the number of free connections to the
+// wrapped remote or -1 if it doesn't limit them.
+func (f *Fs) ConnectionLimit(ctx context.Context) int {
+	do := f.Fs.Features().ConnectionLimit
+	if do == nil {
+		return -1
+	}
+	return do(ctx)
+}
+
 var commandHelp = []fs.CommandHelp{
 	{
 		Name:  "stats",
@@ -1946,22 +1956,23 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs             = (*Fs)(nil)
-	_ fs.Purger         = (*Fs)(nil)
-	_ fs.Copier         = (*Fs)(nil)
-	_ fs.Mover          = (*Fs)(nil)
-	_ fs.DirMover       = (*Fs)(nil)
-	_ fs.PutUncheckeder = (*Fs)(nil)
-	_ fs.PutStreamer    = (*Fs)(nil)
-	_ fs.CleanUpper     = (*Fs)(nil)
-	_ fs.UnWrapper      = (*Fs)(nil)
-	_ fs.Wrapper        = (*Fs)(nil)
-	_ fs.ListRer        = (*Fs)(nil)
-	_ fs.ChangeNotifier = (*Fs)(nil)
-	_ fs.Abouter        = (*Fs)(nil)
-	_ fs.UserInfoer     = (*Fs)(nil)
-	_ fs.Disconnecter   = (*Fs)(nil)
-	_ fs.Commander      = (*Fs)(nil)
-	_ fs.MergeDirser    = (*Fs)(nil)
-	_ fs.Shutdowner     = (*Fs)(nil)
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.PutUncheckeder    = (*Fs)(nil)
+	_ fs.PutStreamer       = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+	_ fs.Wrapper           = (*Fs)(nil)
+	_ fs.ListRer           = (*Fs)(nil)
+	_ fs.ChangeNotifier    = (*Fs)(nil)
+	_ fs.Abouter           = (*Fs)(nil)
+	_ fs.UserInfoer        = (*Fs)(nil)
+	_ fs.Disconnecter      = (*Fs)(nil)
+	_ fs.Commander         = (*Fs)(nil)
+	_ fs.MergeDirser       = (*Fs)(nil)
+	_ fs.Shutdowner        = (*Fs)(nil)
+	_ fs.ConnectionLimiter = (*Fs)(nil)
 )
@@ -1211,6 +1211,16 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 	return do(ctx)
 }
 
+// ConnectionLimit returns the number of free connections to the
+// wrapped remote or -1 if it doesn't limit them.
+func (f *Fs) ConnectionLimit(ctx context.Context) int {
+	do := f.Fs.Features().ConnectionLimit
+	if do == nil {
+		return -1
+	}
+	return do(ctx)
+}
+
 // This loads the metadata of a press Object if it's not loaded yet
 func (o *Object) loadMetadataIfNotLoaded(ctx context.Context) (err error) {
 	err = o.loadMetadataObjectIfNotLoaded(ctx)
@@ -1524,24 +1534,25 @@ func (f *Fs) Precision() time.Duration {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.DirSetModTimer  = (*Fs)(nil)
-	_ fs.MkdirMetadataer = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.UnWrapper       = (*Fs)(nil)
-	_ fs.ListRer         = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Wrapper         = (*Fs)(nil)
-	_ fs.MergeDirser     = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.ChangeNotifier  = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.Shutdowner      = (*Fs)(nil)
-	_ fs.FullObjectInfo  = (*ObjectInfo)(nil)
-	_ fs.FullObject      = (*Object)(nil)
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.DirSetModTimer    = (*Fs)(nil)
+	_ fs.MkdirMetadataer   = (*Fs)(nil)
+	_ fs.PutStreamer       = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+	_ fs.ListRer           = (*Fs)(nil)
+	_ fs.Abouter           = (*Fs)(nil)
+	_ fs.Wrapper           = (*Fs)(nil)
+	_ fs.MergeDirser       = (*Fs)(nil)
+	_ fs.DirCacheFlusher   = (*Fs)(nil)
+	_ fs.ChangeNotifier    = (*Fs)(nil)
+	_ fs.PublicLinker      = (*Fs)(nil)
+	_ fs.Shutdowner        = (*Fs)(nil)
+	_ fs.ConnectionLimiter = (*Fs)(nil)
+	_ fs.FullObjectInfo    = (*ObjectInfo)(nil)
+	_ fs.FullObject        = (*Object)(nil)
 )
@@ -658,6 +658,15 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 	return f.drainPool(ctx)
 }
 
+// ConnectionLimit returns the number of connections which are free
+// under --ftp-concurrency or -1 if it isn't set
+func (f *Fs) ConnectionLimit(ctx context.Context) int {
+	if f.opt.Concurrency <= 0 {
+		return -1
+	}
+	return f.tokens.Free()
+}
+
 // translateErrorFile turns FTP errors into rclone errors if possible for a file
 func translateErrorFile(err error) error {
 	if errX := textprotoError(err); errX != nil {
@@ -1337,10 +1346,11 @@ func (o *Object) Remove(ctx context.Context) (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Shutdowner  = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs                = &Fs{}
+	_ fs.Mover             = &Fs{}
+	_ fs.DirMover          = &Fs{}
+	_ fs.PutStreamer       = &Fs{}
+	_ fs.Shutdowner        = &Fs{}
+	_ fs.ConnectionLimiter = &Fs{}
+	_ fs.Object            = &Object{}
 )
@@ -1226,6 +1226,28 @@ func (o *Object) openTranslatedLink(offset, limit int64) (lrc io.ReadCloser, err
 	return readers.NewLimitedReadCloser(io.NopCloser(strings.NewReader(linkdst[offset:])), limit), nil
 }
 
+// OpenReaderAt opens the object for random reads with ReadAt
+//
+// This is used by multi-thread copies to copy local files with
+// pread/pwrite.
+func (o *Object) OpenReaderAt(ctx context.Context) (fs.ReaderAtCloser, error) {
+	if o.translatedLink {
+		return nil, fs.ErrorNotImplemented
+	}
+	return file.Open(o.path)
+}
+
+// ReopenWriterAt opens the existing object for random writes with
+// WriteAt without truncating it
+//
+// This is used to rewrite chunks of the object in place.
+func (o *Object) ReopenWriterAt(ctx context.Context) (fs.WriterAtCloser, error) {
+	if o.translatedLink {
+		return nil, fs.ErrorNotImplemented
+	}
+	return file.OpenFile(o.path, os.O_WRONLY, 0666)
+}
+
 // Open an object for read
 func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	var offset, limit int64 = 0, -1
@@ -1631,18 +1653,20 @@ func (d *Directory) Hash() {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.Mover           = &Fs{}
-	_ fs.DirMover        = &Fs{}
-	_ fs.Commander       = &Fs{}
-	_ fs.OpenWriterAter  = &Fs{}
-	_ fs.DirSetModTimer  = &Fs{}
-	_ fs.MkdirMetadataer = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.Metadataer      = &Object{}
-	_ fs.SetMetadataer   = &Object{}
-	_ fs.Directory       = &Directory{}
-	_ fs.SetModTimer     = &Directory{}
-	_ fs.SetMetadataer   = &Directory{}
+	_ fs.Fs               = &Fs{}
+	_ fs.PutStreamer      = &Fs{}
+	_ fs.Mover            = &Fs{}
+	_ fs.DirMover         = &Fs{}
+	_ fs.Commander        = &Fs{}
+	_ fs.OpenWriterAter   = &Fs{}
+	_ fs.DirSetModTimer   = &Fs{}
+	_ fs.MkdirMetadataer  = &Fs{}
+	_ fs.Object           = &Object{}
+	_ fs.Metadataer       = &Object{}
+	_ fs.SetMetadataer    = &Object{}
+	_ fs.OpenReaderAter   = &Object{}
+	_ fs.ReopenWriterAter = &Object{}
+	_ fs.Directory        = &Directory{}
+	_ fs.SetModTimer      = &Directory{}
+	_ fs.SetMetadataer    = &Directory{}
 )
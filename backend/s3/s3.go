@@ -5775,27 +5775,39 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 	var mReq s3.CreateMultipartUploadInput
 	setFrom_s3CreateMultipartUploadInput_s3PutObjectInput(&mReq, ui.req)
 
-	uploadParts := f.opt.MaxUploadParts
-	if uploadParts < 1 {
-		uploadParts = 1
-	} else if uploadParts > maxUploadParts {
-		uploadParts = maxUploadParts
+	info, err = f.PlanChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, nil, err
 	}
 	size := src.Size()
 
-	// calculate size of parts
-	chunkSize := f.opt.ChunkSize
+	chunkWriter := &s3ChunkWriter{
+		chunkSize:            info.ChunkSize,
+		size:                 size,
+		f:                    f,
+		bucket:               mReq.Bucket,
+		key:                  mReq.Key,
+		multiPartUploadInput: &mReq,
+		completedParts:       make([]*s3.CompletedPart, 0),
+		ui:                   ui,
+		o:                    o,
+	}
 
-	// size can be -1 here meaning we don't know the size of the incoming file. We use ChunkSize
-	// buffers here (default 5 MiB). With a maximum number of parts (10,000) this will be a file of
-	// 48 GiB which seems like a not too unreasonable limit.
-	if size == -1 {
-		warnStreamUpload.Do(func() {
-			fs.Logf(f, "Streaming uploads using chunk size %v will have maximum file size of %v",
-				f.opt.ChunkSize, fs.SizeSuffix(int64(chunkSize)*int64(uploadParts)))
-		})
-	} else {
-		chunkSize = chunksize.Calculator(src, size, uploadParts, chunkSize)
+	// Carry on with the upload asked for if it still exists
+	for _, option := range options {
+		if resume, ok := option.(*fs.ResumeUploadOption); ok && size >= 0 {
+			parts, chunks, err := chunkWriter.uploadedParts(ctx, resume.UploadID)
+			if err != nil {
+				fs.Debugf(o, "open chunk writer: starting a new multipart upload as can't resume %q: %v", resume.UploadID, err)
+				break
+			}
+			chunkWriter.uploadID = aws.String(resume.UploadID)
+			chunkWriter.completedParts = parts
+			info.UploadID = resume.UploadID
+			info.CompletedChunks = chunks
+			fs.Debugf(o, "open chunk writer: resumed multipart upload %v with %d parts already uploaded", resume.UploadID, len(parts))
+			return info, chunkWriter, nil
+		}
 	}
 
 	var mOut *s3.CreateMultipartUploadOutput
@@ -5813,26 +5825,97 @@ func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectIn
 	if err != nil {
 		return info, nil, fmt.Errorf("create multipart upload failed: %w", err)
 	}
+	chunkWriter.bucket = mOut.Bucket
+	chunkWriter.key = mOut.Key
+	chunkWriter.uploadID = mOut.UploadId
+	info.UploadID = *mOut.UploadId
+	fs.Debugf(o, "open chunk writer: started multipart upload: %v", *mOut.UploadId)
+	return info, chunkWriter, err
+}
 
-	chunkWriter := &s3ChunkWriter{
-		chunkSize:            int64(chunkSize),
-		size:                 size,
-		f:                    f,
-		bucket:               mOut.Bucket,
-		key:                  mOut.Key,
-		uploadID:             mOut.UploadId,
-		multiPartUploadInput: &mReq,
-		completedParts:       make([]*s3.CompletedPart, 0),
-		ui:                   ui,
-		o:                    o,
+// uploadedParts lists the parts of the multipart upload uploadID which
+// can be kept when resuming it, those which are the size of the chunk
+// with their part number, and the chunk numbers of those parts.
+func (w *s3ChunkWriter) uploadedParts(ctx context.Context, uploadID string) (parts []*s3.CompletedPart, chunks []int, err error) {
+	req := s3.ListPartsInput{
+		Bucket:               w.bucket,
+		Key:                  w.key,
+		UploadId:             aws.String(uploadID),
+		RequestPayer:         w.multiPartUploadInput.RequestPayer,
+		SSECustomerAlgorithm: w.multiPartUploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       w.multiPartUploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    w.multiPartUploadInput.SSECustomerKeyMD5,
 	}
-	info = fs.ChunkWriterInfo{
-		ChunkSize:         int64(chunkSize),
-		Concurrency:       o.fs.opt.UploadConcurrency,
-		LeavePartsOnError: o.fs.opt.LeavePartsOnError,
+	for {
+		var resp *s3.ListPartsOutput
+		err = w.f.pacer.Call(func() (bool, error) {
+			resp, err = w.f.c.ListPartsWithContext(ctx, &req)
+			return w.f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list parts of multipart upload %q: %w", uploadID, err)
+		}
+		for _, part := range resp.Parts {
+			if part.PartNumber == nil || part.Size == nil || part.ETag == nil {
+				continue
+			}
+			chunk := int(*part.PartNumber - 1)
+			start := int64(chunk) * w.chunkSize
+			wantSize := w.chunkSize
+			if w.size-start < wantSize {
+				wantSize = w.size - start
+			}
+			if chunk < 0 || wantSize <= 0 || *part.Size != wantSize {
+				fs.Debugf(w.o, "multipart upload %q: ignoring part %d with size %d", uploadID, *part.PartNumber, *part.Size)
+				continue
+			}
+			parts = append(parts, &s3.CompletedPart{
+				PartNumber: part.PartNumber,
+				ETag:       part.ETag,
+			})
+			chunks = append(chunks, chunk)
+		}
+		if resp.IsTruncated == nil || !*resp.IsTruncated || resp.NextPartNumberMarker == nil {
+			break
+		}
+		req.PartNumberMarker = resp.NextPartNumberMarker
 	}
-	fs.Debugf(o, "open chunk writer: started multipart upload: %v", *mOut.UploadId)
-	return info, chunkWriter, err
+	return parts, chunks, nil
+}
+
+// PlanChunkWriter returns the ChunkWriterInfo OpenChunkWriter would
+// return without starting a multipart upload
+func (f *Fs) PlanChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, err error) {
+	uploadParts := f.opt.MaxUploadParts
+	if uploadParts < 1 {
+		uploadParts = 1
+	} else if uploadParts > maxUploadParts {
+		uploadParts = maxUploadParts
+	}
+	size := src.Size()
+
+	// calculate size of parts
+	chunkSize := f.opt.ChunkSize
+
+	// size can be -1 here meaning we don't know the size of the incoming file. We use ChunkSize
+	// buffers here (default 5 MiB). With a maximum number of parts (10,000) this will be a file of
+	// 48 GiB which seems like a not too unreasonable limit.
+	if size == -1 {
+		warnStreamUpload.Do(func() {
+			fs.Logf(f, "Streaming uploads using chunk size %v will have maximum file size of %v",
+				f.opt.ChunkSize, fs.SizeSuffix(int64(chunkSize)*int64(uploadParts)))
+		})
+	} else {
+		chunkSize = chunksize.Calculator(src, size, uploadParts, chunkSize)
+	}
+
+	return fs.ChunkWriterInfo{
+		ChunkSize:         int64(chunkSize),
+		Concurrency:       f.opt.UploadConcurrency,
+		LeavePartsOnError: f.opt.LeavePartsOnError,
+		// Put uploads files below --s3-upload-cutoff with a single PutObject
+		SingleShotUpload: size >= 0 && size < int64(f.opt.UploadCutoff),
+	}, nil
 }
 
 // add a part number and etag to the completed parts
@@ -6488,17 +6571,18 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.Purger          = &Fs{}
-	_ fs.Copier          = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.ListRer         = &Fs{}
-	_ fs.Commander       = &Fs{}
-	_ fs.CleanUpper      = &Fs{}
-	_ fs.OpenChunkWriter = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.MimeTyper       = &Object{}
-	_ fs.GetTierer       = &Object{}
-	_ fs.SetTierer       = &Object{}
-	_ fs.Metadataer      = &Object{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.Purger             = &Fs{}
+	_ fs.Copier             = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.ListRer            = &Fs{}
+	_ fs.Commander          = &Fs{}
+	_ fs.CleanUpper         = &Fs{}
+	_ fs.OpenChunkWriter    = &Fs{}
+	_ fs.ChunkWriterPlanner = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.MimeTyper          = &Object{}
+	_ fs.GetTierer          = &Object{}
+	_ fs.SetTierer          = &Object{}
+	_ fs.Metadataer         = &Object{}
 )
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"io"
 	"path"
 	"strings"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/bucket"
@@ -125,6 +127,64 @@ func (f *Fs) InternalTestNoHead(t *testing.T) {
 
 }
 
+func (f *Fs) InternalTestResumeUpload(t *testing.T) {
+	ctx := context.Background()
+	oldChunkSize := f.opt.ChunkSize
+	f.opt.ChunkSize = minChunkSize
+	defer func() {
+		f.opt.ChunkSize = oldChunkSize
+	}()
+	chunkSize := int64(minChunkSize)
+	contents := []byte(random.String(int(2*chunkSize + 100)))
+	item := fstest.NewItem("test-resume-upload", string(contents), fstest.Time("2001-05-06T04:05:06.499999999Z"))
+	src := object.NewStaticObjectInfo(item.Path, item.ModTime, int64(len(contents)), true, nil, nil)
+	chunk := func(n int) io.ReadSeeker {
+		end := int64(n+1) * chunkSize
+		if end > int64(len(contents)) {
+			end = int64(len(contents))
+		}
+		return bytes.NewReader(contents[int64(n)*chunkSize : end])
+	}
+
+	// Start an upload and write the first 2 chunks of 3
+	info, writer, err := f.OpenChunkWriter(ctx, item.Path, src)
+	require.NoError(t, err)
+	require.NotEqual(t, "", info.UploadID)
+	assert.Empty(t, info.CompletedChunks)
+	for n := 0; n < 2; n++ {
+		_, err = writer.WriteChunk(ctx, n, chunk(n))
+		require.NoError(t, err)
+	}
+
+	// Resuming it finds the chunks already written
+	resumed, writer, err := f.OpenChunkWriter(ctx, item.Path, src, &fs.ResumeUploadOption{UploadID: info.UploadID})
+	require.NoError(t, err)
+	assert.Equal(t, info.UploadID, resumed.UploadID)
+	assert.Equal(t, []int{0, 1}, resumed.CompletedChunks)
+	_, err = writer.WriteChunk(ctx, 2, chunk(2))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close(ctx))
+
+	obj, err := f.NewObject(ctx, item.Path)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, obj.Remove(ctx))
+	}()
+	in, err := obj.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, in.Close())
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(contents, got), "contents differ")
+
+	// An upload which no longer exists starts a new one
+	fresh, writer, err := f.OpenChunkWriter(ctx, item.Path, src, &fs.ResumeUploadOption{UploadID: info.UploadID})
+	require.NoError(t, err)
+	assert.NotEqual(t, info.UploadID, fresh.UploadID)
+	assert.Empty(t, fresh.CompletedChunks)
+	require.NoError(t, writer.Abort(ctx))
+}
+
 func TestVersionLess(t *testing.T) {
 	key1 := "key1"
 	key2 := "key2"
@@ -151,6 +211,32 @@ func TestVersionLess(t *testing.T) {
 	}
 }
 
+func TestPlanChunkWriter(t *testing.T) {
+	ctx := context.Background()
+	f := &Fs{opt: Options{
+		ChunkSize:         5 * fs.Mebi,
+		MaxUploadParts:    maxUploadParts,
+		UploadCutoff:      200 * fs.Mebi,
+		UploadConcurrency: 4,
+	}}
+	for _, test := range []struct {
+		size           int64
+		wantChunkSize  int64
+		wantSingleShot bool
+	}{
+		{size: 4 * int64(fs.Mebi), wantChunkSize: 5 * int64(fs.Mebi), wantSingleShot: true},
+		{size: 200 * int64(fs.Mebi), wantChunkSize: 5 * int64(fs.Mebi)},
+		{size: -1, wantChunkSize: 5 * int64(fs.Mebi)},
+	} {
+		src := object.NewStaticObjectInfo("file.txt", time.Now(), test.size, true, nil, nil)
+		info, err := f.PlanChunkWriter(ctx, "file.txt", src)
+		require.NoError(t, err)
+		assert.Equal(t, test.wantChunkSize, info.ChunkSize, test.size)
+		assert.Equal(t, 4, info.Concurrency, test.size)
+		assert.Equal(t, test.wantSingleShot, info.SingleShotUpload, test.size)
+	}
+}
+
 func TestMergeDeleteMarkers(t *testing.T) {
 	key1 := "key1"
 	key2 := "key2"
@@ -448,6 +534,7 @@ func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("Metadata", f.InternalTestMetadata)
 	t.Run("NoHead", f.InternalTestNoHead)
 	t.Run("Versions", f.InternalTestVersions)
+	t.Run("ResumeUpload", f.InternalTestResumeUpload)
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
@@ -1791,6 +1791,15 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 	return f.drainPool(ctx)
 }
 
+// ConnectionLimit returns the number of connections which are free
+// under --sftp-connections or -1 if it isn't set
+func (f *Fs) ConnectionLimit(ctx context.Context) int {
+	if f.opt.Connections <= 0 {
+		return -1
+	}
+	return f.tokens.Free()
+}
+
 // Fs is the filesystem this remote sftp file object is located within
 func (o *Object) Fs() fs.Info {
 	return o.fs
@@ -2219,13 +2228,14 @@ func (o *Object) Remove(ctx context.Context) error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs             = &Fs{}
-	_ fs.PutStreamer    = &Fs{}
-	_ fs.Mover          = &Fs{}
-	_ fs.Copier         = &Fs{}
-	_ fs.DirMover       = &Fs{}
-	_ fs.DirSetModTimer = &Fs{}
-	_ fs.Abouter        = &Fs{}
-	_ fs.Shutdowner     = &Fs{}
-	_ fs.Object         = &Object{}
+	_ fs.Fs                = &Fs{}
+	_ fs.PutStreamer       = &Fs{}
+	_ fs.Mover             = &Fs{}
+	_ fs.Copier            = &Fs{}
+	_ fs.DirMover          = &Fs{}
+	_ fs.DirSetModTimer    = &Fs{}
+	_ fs.Abouter           = &Fs{}
+	_ fs.Shutdowner        = &Fs{}
+	_ fs.ConnectionLimiter = &Fs{}
+	_ fs.Object            = &Object{}
 )
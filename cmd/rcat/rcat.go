@@ -52,6 +52,11 @@ and actually stream it, even if remote backend doesn't support streaming.
 size of the stream is different in length to the ` + "`--size`" + ` passed in
 then the transfer will likely fail.
 
+If ` + "`--size`" + ` is at least ` + "`--multi-thread-cutoff`" + ` and the remote
+supports multipart uploads then the input is read in chunks which are
+uploaded in parallel using ` + "`--multi-thread-streams`" + ` streams. One
+chunk per stream is held in memory while it is uploaded.
+
 Note that the upload cannot be retried because the data is not stored.
 If the backend supports multipart uploading then individual chunks can
 be retried. If you need to transfer a lot of data, you may be better
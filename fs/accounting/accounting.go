@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -61,6 +62,9 @@ type Account struct {
 	exit     chan struct{} // channel that will be closed when transfer is finished
 	withBuf  bool          // is using a buffered in
 	checking bool          // set if attached transfer is checking
+	label    string        // if set the bytes are also counted for this label
+
+	readAhead atomic.Int64 // size of the read-ahead buffer counted in the bytes in flight
 
 	tokenBucket buckets // per file bandwidth limiter (may be nil)
 
@@ -76,6 +80,9 @@ type accountValues struct {
 	lpTime  time.Time  // Time of last average measurement
 	lpBytes int        // Number of bytes read since last measurement
 	avg     float64    // Moving average of last few measurements in Byte/s
+
+	chunkETA   time.Duration // ETA estimated from completed chunks
+	chunkETAOK bool          // set if chunkETA is valid
 }
 
 const averagePeriod = 16 // period to do exponentially weighted averages over
@@ -102,6 +109,7 @@ func newAccountSizeName(ctx context.Context, stats *StatsInfo, in io.ReadCloser,
 	if acc.ci.CutoffMode == fs.CutoffModeHard {
 		acc.values.max = int64((acc.ci.MaxTransfer))
 	}
+	acc.label, _ = LabelFromContext(ctx)
 	currLimit := acc.ci.BwLimitFile.LimitAt(time.Now())
 	if currLimit.Bandwidth.IsSet() {
 		fs.Debugf(acc.name, "Limiting file transfer to %v", currLimit.Bandwidth)
@@ -134,11 +142,24 @@ func (acc *Account) WithBuffer() *Account {
 		} else {
 			acc.in = rc
 			acc.close = rc
+			// Count the read-ahead buffer with the other
+			// buffered bytes so memory use shows up in the stats
+			readAhead := int64(buffers) * asyncreader.BufferSize
+			acc.readAhead.Store(readAhead)
+			acc.stats.AddBytesInFlight(readAhead)
 		}
 	}
 	return acc
 }
 
+// releaseReadAhead removes the read-ahead buffer from the bytes in
+// flight. It is safe to call more than once.
+func (acc *Account) releaseReadAhead() {
+	if readAhead := acc.readAhead.Swap(0); readAhead != 0 {
+		acc.stats.AddBytesInFlight(-readAhead)
+	}
+}
+
 // HasBuffer - returns true if this Account has an AsyncReader with a buffer
 func (acc *Account) HasBuffer() bool {
 	acc.mu.Lock()
@@ -176,6 +197,7 @@ func (acc *Account) Abandon() {
 	if asyncIn, ok := acc.in.(*asyncreader.AsyncReader); ok {
 		asyncIn.Abandon()
 	}
+	acc.releaseReadAhead()
 }
 
 // UpdateReader updates the underlying io.ReadCloser stopping the
@@ -345,9 +367,15 @@ func (acc *Account) accountRead(n int) {
 	acc.values.mu.Unlock()
 
 	acc.stats.Bytes(int64(n))
+	if acc.label != "" {
+		acc.stats.LabelBytes(acc.label, int64(n))
+	}
 
 	TokenBucket.LimitBandwidth(TokenBucketSlotAccounting, n)
 	acc.limitPerFileBandwidth(n)
+	if acc.label != "" {
+		limitLabelBandwidth(acc.label, n)
+	}
 }
 
 // read bytes from the io.Reader passed in and account them
@@ -427,6 +455,7 @@ func (acc *Account) Close() error {
 		return nil
 	}
 	acc.closed = true
+	defer acc.releaseReadAhead()
 	if acc.close == nil {
 		return nil
 	}
@@ -439,6 +468,7 @@ func (acc *Account) Done() {
 	defer acc.mu.Unlock()
 	close(acc.exit)
 	acc.stats.inProgress.clear(acc.name)
+	acc.releaseReadAhead()
 }
 
 // progress returns bytes read as well as the size.
@@ -488,6 +518,32 @@ func (acc *Account) eta() (etaDuration time.Duration, ok bool) {
 	return eta(acc.values.bytes, acc.size, acc.values.avg)
 }
 
+// SetChunkETA sets the ETA of the transfer as estimated from the rate
+// of completion of its chunks by a multi-thread copy.
+//
+// This is reported in the stats for the transfer alongside the ETA
+// estimated from the transfer speed.
+func (acc *Account) SetChunkETA(eta time.Duration) {
+	if acc == nil {
+		return
+	}
+	acc.values.mu.Lock()
+	acc.values.chunkETA = eta
+	acc.values.chunkETAOK = true
+	acc.values.mu.Unlock()
+}
+
+// chunkETA returns the ETA set by SetChunkETA.
+// If it hasn't been set then 'ok' returns false.
+func (acc *Account) chunkETA() (etaDuration time.Duration, ok bool) {
+	if acc == nil {
+		return 0, false
+	}
+	acc.values.mu.Lock()
+	defer acc.values.mu.Unlock()
+	return acc.values.chunkETA, acc.values.chunkETAOK
+}
+
 // shortenName shortens in to size runes long
 // If size <= 0 then in is left untouched
 func shortenName(in string, size int) string {
@@ -554,6 +610,9 @@ func (acc *Account) rcStats(out rc.Params) {
 	} else {
 		out["eta"] = nil
 	}
+	if chunkETA, ok := acc.chunkETA(); ok {
+		out["chunkEta"] = chunkETA.Seconds()
+	}
 	out["name"] = acc.name
 
 	percentageDone := 0
@@ -7,11 +7,13 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/asyncreader"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -63,6 +65,45 @@ func TestAccountWithBuffer(t *testing.T) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountWithBufferBytesInFlight(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	readAhead := int64(ci.BufferSize) / asyncreader.BufferSize * asyncreader.BufferSize
+	require.NotZero(t, readAhead)
+	stats := NewStats(ctx)
+	newAcc := func() *Account {
+		in := io.NopCloser(bytes.NewBuffer([]byte{1}))
+		return newAccountSizeName(ctx, stats, in, -1, "test")
+	}
+
+	// The read-ahead buffer is counted once while it is open
+	acc := newAcc()
+	acc.WithBuffer()
+	assert.Equal(t, readAhead, stats.BytesInFlight())
+	acc.WithBuffer()
+	assert.Equal(t, readAhead, stats.BytesInFlight())
+	assert.NoError(t, acc.Close())
+	assert.Equal(t, int64(0), stats.BytesInFlight())
+	acc.Done()
+	assert.Equal(t, int64(0), stats.BytesInFlight())
+
+	// Abandoning it releases it
+	acc = newAcc()
+	acc.WithBuffer()
+	acc.Abandon()
+	assert.Equal(t, int64(0), stats.BytesInFlight())
+	assert.NoError(t, acc.Close())
+	assert.Equal(t, int64(0), stats.BytesInFlight())
+
+	// Replacing the reader swaps the buffer
+	acc = newAcc()
+	acc.WithBuffer()
+	acc.UpdateReader(ctx, io.NopCloser(bytes.NewBuffer([]byte{2})))
+	assert.Equal(t, readAhead, stats.BytesInFlight())
+	acc.Done()
+	assert.Equal(t, int64(0), stats.BytesInFlight())
+}
+
 func TestAccountGetUpdateReader(t *testing.T) {
 	ctx := context.Background()
 	test := func(doClose bool) func(t *testing.T) {
@@ -167,6 +208,55 @@ func testAccountWriteTo(t *testing.T, withBuffer bool) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountReadLabel(t *testing.T) {
+	ctx := context.Background()
+	label, ok := LabelFromContext(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, "", label)
+
+	ctx = WithLabel(ctx, "potato")
+	label, ok = LabelFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "potato", label)
+
+	in := io.NopCloser(bytes.NewBuffer([]byte{1, 2, 3}))
+	stats := NewStats(ctx)
+	acc := newAccountSizeName(ctx, stats, in, 3, "test")
+	_, err := io.ReadAll(acc)
+	require.NoError(t, err)
+	require.NoError(t, acc.AccountRead(2))
+	assert.Equal(t, int64(5), stats.GetBytes())
+	assert.Equal(t, map[string]int64{"potato": 5}, stats.GetLabelBytes())
+	require.NoError(t, acc.Close())
+}
+
+func TestAccountReadLabelBwLimit(t *testing.T) {
+	const label = "potato-limited"
+	ctx := WithLabel(context.Background(), label)
+	SetLabelBwLimit(label, 1024*1024)
+	defer SetLabelBwLimit(label, 0)
+
+	in := io.NopCloser(bytes.NewBuffer(make([]byte, 100*1024)))
+	stats := NewStats(ctx)
+	acc := newAccountSizeName(ctx, stats, in, 100*1024, "test")
+	start := time.Now()
+	_, err := io.ReadAll(acc)
+	require.NoError(t, err)
+	// 100 KiB at 1 MiB/s should take about 100ms
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.NoError(t, acc.Close())
+
+	// Once removed the limit doesn't apply
+	SetLabelBwLimit(label, 0)
+	in = io.NopCloser(bytes.NewBuffer(make([]byte, 100*1024)))
+	acc = newAccountSizeName(ctx, stats, in, 100*1024, "test")
+	start = time.Now()
+	_, err = io.ReadAll(acc)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	require.NoError(t, acc.Close())
+}
+
 func TestAccountWriteTo(t *testing.T) {
 	testAccountWriteTo(t, false)
 }
@@ -195,6 +285,25 @@ func TestAccountString(t *testing.T) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountChunkETA(t *testing.T) {
+	ctx := context.Background()
+	in := io.NopCloser(bytes.NewBuffer([]byte{1, 2, 3}))
+	stats := NewStats(ctx)
+	acc := newAccountSizeName(ctx, stats, in, 3, "test")
+
+	out := rc.Params{}
+	acc.rcStats(out)
+	_, found := out["chunkEta"]
+	assert.False(t, found)
+
+	acc.SetChunkETA(90 * time.Second)
+	out = rc.Params{}
+	acc.rcStats(out)
+	assert.Equal(t, 90.0, out["chunkEta"])
+
+	assert.NoError(t, acc.Close())
+}
+
 // Test the Accounter interface methods on Account and accountStream
 func TestAccountAccounter(t *testing.T) {
 	ctx := context.Background()
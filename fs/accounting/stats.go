@@ -62,6 +62,12 @@ type StatsInfo struct {
 	serverSideCopyBytes int64
 	serverSideMoves     int64
 	serverSideMoveBytes int64
+	bytesInFlight       int64            // bytes read from the source but not yet written
+	chunkRetries        int64            // times multi-thread copies retried reading a chunk
+	chunksTimed         int64            // buffered multi-thread chunks timed in chunkReadTime and chunkWriteTime
+	chunkReadTime       time.Duration    // time spent reading buffered multi-thread chunks from the source
+	chunkWriteTime      time.Duration    // time spent writing buffered multi-thread chunks to the destination
+	labelBytes          map[string]int64 // bytes transferred for each label set with WithLabel
 }
 
 type averageValues struct {
@@ -118,6 +124,18 @@ func (s *StatsInfo) RemoteStats() (out rc.Params, err error) {
 	out["serverSideCopyBytes"] = s.serverSideCopyBytes
 	out["serverSideMoves"] = s.serverSideMoves
 	out["serverSideMoveBytes"] = s.serverSideMoveBytes
+	out["bytesInFlight"] = s.bytesInFlight
+	out["multiThreadChunkRetries"] = s.chunkRetries
+	out["multiThreadChunksTimed"] = s.chunksTimed
+	out["multiThreadChunkReadTime"] = s.chunkReadTime.Seconds()
+	out["multiThreadChunkWriteTime"] = s.chunkWriteTime.Seconds()
+	if len(s.labelBytes) > 0 {
+		labels := make(map[string]int64, len(s.labelBytes))
+		for label, n := range s.labelBytes {
+			labels[label] = n
+		}
+		out["labels"] = labels
+	}
 	eta, etaOK := eta(s.bytes, ts.totalBytes, ts.speed)
 	if etaOK {
 		out["eta"] = eta.Seconds()
@@ -530,6 +548,81 @@ func (s *StatsInfo) Log() {
 
 }
 
+// AddBytesInFlight adds n to the number of bytes which have been read
+// from the source and are buffered but haven't been written to the
+// destination yet. Call it with a negative n when they are written.
+func (s *StatsInfo) AddBytesInFlight(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytesInFlight += n
+}
+
+// BytesInFlight returns the number of bytes which have been read from
+// the source but not yet written to the destination.
+func (s *StatsInfo) BytesInFlight() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bytesInFlight
+}
+
+// AddMultiThreadChunkRetries adds n to the number of times
+// multi-thread copies have had to retry reading a chunk.
+func (s *StatsInfo) AddMultiThreadChunkRetries(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunkRetries += n
+}
+
+// MultiThreadChunkRetries returns the number of times multi-thread
+// copies have had to retry reading a chunk.
+func (s *StatsInfo) MultiThreadChunkRetries() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chunkRetries
+}
+
+// AddMultiThreadChunkTimes records that a multi-thread copy took read
+// to read a chunk from the source into a buffer and write to write it
+// to the destination.
+func (s *StatsInfo) AddMultiThreadChunkTimes(read, write time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunksTimed++
+	s.chunkReadTime += read
+	s.chunkWriteTime += write
+}
+
+// MultiThreadChunkTimes returns the total time multi-thread copies
+// spent reading and writing the chunks recorded with
+// AddMultiThreadChunkTimes and how many chunks there were.
+func (s *StatsInfo) MultiThreadChunkTimes() (read, write time.Duration, chunks int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chunkReadTime, s.chunkWriteTime, s.chunksTimed
+}
+
+// LabelBytes adds n to the bytes transferred for label
+func (s *StatsInfo) LabelBytes(label string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.labelBytes == nil {
+		s.labelBytes = make(map[string]int64)
+	}
+	s.labelBytes[label] += n
+}
+
+// GetLabelBytes returns the bytes transferred for each label set
+// with WithLabel
+func (s *StatsInfo) GetLabelBytes() map[string]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	labels := make(map[string]int64, len(s.labelBytes))
+	for label, n := range s.labelBytes {
+		labels[label] = n
+	}
+	return labels
+}
+
 // Bytes updates the stats for bytes bytes
 func (s *StatsInfo) Bytes(bytes int64) {
 	s.average.mu.Lock()
@@ -693,6 +786,11 @@ func (s *StatsInfo) ResetCounters() {
 	s.deletesSize = 0
 	s.deletedDirs = 0
 	s.renames = 0
+	s.chunkRetries = 0
+	s.chunksTimed = 0
+	s.chunkReadTime = 0
+	s.chunkWriteTime = 0
+	s.labelBytes = nil
 	s.startedTransfers = nil
 	s.oldDuration = 0
 
@@ -88,13 +88,19 @@ Returns the following values:
 ` + "```" + `
 {
 	"bytes": total transferred bytes since the start of the group,
+	"bytesInFlight": bytes read from the source and buffered but not yet written to the destination, including read-ahead buffers,
 	"checks": number of files checked,
 	"deletes" : number of files deleted,
 	"elapsedTime": time in floating point seconds since rclone was started,
 	"errors": number of errors,
 	"eta": estimated time in seconds until the group completes,
 	"fatalError": boolean whether there has been at least one fatal error,
+	"labels": bytes transferred for each label set on the transfers, if any,
 	"lastError": last error string,
+	"multiThreadChunkRetries": number of times multi-thread copies retried reading a chunk,
+	"multiThreadChunkReadTime": time in floating point seconds multi-thread copies spent reading buffered chunks from the source,
+	"multiThreadChunkWriteTime": time in floating point seconds multi-thread copies spent writing buffered chunks to the destination,
+	"multiThreadChunksTimed": number of buffered chunks in multiThreadChunkReadTime and multiThreadChunkWriteTime,
 	"renames" : number of files renamed,
 	"retryError": boolean showing whether there has been at least one non-NoRetryError,
         "serverSideCopies": number of server side copies done,
@@ -111,6 +117,7 @@ Returns the following values:
 		[
 			{
 				"bytes": total transferred bytes for this file,
+				"chunkEta": estimated time in seconds until completion from the rate chunks are completing (multi-thread copies only),
 				"eta": estimated time in seconds until file transfer completion
 				"name": name of the file,
 				"percentage": progress of the file transfer in percent,
@@ -271,6 +278,30 @@ func StatsGroupFromContext(ctx context.Context) (string, bool) {
 	return statsGroup, ok
 }
 
+type labelCtx int64
+
+const labelKey labelCtx = 1
+
+// WithLabel returns a copy of the parent context which labels the
+// transfers made with it, for example with the tenant they are for.
+//
+// The bytes transferred are counted for each label as well as in
+// total and shown in "labels" in the stats, and limited by any
+// bandwidth limit set for the label with SetLabelBwLimit.
+func WithLabel(parent context.Context, label string) context.Context {
+	return context.WithValue(parent, labelKey, label)
+}
+
+// LabelFromContext returns the label set with WithLabel if it's
+// available. Returns false if the label is empty.
+func LabelFromContext(ctx context.Context) (string, bool) {
+	label, ok := ctx.Value(labelKey).(string)
+	if label == "" {
+		ok = false
+	}
+	return label, ok
+}
+
 // Stats gets stats by extracting group from context.
 func Stats(ctx context.Context) *StatsInfo {
 	group, ok := StatsGroupFromContext(ctx)
@@ -388,6 +419,17 @@ func (sg *statsGroups) sum(ctx context.Context) *StatsInfo {
 			sum.renameQueueSize += stats.renameQueueSize
 			sum.deletes += stats.deletes
 			sum.deletedDirs += stats.deletedDirs
+			sum.bytesInFlight += stats.bytesInFlight
+			sum.chunkRetries += stats.chunkRetries
+			sum.chunksTimed += stats.chunksTimed
+			sum.chunkReadTime += stats.chunkReadTime
+			sum.chunkWriteTime += stats.chunkWriteTime
+			for label, n := range stats.labelBytes {
+				if sum.labelBytes == nil {
+					sum.labelBytes = make(map[string]int64)
+				}
+				sum.labelBytes[label] += n
+			}
 			sum.inProgress.merge(stats.inProgress)
 			sum.startedTransfers = append(sum.startedTransfers, stats.startedTransfers...)
 			sum.oldTimeRanges = append(sum.oldTimeRanges, stats.oldTimeRanges...)
@@ -51,11 +51,21 @@ func TestStatsGroupOperations(t *testing.T) {
 		stats1.bytes = 5
 		stats1.transferQueueSize = 10
 		stats1.errors = 6
+		stats1.bytesInFlight = 7
+		stats1.chunkRetries = 1
+		stats1.chunksTimed = 2
+		stats1.chunkReadTime = time.Second
+		stats1.chunkWriteTime = 2 * time.Second
 		stats1.oldDuration = time.Second
 		stats1.oldTimeRanges = []timeRange{{time.Now(), time.Now().Add(time.Second)}}
 		stats2 := NewStats(ctx)
 		stats2.bytes = 10
 		stats2.errors = 12
+		stats2.bytesInFlight = 3
+		stats2.chunkRetries = 4
+		stats2.chunksTimed = 3
+		stats2.chunkReadTime = 3 * time.Second
+		stats2.chunkWriteTime = 4 * time.Second
 		stats1.transferQueueSize = 20
 		stats2.oldDuration = 2 * time.Second
 		stats2.oldTimeRanges = []timeRange{{time.Now(), time.Now().Add(2 * time.Second)}}
@@ -66,6 +76,11 @@ func TestStatsGroupOperations(t *testing.T) {
 		assert.Equal(t, stats1.bytes+stats2.bytes, sum.bytes)
 		assert.Equal(t, stats1.transferQueueSize+stats2.transferQueueSize, sum.transferQueueSize)
 		assert.Equal(t, stats1.errors+stats2.errors, sum.errors)
+		assert.Equal(t, stats1.bytesInFlight+stats2.bytesInFlight, sum.bytesInFlight)
+		assert.Equal(t, stats1.chunkRetries+stats2.chunkRetries, sum.chunkRetries)
+		assert.Equal(t, stats1.chunksTimed+stats2.chunksTimed, sum.chunksTimed)
+		assert.Equal(t, stats1.chunkReadTime+stats2.chunkReadTime, sum.chunkReadTime)
+		assert.Equal(t, stats1.chunkWriteTime+stats2.chunkWriteTime, sum.chunkWriteTime)
 		assert.Equal(t, stats1.oldDuration+stats2.oldDuration, sum.oldDuration)
 		assert.Equal(t, stats1.average.speed+stats2.average.speed, sum.average.speed)
 		// dict can iterate in either order
@@ -271,6 +271,70 @@ func TestRemoteStats(t *testing.T) {
 		assert.Equal(t, float64(10), rs["transferTime"])
 		assert.Greater(t, rs["elapsedTime"], float64(0))
 	})
+
+	t.Run("Bytes in flight", func(t *testing.T) {
+		s := NewStats(ctx)
+		s.AddBytesInFlight(100)
+		s.AddBytesInFlight(50)
+		s.AddBytesInFlight(-100)
+		assert.Equal(t, int64(50), s.BytesInFlight())
+		rs, err := s.RemoteStats()
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), rs["bytesInFlight"])
+	})
+
+	t.Run("Multi-thread chunk retries", func(t *testing.T) {
+		s := NewStats(ctx)
+		s.AddMultiThreadChunkRetries(2)
+		s.AddMultiThreadChunkRetries(3)
+		assert.Equal(t, int64(5), s.MultiThreadChunkRetries())
+		rs, err := s.RemoteStats()
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), rs["multiThreadChunkRetries"])
+
+		s.ResetCounters()
+		assert.Equal(t, int64(0), s.MultiThreadChunkRetries())
+	})
+
+	t.Run("Multi-thread chunk times", func(t *testing.T) {
+		s := NewStats(ctx)
+		s.AddMultiThreadChunkTimes(time.Second, 3*time.Second)
+		s.AddMultiThreadChunkTimes(2*time.Second, 500*time.Millisecond)
+		read, write, chunks := s.MultiThreadChunkTimes()
+		assert.Equal(t, 3*time.Second, read)
+		assert.Equal(t, 3500*time.Millisecond, write)
+		assert.Equal(t, int64(2), chunks)
+		rs, err := s.RemoteStats()
+
+		require.NoError(t, err)
+		assert.Equal(t, float64(3), rs["multiThreadChunkReadTime"])
+		assert.Equal(t, 3.5, rs["multiThreadChunkWriteTime"])
+		assert.Equal(t, int64(2), rs["multiThreadChunksTimed"])
+
+		s.ResetCounters()
+		_, _, chunks = s.MultiThreadChunkTimes()
+		assert.Equal(t, int64(0), chunks)
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		s := NewStats(ctx)
+		rs, err := s.RemoteStats()
+		require.NoError(t, err)
+		assert.NotContains(t, rs, "labels")
+
+		s.LabelBytes("potato", 10)
+		s.LabelBytes("sausage", 5)
+		s.LabelBytes("potato", 1)
+		assert.Equal(t, map[string]int64{"potato": 11, "sausage": 5}, s.GetLabelBytes())
+		rs, err = s.RemoteStats()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"potato": 11, "sausage": 5}, rs["labels"])
+
+		s.ResetCounters()
+		assert.Equal(t, map[string]int64{}, s.GetLabelBytes())
+	})
 }
 
 // make time ranges from string description for testing
@@ -189,6 +189,47 @@ func (tb *tokenBucket) SetBwLimit(bandwidth fs.BwPair) {
 	}
 }
 
+// labelBuckets holds the bandwidth limits set for labels with
+// SetLabelBwLimit
+var labelBuckets struct {
+	mu       sync.RWMutex
+	limiters map[string]*rate.Limiter
+}
+
+// SetLabelBwLimit limits the bandwidth of the transfers labelled with
+// label by WithLabel to bandwidth bytes/s, as well as any other
+// bandwidth limits. All the transfers with the label share the limit.
+//
+// A bandwidth of 0 removes the limit.
+func SetLabelBwLimit(label string, bandwidth fs.SizeSuffix) {
+	labelBuckets.mu.Lock()
+	defer labelBuckets.mu.Unlock()
+	if bandwidth <= 0 {
+		delete(labelBuckets.limiters, label)
+		fs.Debugf(nil, "Bandwidth limit for label %q reset to unlimited", label)
+		return
+	}
+	if labelBuckets.limiters == nil {
+		labelBuckets.limiters = make(map[string]*rate.Limiter)
+	}
+	labelBuckets.limiters[label] = newEmptyTokenBucket(bandwidth)
+	fs.Debugf(nil, "Bandwidth limit for label %q set to %v", label, bandwidth)
+}
+
+// limitLabelBandwidth sleeps for the correct amount of time for the
+// passage of n bytes according to the bandwidth limit of label if any
+func limitLabelBandwidth(label string, n int) {
+	labelBuckets.mu.RLock()
+	tb := labelBuckets.limiters[label]
+	labelBuckets.mu.RUnlock()
+	if tb != nil {
+		err := tb.WaitN(context.Background(), n)
+		if err != nil {
+			fs.Errorf(nil, "Token bucket error for label %q: %v", label, err)
+		}
+	}
+}
+
 // read and set the bandwidth limits
 func (tb *tokenBucket) rcBwlimit(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	if in["rate"] != nil {
@@ -3,6 +3,7 @@ package accounting
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"sync"
 	"time"
@@ -23,6 +24,9 @@ type TransferSnapshot struct {
 	Group       string    `json:"group"`
 	SrcFs       string    `json:"srcFs,omitempty"`
 	DstFs       string    `json:"dstFs,omitempty"`
+	Streams     int       `json:"streams,omitempty"`
+	PeakStreams int       `json:"peakStreams,omitempty"`
+	WriteMethod string    `json:"writeMethod,omitempty"`
 }
 
 // MarshalJSON implements json.Marshaler interface.
@@ -65,6 +69,9 @@ type Transfer struct {
 	acc         *Account
 	err         error
 	completedAt time.Time
+	streams     int    // number of streams used by a multi-thread transfer
+	peakStreams int    // most chunks a multi-thread transfer had in flight at once
+	writeMethod string // how a multi-thread transfer wrote the destination
 }
 
 // newCheckingTransfer instantiates new checking of the object.
@@ -121,8 +128,22 @@ func (tr *Transfer) Done(ctx context.Context, err error) {
 
 	tr.mu.Lock()
 	tr.completedAt = time.Now()
+	streams := tr.streams
+	peakStreams := tr.peakStreams
+	writeMethod := tr.writeMethod
 	tr.mu.Unlock()
 
+	if streams > 0 && err == nil {
+		how := ""
+		if writeMethod != "" {
+			how = " using " + writeMethod
+		}
+		if peakStreams > 0 {
+			how += fmt.Sprintf(" (peak %d in flight)", peakStreams)
+		}
+		fs.LogLevelPrintf(ci.StatsLogLevel, nil, "%s: copied with %d streams%s", tr.remote, streams, how)
+	}
+
 	if tr.checking {
 		tr.stats.DoneChecking(tr.remote)
 	} else {
@@ -160,6 +181,55 @@ func (tr *Transfer) Account(ctx context.Context, in io.ReadCloser) *Account {
 	return tr.acc
 }
 
+// SetStreams records the number of streams a multi-thread transfer
+// is using.
+func (tr *Transfer) SetStreams(streams int) {
+	tr.mu.Lock()
+	tr.streams = streams
+	tr.mu.Unlock()
+}
+
+// Streams returns the number of streams set with SetStreams or 0 if
+// this isn't a multi-thread transfer.
+func (tr *Transfer) Streams() int {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.streams
+}
+
+// SetPeakStreams records the most chunks a multi-thread transfer had
+// in flight at once. If this is less than Streams then the transfer
+// didn't use all the streams it was given.
+func (tr *Transfer) SetPeakStreams(peakStreams int) {
+	tr.mu.Lock()
+	tr.peakStreams = peakStreams
+	tr.mu.Unlock()
+}
+
+// PeakStreams returns the value set with SetPeakStreams or 0 if it
+// hasn't been set.
+func (tr *Transfer) PeakStreams() int {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.peakStreams
+}
+
+// SetWriteMethod records how a multi-thread transfer is writing the
+// destination, eg "OpenChunkWriter" or "OpenWriterAt".
+func (tr *Transfer) SetWriteMethod(writeMethod string) {
+	tr.mu.Lock()
+	tr.writeMethod = writeMethod
+	tr.mu.Unlock()
+}
+
+// WriteMethod returns the write method set with SetWriteMethod or ""
+// if this isn't a multi-thread transfer.
+func (tr *Transfer) WriteMethod() string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.writeMethod
+}
+
 // TimeRange returns the time transfer started and ended at. If not completed
 // it will return zero time for end time.
 func (tr *Transfer) TimeRange() (time.Time, time.Time) {
@@ -193,6 +263,9 @@ func (tr *Transfer) Snapshot() TransferSnapshot {
 		CompletedAt: tr.completedAt,
 		Error:       tr.err,
 		Group:       tr.stats.group,
+		Streams:     tr.streams,
+		PeakStreams: tr.peakStreams,
+		WriteMethod: tr.writeMethod,
 	}
 	if tr.srcFs != nil {
 		snapshot.SrcFs = fs.ConfigString(tr.srcFs)
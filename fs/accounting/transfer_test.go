@@ -37,6 +37,27 @@ func TestTransfer(t *testing.T) {
 		assert.Equal(t, "", snap.Group)
 		assert.Equal(t, "srcFs:srcFs", snap.SrcFs)
 		assert.Equal(t, "dstFs:dstFs", snap.DstFs)
+		assert.Equal(t, 0, snap.Streams)
+		assert.Equal(t, "", snap.WriteMethod)
+	})
+
+	t.Run("SetStreams", func(t *testing.T) {
+		tr.SetStreams(4)
+		assert.Equal(t, 4, tr.Streams())
+		assert.Equal(t, 4, tr.Snapshot().Streams)
+	})
+
+	t.Run("SetPeakStreams", func(t *testing.T) {
+		assert.Equal(t, 0, tr.Snapshot().PeakStreams)
+		tr.SetPeakStreams(3)
+		assert.Equal(t, 3, tr.PeakStreams())
+		assert.Equal(t, 3, tr.Snapshot().PeakStreams)
+	})
+
+	t.Run("SetWriteMethod", func(t *testing.T) {
+		tr.SetWriteMethod("OpenWriterAt")
+		assert.Equal(t, "OpenWriterAt", tr.WriteMethod())
+		assert.Equal(t, "OpenWriterAt", tr.Snapshot().WriteMethod)
 	})
 
 	t.Run("Done", func(t *testing.T) {
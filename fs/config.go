@@ -51,109 +51,150 @@ var (
 
 // ConfigInfo is filesystem config options
 type ConfigInfo struct {
-	LogLevel                   LogLevel
-	StatsLogLevel              LogLevel
-	UseJSONLog                 bool
-	DryRun                     bool
-	Interactive                bool
-	CheckSum                   bool
-	SizeOnly                   bool
-	IgnoreTimes                bool
-	IgnoreExisting             bool
-	IgnoreErrors               bool
-	ModifyWindow               time.Duration
-	Checkers                   int
-	Transfers                  int
-	ConnectTimeout             time.Duration // Connect timeout
-	Timeout                    time.Duration // Data channel timeout
-	ExpectContinueTimeout      time.Duration
-	Dump                       DumpFlags
-	InsecureSkipVerify         bool // Skip server certificate verification
-	DeleteMode                 DeleteMode
-	MaxDelete                  int64
-	MaxDeleteSize              SizeSuffix
-	TrackRenames               bool          // Track file renames.
-	TrackRenamesStrategy       string        // Comma separated list of strategies used to track renames
-	Retries                    int           // High-level retries
-	RetriesInterval            time.Duration // --retries-sleep
-	LowLevelRetries            int
-	UpdateOlder                bool // Skip files that are newer on the destination
-	NoGzip                     bool // Disable compression
-	MaxDepth                   int
-	IgnoreSize                 bool
-	IgnoreChecksum             bool
-	IgnoreCaseSync             bool
-	FixCase                    bool
-	NoTraverse                 bool
-	CheckFirst                 bool
-	NoCheckDest                bool
-	NoUnicodeNormalization     bool
-	NoUpdateModTime            bool
-	NoUpdateDirModTime         bool
-	DataRateUnit               string
-	CompareDest                []string
-	CopyDest                   []string
-	BackupDir                  string
-	Suffix                     string
-	SuffixKeepExtension        bool
-	UseListR                   bool
-	BufferSize                 SizeSuffix
-	BwLimit                    BwTimetable
-	BwLimitFile                BwTimetable
-	TPSLimit                   float64
-	TPSLimitBurst              int
-	BindAddr                   net.IP
-	DisableFeatures            []string
-	UserAgent                  string
-	Immutable                  bool
-	AutoConfirm                bool
-	StreamingUploadCutoff      SizeSuffix
-	StatsFileNameLength        int
-	AskPassword                bool
-	PasswordCommand            SpaceSepList
-	UseServerModTime           bool
-	MaxTransfer                SizeSuffix
-	MaxDuration                time.Duration
-	CutoffMode                 CutoffMode
-	MaxBacklog                 int
-	MaxStatsGroups             int
-	StatsOneLine               bool
-	StatsOneLineDate           bool   // If we want a date prefix at all
-	StatsOneLineDateFormat     string // If we want to customize the prefix
-	ErrorOnNoTransfer          bool   // Set appropriate exit code if no files transferred
-	Progress                   bool
-	ProgressTerminalTitle      bool
-	Cookie                     bool
-	UseMmap                    bool
-	CaCert                     []string // Client Side CA
-	ClientCert                 string   // Client Side Cert
-	ClientKey                  string   // Client Side Key
-	MultiThreadCutoff          SizeSuffix
-	MultiThreadStreams         int
-	MultiThreadSet             bool       // whether MultiThreadStreams was set (set in fs/config/configflags)
-	MultiThreadChunkSize       SizeSuffix // Chunk size for multi-thread downloads / uploads, if not set by filesystem
-	MultiThreadWriteBufferSize SizeSuffix
-	OrderBy                    string // instructions on how to order the transfer
-	UploadHeaders              []*HTTPOption
-	DownloadHeaders            []*HTTPOption
-	Headers                    []*HTTPOption
-	MetadataSet                Metadata // extra metadata to write when uploading
-	RefreshTimes               bool
-	NoConsole                  bool
-	TrafficClass               uint8
-	FsCacheExpireDuration      time.Duration
-	FsCacheExpireInterval      time.Duration
-	DisableHTTP2               bool
-	HumanReadable              bool
-	KvLockTime                 time.Duration // maximum time to keep key-value database locked by process
-	DisableHTTPKeepAlives      bool
-	Metadata                   bool
-	ServerSideAcrossConfigs    bool
-	TerminalColorMode          TerminalColorMode
-	DefaultTime                Time // time that directories with no time should display
-	Inplace                    bool // Download directly to destination file instead of atomic download to temp/rename
-	PartialSuffix              string
-	MetadataMapper             SpaceSepList
+	LogLevel                    LogLevel
+	StatsLogLevel               LogLevel
+	UseJSONLog                  bool
+	DryRun                      bool
+	Interactive                 bool
+	CheckSum                    bool
+	SizeOnly                    bool
+	IgnoreTimes                 bool
+	IgnoreExisting              bool
+	IgnoreErrors                bool
+	ModifyWindow                time.Duration
+	Checkers                    int
+	Transfers                   int
+	ConnectTimeout              time.Duration // Connect timeout
+	Timeout                     time.Duration // Data channel timeout
+	ExpectContinueTimeout       time.Duration
+	Dump                        DumpFlags
+	InsecureSkipVerify          bool // Skip server certificate verification
+	DeleteMode                  DeleteMode
+	MaxDelete                   int64
+	MaxDeleteSize               SizeSuffix
+	TrackRenames                bool          // Track file renames.
+	TrackRenamesStrategy        string        // Comma separated list of strategies used to track renames
+	Retries                     int           // High-level retries
+	RetriesInterval             time.Duration // --retries-sleep
+	LowLevelRetries             int
+	UpdateOlder                 bool // Skip files that are newer on the destination
+	NoGzip                      bool // Disable compression
+	MaxDepth                    int
+	IgnoreSize                  bool
+	IgnoreChecksum              bool
+	IgnoreCaseSync              bool
+	FixCase                     bool
+	NoTraverse                  bool
+	CheckFirst                  bool
+	NoCheckDest                 bool
+	NoUnicodeNormalization      bool
+	NoUpdateModTime             bool
+	NoUpdateDirModTime          bool
+	DataRateUnit                string
+	CompareDest                 []string
+	CopyDest                    []string
+	BackupDir                   string
+	Suffix                      string
+	SuffixKeepExtension         bool
+	UseListR                    bool
+	BufferSize                  SizeSuffix
+	BwLimit                     BwTimetable
+	BwLimitFile                 BwTimetable
+	TPSLimit                    float64
+	TPSLimitBurst               int
+	BindAddr                    net.IP
+	DisableFeatures             []string
+	UserAgent                   string
+	Immutable                   bool
+	AutoConfirm                 bool
+	StreamingUploadCutoff       SizeSuffix
+	StatsFileNameLength         int
+	AskPassword                 bool
+	PasswordCommand             SpaceSepList
+	UseServerModTime            bool
+	MaxTransfer                 SizeSuffix
+	MaxDuration                 time.Duration
+	CutoffMode                  CutoffMode
+	MaxBacklog                  int
+	MaxStatsGroups              int
+	StatsOneLine                bool
+	StatsOneLineDate            bool   // If we want a date prefix at all
+	StatsOneLineDateFormat      string // If we want to customize the prefix
+	ErrorOnNoTransfer           bool   // Set appropriate exit code if no files transferred
+	Progress                    bool
+	ProgressTerminalTitle       bool
+	Cookie                      bool
+	UseMmap                     bool
+	CaCert                      []string // Client Side CA
+	ClientCert                  string   // Client Side Cert
+	ClientKey                   string   // Client Side Key
+	MultiThreadCutoff           SizeSuffix
+	MultiThreadCutoffAuto       bool     // estimate the cutoff from measured transfers instead of using MultiThreadCutoff
+	MultiThreadInclude          []string // name or MIME type patterns of objects to multi-thread whatever their size
+	MultiThreadExclude          []string // name or MIME type patterns of objects never to multi-thread
+	MultiThreadDisableKey       string   // metadata key which stops an object being copied with multi-thread
+	MultiThreadStreams          int
+	MultiThreadStreamsAuto      bool       // choose the number of streams of each multi-thread copy from the bandwidth-delay product
+	MultiThreadStreamsStrict    bool       // if set always use MultiThreadStreams when set instead of a higher backend concurrency
+	MultiThreadStreamWindow     SizeSuffix // if set the bytes each stream keeps in flight for MultiThreadStreamsAuto instead of measuring it
+	MultiThreadSet              bool       // whether MultiThreadStreams was set (set in fs/config/configflags)
+	MultiThreadReadStreams      int        // if set the number of chunks multi-thread copies read at once instead of MultiThreadStreams
+	MultiThreadWriteStreams     int        // if set the number of chunks multi-thread copies write at once instead of MultiThreadStreams
+	MultiThreadTotalStreams     int        // if set the most streams all the multi-thread copies use between them
+	MultiThreadMaxGoroutines    int        // if set the most chunk copying goroutines all the multi-thread copies start between them
+	MultiThreadMaxInflightBytes SizeSuffix // if set the most bytes of chunks each multi-thread copy has in flight at once
+	MultiThreadMaxReadChunk     SizeSuffix // if set the most of each chunk multi-thread copies buffer at once
+	MultiThreadMinSpeed         SizeSuffix // if set, stop multi-thread chunks slower than this many bytes/s so they can be retried
+	MultiThreadReuseReader      bool       // if set, chunks of a multi-thread copy on the same stream reuse a source reader where the source supports ReadAt
+	MultiThreadLocal            bool       // use multi-thread copies for local to local copies
+	MultiThreadChunkSize        SizeSuffix // Chunk size for multi-thread downloads / uploads, if not set by filesystem
+	MultiThreadChunkSizeSet     bool       // whether MultiThreadChunkSize was set (set in fs/config/configflags)
+	MultiThreadWriteBufferSize  SizeSuffix
+	MultiThreadAdaptiveBuffer   bool            // if set, grow the write buffer of each chunk up to MultiThreadWriteBufferSize as needed
+	MultiThreadRangeAlign       SizeSuffix      // if set, align the ranges multi-thread copies read to this boundary
+	MultiThreadSerialDebug      bool            // force the multi-thread chunk path with a single stream for debugging
+	MultiThreadSimulateFailure  string          // chunks for multi-thread copies to fail for debugging
+	MultiThreadSort             MultiThreadSort // order to start the chunks of multi-thread copies in
+	MultiThreadAtomic           bool            // write OpenWriterAt multi-thread copies to a temporary name then rename them
+	MultiThreadFsync            bool            // sync files written with OpenWriterAt to storage before closing them
+	MultiThreadFinalizeTimeout  time.Duration   // max time to wait for the chunk writer to close, 0 for no limit
+	MultiThreadBreakerFailures  int             // if set, stop multi-thread copies to a destination after this many fail in a row
+	MultiThreadBreakerReset     time.Duration   // if set, try multi-thread copies to a stopped destination again after this long
+	MultiThreadVerify           bool            // read the destination back after a multi-thread copy to check its hash
+	MultiThreadResume           bool            // keep multi-thread uploads on error so they can be resumed
+	MultiThreadKeepPartial      bool            // keep the partial file of cancelled OpenWriterAt multi-thread copies
+	MultiThreadKeepPartsOnError bool            // never abort failed multi-thread copies so the parts written can be inspected
+	MultiThreadRequireHash      bool            // only use multi-thread copies if they can be verified with a hash
+	MultiThreadDispatchJitter   time.Duration   // max random delay between starting the first chunks of a multi-thread copy
+	MultiThreadCheckRange       bool            // check the source returns only the range requested for each chunk
+	MultiThreadCheckBoundaries  bool            // if set read back the start of each chunk to check the backend put it at the right offset
+	MultiThreadCheckConns       bool            // if set limit multi-thread copies to the free connections of the backends
+	MultiThreadCopyFileRange    bool            // use copy_file_range for local to local multi-thread copies if supported
+	MultiThreadAdaptiveChunk    bool            // size the chunks of OpenWriterAt multi-thread copies from the speed of the first chunk
+	MultiThreadCDC              bool            // use content defined chunks for OpenWriterAt multi-thread copies
+	MultiThreadChecksumOnRead   bool            // check the data read by multi-thread copies against the source checksums
+	MultiThreadManifest         bool            // write a manifest of the chunk offsets and checksums next to multi-thread copies
+	OrderBy                     string          // instructions on how to order the transfer
+	UploadHeaders               []*HTTPOption
+	DownloadHeaders             []*HTTPOption
+	Headers                     []*HTTPOption
+	MetadataSet                 Metadata // extra metadata to write when uploading
+	RefreshTimes                bool
+	NoConsole                   bool
+	TrafficClass                uint8
+	FsCacheExpireDuration       time.Duration
+	FsCacheExpireInterval       time.Duration
+	DisableHTTP2                bool
+	HumanReadable               bool
+	KvLockTime                  time.Duration // maximum time to keep key-value database locked by process
+	DisableHTTPKeepAlives       bool
+	Metadata                    bool
+	ServerSideAcrossConfigs     bool
+	TerminalColorMode           TerminalColorMode
+	DefaultTime                 Time // time that directories with no time should display
+	Inplace                     bool // Download directly to destination file instead of atomic download to temp/rename
+	PartialSuffix               string
+	MetadataMapper              SpaceSepList
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -193,6 +234,7 @@ func NewConfig() *ConfigInfo {
 	c.MultiThreadStreams = 4
 	c.MultiThreadChunkSize = SizeSuffix(64 * 1024 * 1024)
 	c.MultiThreadWriteBufferSize = SizeSuffix(128 * 1024)
+	c.MultiThreadDisableKey = "rclone-no-multi-thread"
 
 	c.TrackRenamesStrategy = "hash"
 	c.FsCacheExpireDuration = 300 * time.Second
@@ -130,9 +130,50 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.StringVarP(flagSet, &ci.ClientCert, "client-cert", "", ci.ClientCert, "Client SSL certificate (PEM) for mutual TLS auth", "Networking")
 	flags.StringVarP(flagSet, &ci.ClientKey, "client-key", "", ci.ClientKey, "Client SSL private key (PEM) for mutual TLS auth", "Networking")
 	flags.FVarP(flagSet, &ci.MultiThreadCutoff, "multi-thread-cutoff", "", "Use multi-thread downloads for files above this size", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCutoffAuto, "multi-thread-cutoff-auto", "", ci.MultiThreadCutoffAuto, "Estimate --multi-thread-cutoff from the speed and latency of the first transfers", "Copy")
+	flags.StringArrayVarP(flagSet, &ci.MultiThreadInclude, "multi-thread-include", "", nil, "Use multi-thread transfers for files matching this name or MIME type pattern whatever their size", "Copy")
+	flags.StringArrayVarP(flagSet, &ci.MultiThreadExclude, "multi-thread-exclude", "", nil, "Never use multi-thread transfers for files matching this name or MIME type pattern", "Copy")
+	flags.StringVarP(flagSet, &ci.MultiThreadDisableKey, "multi-thread-disable-key", "", ci.MultiThreadDisableKey, "Never use multi-thread transfers for objects with this metadata key when using --metadata", "Copy,Metadata")
 	flags.IntVarP(flagSet, &ci.MultiThreadStreams, "multi-thread-streams", "", ci.MultiThreadStreams, "Number of streams to use for multi-thread downloads", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadStreamsAuto, "multi-thread-streams-auto", "", ci.MultiThreadStreamsAuto, "Choose the number of streams, up to --multi-thread-streams, from the bandwidth-delay product", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadStreamsStrict, "multi-thread-streams-strict", "", ci.MultiThreadStreamsStrict, "Always use --multi-thread-streams if set even if the backend asks for more", "Copy")
+	flags.FVarP(flagSet, &ci.MultiThreadStreamWindow, "multi-thread-stream-window", "", "Bytes each stream keeps in flight for --multi-thread-streams-auto (0 to measure)", "Copy")
+	flags.IntVarP(flagSet, &ci.MultiThreadReadStreams, "multi-thread-read-streams", "", ci.MultiThreadReadStreams, "Number of streams to read with in multi-thread transfers if different to --multi-thread-streams", "Copy")
+	flags.IntVarP(flagSet, &ci.MultiThreadWriteStreams, "multi-thread-write-streams", "", ci.MultiThreadWriteStreams, "Number of streams to write with in multi-thread transfers if different to --multi-thread-streams", "Copy")
+	flags.IntVarP(flagSet, &ci.MultiThreadTotalStreams, "multi-thread-total-streams", "", ci.MultiThreadTotalStreams, "Max number of streams all the multi-thread transfers use between them (0 for no limit)", "Copy")
+	flags.IntVarP(flagSet, &ci.MultiThreadMaxGoroutines, "multi-thread-max-goroutines", "", ci.MultiThreadMaxGoroutines, "Max number of chunk copying goroutines all the multi-thread transfers use between them (0 for no limit)", "Copy")
+	flags.FVarP(flagSet, &ci.MultiThreadMaxInflightBytes, "multi-thread-max-inflight-bytes", "", "Max total size of the chunks each multi-thread transfer has in flight (0 for no limit)", "Copy")
+	flags.FVarP(flagSet, &ci.MultiThreadMaxReadChunk, "multi-thread-max-read-chunk", "", "Max size of each chunk multi-thread transfers buffer at once, reading bigger chunks in pieces (0 for no limit)", "Copy")
+	flags.FVarP(flagSet, &ci.MultiThreadMinSpeed, "multi-thread-min-speed", "", "Stop multi-thread chunks slower than this per second for 30s so they can be retried (0 for off)", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadReuseReader, "multi-thread-reuse-reader", "", ci.MultiThreadReuseReader, "Reuse a source reader for the chunks on each multi-thread stream where the source supports it", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadAdaptiveChunk, "multi-thread-adaptive-chunk", "", ci.MultiThreadAdaptiveChunk, "Size multi-thread chunks from the speed of the first chunk if the backend doesn't set the chunk size", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadAtomic, "multi-thread-atomic", "", ci.MultiThreadAtomic, "Write multi-thread transfers to a temporary name then rename them even with --inplace", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadFsync, "multi-thread-fsync", "", ci.MultiThreadFsync, "Sync files written by multi-thread transfers to storage before closing them", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadLocal, "multi-thread-local", "", ci.MultiThreadLocal, "Use multi-thread transfers for local to local copies", "Copy")
 	flags.FVarP(flagSet, &ci.MultiThreadWriteBufferSize, "multi-thread-write-buffer-size", "", "In memory buffer size for writing when in multi-thread mode", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadAdaptiveBuffer, "multi-thread-adaptive-buffer", "", ci.MultiThreadAdaptiveBuffer, "Start the multi-thread write buffer small and grow it up to --multi-thread-write-buffer-size as needed", "Copy")
+	flags.FVarP(flagSet, &ci.MultiThreadRangeAlign, "multi-thread-range-align", "", "Align the ranges multi-thread transfers read from the source to this size", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCDC, "multi-thread-cdc", "", ci.MultiThreadCDC, "Split multi-thread transfers into content defined chunks if the backend doesn't set the chunk size", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadChecksumOnRead, "multi-thread-checksum-on-read", "", ci.MultiThreadChecksumOnRead, "Check the data read by multi-thread transfers against the checksums of the source", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadManifest, "multi-thread-manifest", "", ci.MultiThreadManifest, "Write a manifest of the chunk offsets and checksums next to multi-thread transfers", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCheckRange, "multi-thread-check-range", "", ci.MultiThreadCheckRange, "Check the source returns only the data requested for each multi-thread chunk", "Copy,Debugging")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCheckBoundaries, "multi-thread-check-boundaries", "", ci.MultiThreadCheckBoundaries, "Read back the start of each multi-thread chunk to check the backend put it at the right offset", "Copy,Debugging")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCheckConns, "multi-thread-check-connections", "", ci.MultiThreadCheckConns, "Limit the streams of multi-thread transfers to the free connections of the backends", "Copy")
 	flags.FVarP(flagSet, &ci.MultiThreadChunkSize, "multi-thread-chunk-size", "", "Chunk size for multi-thread downloads / uploads, if not set by filesystem", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadSerialDebug, "multi-thread-serial-debug", "", ci.MultiThreadSerialDebug, "Use the multi-thread chunk path with a single stream so chunks are copied in order", "Copy,Debugging")
+	flags.FVarP(flagSet, &ci.MultiThreadSort, "multi-thread-sort", "", "Order to copy multi-thread chunks in ascending|descending|center-out", "Copy")
+	flags.StringVarP(flagSet, &ci.MultiThreadSimulateFailure, "multi-thread-simulate-failure", "", ci.MultiThreadSimulateFailure, "Fail these multi-thread chunks, e.g. 0,3 or p=0.1, for testing", "Copy,Debugging")
+	_ = flagSet.MarkHidden("multi-thread-simulate-failure")
+	flags.BoolVarP(flagSet, &ci.MultiThreadKeepPartial, "multi-thread-keep-partial", "", ci.MultiThreadKeepPartial, "Keep the partial file of cancelled multi-thread transfers written in place so they can be resumed", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadKeepPartsOnError, "multi-thread-keep-parts-on-error", "", ci.MultiThreadKeepPartsOnError, "Leave the parts of failed multi-thread transfers on the destination for debugging", "Copy,Debugging")
+	flags.BoolVarP(flagSet, &ci.MultiThreadCopyFileRange, "multi-thread-copy-file-range", "", ci.MultiThreadCopyFileRange, "Use copy_file_range for local to local multi-thread copies on Linux", "Copy")
+	flags.DurationVarP(flagSet, &ci.MultiThreadDispatchJitter, "multi-thread-dispatch-jitter", "", ci.MultiThreadDispatchJitter, "Max random delay between starting the first chunks of a multi-thread transfer (0 for none)", "Copy")
+	flags.DurationVarP(flagSet, &ci.MultiThreadFinalizeTimeout, "multi-thread-finalize-timeout", "", ci.MultiThreadFinalizeTimeout, "Max time to wait for a multi-thread transfer to be finalized (0 for no limit)", "Copy")
+	flags.IntVarP(flagSet, &ci.MultiThreadBreakerFailures, "multi-thread-breaker-failures", "", ci.MultiThreadBreakerFailures, "Use single stream copies to a destination after this many multi-thread copies to it fail in a row (0 for off)", "Copy")
+	flags.DurationVarP(flagSet, &ci.MultiThreadBreakerReset, "multi-thread-breaker-reset", "", ci.MultiThreadBreakerReset, "Try multi-thread copies to a destination stopped by --multi-thread-breaker-failures again after this long (0 for never)", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadRequireHash, "multi-thread-require-hash", "", ci.MultiThreadRequireHash, "Only use multi-thread transfers if there is a common hash to verify them", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadResume, "multi-thread-resume", "", ci.MultiThreadResume, "Resume interrupted multi-thread uploads on backends which support it", "Copy")
+	flags.BoolVarP(flagSet, &ci.MultiThreadVerify, "multi-thread-verify", "", ci.MultiThreadVerify, "Read back the destination of multi-thread transfers in parallel to check the hash", "Copy")
 	flags.BoolVarP(flagSet, &ci.UseJSONLog, "use-json-log", "", ci.UseJSONLog, "Use json log format", "Logging")
 	flags.StringVarP(flagSet, &ci.OrderBy, "order-by", "", ci.OrderBy, "Instructions on how to order the transfers, e.g. 'size,descending'", "Copy")
 	flags.StringArrayVarP(flagSet, &uploadHeaders, "header-upload", "", nil, "Set HTTP header for upload transactions", "Networking")
@@ -329,6 +370,10 @@ func SetFlags(ci *fs.ConfigInfo) {
 	multiThreadStreamsFlag := pflag.Lookup("multi-thread-streams")
 	ci.MultiThreadSet = multiThreadStreamsFlag != nil && multiThreadStreamsFlag.Changed
 
+	// Set whether multi-thread-chunk-size was set
+	multiThreadChunkSizeFlag := pflag.Lookup("multi-thread-chunk-size")
+	ci.MultiThreadChunkSizeSet = multiThreadChunkSizeFlag != nil && multiThreadChunkSizeFlag.Changed
+
 	if len(partialSuffix) > 16 {
 		log.Fatalf("--partial-suffix: Expecting suffix length not greater than %d but got %d", 16, len(partialSuffix))
 	}
@@ -8,6 +8,8 @@ import (
 	"reflect"
 	"strings"
 	"time"
+
+	"github.com/rclone/rclone/fs/hash"
 )
 
 // Features describe the optional features of the Fs
@@ -175,6 +177,10 @@ type Features struct {
 	//
 	OpenChunkWriter func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
 
+	// PlanChunkWriter returns the ChunkWriterInfo OpenChunkWriter
+	// would return without starting an upload
+	PlanChunkWriter func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, err error)
+
 	// UserInfo returns info about the connected user
 	UserInfo func(ctx context.Context) (map[string]string, error)
 
@@ -195,6 +201,11 @@ type Features struct {
 	// Shutdown the backend, closing any background tasks and any
 	// cached connections.
 	Shutdown func(ctx context.Context) error
+
+	// ConnectionLimit returns the number of connections to the
+	// backend which are free to use right now, or -1 if the
+	// number of connections isn't limited.
+	ConnectionLimit func(ctx context.Context) int
 }
 
 // Disable nil's out the named feature.  If it isn't found then it
@@ -335,6 +346,9 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(OpenChunkWriter); ok {
 		ft.OpenChunkWriter = do.OpenChunkWriter
 	}
+	if do, ok := f.(ChunkWriterPlanner); ok {
+		ft.PlanChunkWriter = do.PlanChunkWriter
+	}
 	if do, ok := f.(UserInfoer); ok {
 		ft.UserInfo = do.UserInfo
 	}
@@ -347,6 +361,9 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(Shutdowner); ok {
 		ft.Shutdown = do.Shutdown
 	}
+	if do, ok := f.(ConnectionLimiter); ok {
+		ft.ConnectionLimit = do.ConnectionLimit
+	}
 	return ft.DisableList(GetConfig(ctx).DisableFeatures)
 }
 
@@ -441,6 +458,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.OpenChunkWriter == nil {
 		ft.OpenChunkWriter = nil
 	}
+	if mask.PlanChunkWriter == nil {
+		ft.PlanChunkWriter = nil
+	}
 	if mask.UserInfo == nil {
 		ft.UserInfo = nil
 	}
@@ -451,6 +471,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.Shutdown == nil {
 		ft.Shutdown = nil
 	}
+	if mask.ConnectionLimit == nil {
+		ft.ConnectionLimit = nil
+	}
 	return ft.DisableList(GetConfig(ctx).DisableFeatures)
 }
 
@@ -694,9 +717,19 @@ type OpenWriterAtFn func(ctx context.Context, remote string, size int64) (Writer
 
 // ChunkWriterInfo describes how a backend would like ChunkWriter called
 type ChunkWriterInfo struct {
-	ChunkSize         int64 // preferred chunk size
-	Concurrency       int   // how many chunks to write at once
-	LeavePartsOnError bool  // if set don't delete parts uploaded so far on error
+	ChunkSize          int64     // preferred chunk size
+	Concurrency        int       // how many chunks to write at once, 0 for no preference to use --multi-thread-streams - must not be negative
+	LeavePartsOnError  bool      // if set don't delete parts uploaded so far on error
+	UploadID           string    // if set the upload can be resumed by passing this in a ResumeUploadOption
+	CompletedChunks    []int     // chunks which have already been written if the upload was resumed
+	ChunkHashType      hash.Type // hash of each chunk to pass to WriteChunkWithHash, hash.None for none
+	FinalChunkLast     bool      // if set the final chunk is only written after all the others have been written
+	MinChunkSize       int64     // if set the smallest chunk size the backend supports
+	NoSeekNeeded       bool      // if set the ChunkWriter won't seek the chunks (except for retries) so they needn't be buffered
+	MaxChunks          int       // if set the most chunks the backend supports, the chunk size is increased to fit
+	MetadataAfterClose bool      // if set the ChunkWriter doesn't set the metadata or modtime so they are set after Close
+	WriteBuffer        bool      // if set io.Copy from the chunk readers writes in blocks of --multi-thread-write-buffer-size
+	SingleShotUpload   bool      // if set a source which fits in one chunk is uploaded by Put in one request, so it needn't use a chunk writer
 }
 
 // OpenChunkWriter is an option interface for Fs to implement chunked writing
@@ -708,6 +741,19 @@ type OpenChunkWriter interface {
 	OpenChunkWriter(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
 }
 
+// ChunkWriterPlanner is an optional interface for Fs which implement
+// OpenChunkWriter
+type ChunkWriterPlanner interface {
+	// PlanChunkWriter returns the ChunkWriterInfo OpenChunkWriter
+	// would return for the same arguments without starting an
+	// upload.
+	//
+	// This lets a multi-thread copy decide how to upload src, for
+	// example with a single Put if SingleShotUpload is set and it
+	// would only be one chunk, before any requests are made.
+	PlanChunkWriter(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, err error)
+}
+
 // OpenChunkWriterFn describes the OpenChunkWriter function pointer
 type OpenChunkWriterFn func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
 
@@ -725,6 +771,20 @@ type ChunkWriter interface {
 	Abort(ctx context.Context) error
 }
 
+// ChunkWriterWithHash is an optional interface for a ChunkWriter to
+// implement if it can have the server verify each chunk.
+//
+// If the ChunkWriterInfo.ChunkHashType is set then
+// WriteChunkWithHash will be called instead of WriteChunk.
+type ChunkWriterWithHash interface {
+	ChunkWriter
+
+	// WriteChunkWithHash will write chunk number with reader bytes
+	// as WriteChunk does. expectedHash is the hex encoded hash of
+	// the chunk of type ChunkWriterInfo.ChunkHashType.
+	WriteChunkWithHash(ctx context.Context, chunkNumber int, reader io.ReadSeeker, expectedHash string) (bytesWritten int64, err error)
+}
+
 // UserInfoer is an optional interface for Fs
 type UserInfoer interface {
 	// UserInfo returns info about the connected user
@@ -768,6 +828,20 @@ type Shutdowner interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ConnectionLimiter is an optional interface for Fs which limit the
+// number of connections they make, for example with a connection pool
+type ConnectionLimiter interface {
+	// ConnectionLimit returns the number of connections to the
+	// backend which are free to use right now, or -1 if the
+	// number of connections isn't limited.
+	//
+	// This is used to avoid starting multi-thread copies which
+	// need more connections than are free, which would stall
+	// waiting for each other. It is called before each
+	// multi-thread copy so must be quick.
+	ConnectionLimit(ctx context.Context) int
+}
+
 // ObjectsChan is a channel of Objects
 type ObjectsChan chan Object
 
@@ -385,3 +385,71 @@ func HelpString(indent int) string {
 	}
 	return help.String()
 }
+
+// crc32IEEEReversed is the reversed IEEE polynomial used by CRC32
+const crc32IEEEReversed = 0xedb88320
+
+// multiply the 32x32 GF(2) matrix mat by vec
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) (sum uint32) {
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+// set square to mat * mat
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range mat {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
+
+// CombineCRC32 returns the CRC32 (IEEE) of the concatenation of two
+// blocks of data given crc1, the CRC32 of the first block, crc2, the
+// CRC32 of the second block and len2, the length of the second block.
+//
+// This is the same algorithm as crc32_combine in zlib.
+func CombineCRC32(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	var even, odd [32]uint32
+
+	// put operator for one zero bit in odd
+	odd[0] = crc32IEEEReversed
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+
+	// put operator for two zero bits in even
+	gf2MatrixSquare(&even, &odd)
+
+	// put operator for four zero bits in odd
+	gf2MatrixSquare(&odd, &even)
+
+	// apply len2 zeros to crc1 (first square will put the operator
+	// for one zero byte, eight zero bits, in even)
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
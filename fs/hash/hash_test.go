@@ -2,6 +2,7 @@ package hash_test
 
 import (
 	"bytes"
+	"hash/crc32"
 	"io"
 	"log"
 	"testing"
@@ -208,3 +209,13 @@ func TestHashTypeStability(t *testing.T) {
 	assert.True(t, hash.Supported().Contains(hash.SHA1))
 	assert.False(t, hash.Supported().Contains(hash.None))
 }
+
+func TestCombineCRC32(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog, again and again and again")
+	want := crc32.ChecksumIEEE(data)
+	for split := 0; split <= len(data); split++ {
+		crc1 := crc32.ChecksumIEEE(data[:split])
+		crc2 := crc32.ChecksumIEEE(data[split:])
+		assert.Equal(t, want, hash.CombineCRC32(crc1, crc2, int64(len(data)-split)), "split at %d", split)
+	}
+}
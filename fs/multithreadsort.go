@@ -0,0 +1,22 @@
+package fs
+
+type multiThreadSortChoices struct{}
+
+func (multiThreadSortChoices) Choices() []string {
+	return []string{
+		MultiThreadSortAscending:  "ascending",
+		MultiThreadSortDescending: "descending",
+		MultiThreadSortCenterOut:  "center-out",
+	}
+}
+
+// MultiThreadSort describes the order multi-thread copies start their chunks in
+type MultiThreadSort = Enum[multiThreadSortChoices]
+
+// MultiThreadSort constants
+const (
+	MultiThreadSortAscending MultiThreadSort = iota
+	MultiThreadSortDescending
+	MultiThreadSortCenterOut
+	MultiThreadSortDefault = MultiThreadSortAscending
+)
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +22,77 @@ var (
 	overriddenConfig   = make(map[string]string)
 )
 
+// MultiThreadChunkSizeKey is the config key which sets the chunk size
+// of multi-thread transfers to a remote, overriding
+// --multi-thread-chunk-size. It can be used with any backend, e.g.
+// ":local,multi_thread_chunk_size=256M:".
+const MultiThreadChunkSizeKey = "multi_thread_chunk_size"
+
+// MultiThreadMinChunkSizeKey is the config key which sets the
+// smallest chunk size the backend of a remote accepts for all chunks
+// but the last of a multi-thread transfer, e.g. 5M for S3 compatible
+// storage. Backends with OpenChunkWriter report this themselves so it
+// is only needed for those which use OpenWriterAt.
+const MultiThreadMinChunkSizeKey = "multi_thread_min_chunk_size"
+
+// Store the multi_thread_chunk_size and multi_thread_min_chunk_size
+// set for each Fs
+var (
+	multiThreadChunkSizesMu  sync.Mutex
+	multiThreadChunkSizes    = make(map[Fs]SizeSuffix)
+	multiThreadMinChunkSizes = make(map[Fs]SizeSuffix)
+)
+
+// MultiThreadChunkSize returns the chunk size set for multi-thread
+// transfers to f with MultiThreadChunkSizeKey, if any
+func MultiThreadChunkSize(f Fs) (size SizeSuffix, ok bool) {
+	multiThreadChunkSizesMu.Lock()
+	defer multiThreadChunkSizesMu.Unlock()
+	size, ok = multiThreadChunkSizes[f]
+	return size, ok
+}
+
+// MultiThreadMinChunkSize returns the minimum chunk size set for
+// multi-thread transfers to f with MultiThreadMinChunkSizeKey, if any
+func MultiThreadMinChunkSize(f Fs) (size SizeSuffix, ok bool) {
+	multiThreadChunkSizesMu.Lock()
+	defer multiThreadChunkSizesMu.Unlock()
+	size, ok = multiThreadMinChunkSizes[f]
+	return size, ok
+}
+
+// setMultiThreadChunkSize reads MultiThreadChunkSizeKey and
+// MultiThreadMinChunkSizeKey from config and stores them for f
+func setMultiThreadChunkSize(f Fs, config configmap.Getter) error {
+	err := setConfigSize(f, config, MultiThreadChunkSizeKey, multiThreadChunkSizes)
+	if err != nil {
+		return err
+	}
+	return setConfigSize(f, config, MultiThreadMinChunkSizeKey, multiThreadMinChunkSizes)
+}
+
+// setConfigSize reads the size with key from config and stores it
+// for f in sizes
+func setConfigSize(f Fs, config configmap.Getter, key string, sizes map[Fs]SizeSuffix) error {
+	value, ok := config.Get(key)
+	if !ok || value == "" {
+		return nil
+	}
+	var size SizeSuffix
+	err := size.Set(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	if size <= 0 {
+		return fmt.Errorf("invalid %s %q: must be greater than 0", key, value)
+	}
+	Debugf(f, "Using %s %v", key, size)
+	multiThreadChunkSizesMu.Lock()
+	sizes[f] = size
+	multiThreadChunkSizesMu.Unlock()
+	return nil
+}
+
 // NewFs makes a new Fs object from the path
 //
 // The path is of the form remote:path
@@ -67,6 +139,9 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 	f, err := fsInfo.NewFs(ctx, configName, fsPath, config)
 	if f != nil && (err == nil || err == ErrorIsFile) {
 		addReverse(f, fsInfo)
+		if setErr := setMultiThreadChunkSize(f, config); setErr != nil {
+			return nil, setErr
+		}
 	}
 	return f, err
 }
@@ -43,3 +43,42 @@ func TestNewFs(t *testing.T) {
 	assert.Equal(t, ":mockfs{S_NHG}:/tmp", fs.ConfigString(f3))
 	assert.Equal(t, ":mockfs,potato='true':/tmp", fs.ConfigStringFull(f3))
 }
+
+func TestNewFsMultiThreadChunkSize(t *testing.T) {
+	ctx := context.Background()
+
+	// Register mockfs temporarily
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+
+	f1, err := fs.NewFs(ctx, ":mockfs:/tmp")
+	require.NoError(t, err)
+	_, ok := fs.MultiThreadChunkSize(f1)
+	assert.False(t, ok)
+
+	f2, err := fs.NewFs(ctx, ":mockfs,multi_thread_chunk_size=256M:/tmp")
+	require.NoError(t, err)
+	size, ok := fs.MultiThreadChunkSize(f2)
+	assert.True(t, ok)
+	assert.Equal(t, 256*fs.Mebi, size)
+
+	_, err = fs.NewFs(ctx, ":mockfs,multi_thread_chunk_size=potato:/tmp")
+	assert.ErrorContains(t, err, "invalid multi_thread_chunk_size")
+
+	_, err = fs.NewFs(ctx, ":mockfs,multi_thread_chunk_size=0:/tmp")
+	assert.ErrorContains(t, err, "must be greater than 0")
+
+	_, ok = fs.MultiThreadMinChunkSize(f2)
+	assert.False(t, ok)
+	f3, err := fs.NewFs(ctx, ":mockfs,multi_thread_min_chunk_size=5M:/tmp")
+	require.NoError(t, err)
+	size, ok = fs.MultiThreadMinChunkSize(f3)
+	assert.True(t, ok)
+	assert.Equal(t, 5*fs.Mebi, size)
+
+	_, err = fs.NewFs(ctx, ":mockfs,multi_thread_min_chunk_size=potato:/tmp")
+	assert.ErrorContains(t, err, "invalid multi_thread_min_chunk_size")
+}
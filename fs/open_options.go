@@ -306,6 +306,56 @@ func (o *ChunkOption) String() string {
 	return fmt.Sprintf("ChunkOption(%v)", o.ChunkSize)
 }
 
+// ResumeUploadOption asks OpenChunkWriter to resume the upload
+// UploadID (as returned in ChunkWriterInfo) rather than starting a
+// new one.
+//
+// Backends which can't resume the upload should ignore this and start
+// a new upload.
+type ResumeUploadOption struct {
+	UploadID string
+}
+
+// Header formats the option as an http header
+func (o *ResumeUploadOption) Header() (key string, value string) {
+	return "", ""
+}
+
+// Mandatory returns whether the option must be parsed or can be ignored
+func (o *ResumeUploadOption) Mandatory() bool {
+	return false
+}
+
+// String formats the option into human-readable form
+func (o *ResumeUploadOption) String() string {
+	return fmt.Sprintf("ResumeUploadOption(%q)", o.UploadID)
+}
+
+// NoWriteBufferOption asks a multi-thread copy to a backend using
+// OpenWriterAt, or a chunk writer which sets
+// ChunkWriterInfo.WriteBuffer, not to buffer the writes of each
+// chunk, overriding --multi-thread-write-buffer-size for this
+// transfer.
+//
+// This is useful for destinations which buffer writes themselves. Use
+// operations.WithMultiThreadNoWriteBuffer to pass it from a copy.
+type NoWriteBufferOption struct{}
+
+// Header formats the option as an http header
+func (o *NoWriteBufferOption) Header() (key string, value string) {
+	return "", ""
+}
+
+// Mandatory returns whether the option must be parsed or can be ignored
+func (o *NoWriteBufferOption) Mandatory() bool {
+	return false
+}
+
+// String formats the option into human-readable form
+func (o *NoWriteBufferOption) String() string {
+	return "NoWriteBufferOption()"
+}
+
 // OpenOptionAddHeaders adds each header found in options to the
 // headers map provided the key was non empty.
 func OpenOptionAddHeaders(options []OpenOption, headers map[string]string) {
@@ -38,6 +38,7 @@ type copy struct {
 	tr            *accounting.Transfer // accounting for the transfer
 	inplace       bool                 // set if we are updating inplace and not using a partial name
 	remoteForCopy string               // the name used for the transfer, either remote or remote+".partial"
+	multiThread   bool                 // set if the transfer used multiThreadCopy
 }
 
 // Used to remove a failed copy
@@ -54,6 +55,12 @@ func (c *copy) removeFailedCopy(ctx context.Context, o fs.Object) {
 
 // Used to remove a failed partial copy
 func (c *copy) removeFailedPartialCopy(ctx context.Context, f fs.Fs, remote string) {
+	if c.multiThread && c.ci.MultiThreadKeepPartial {
+		// The multi-thread copy removes the file itself unless
+		// it was cancelled and should be kept
+		fs.Debugf(remote, "Not removing failed partial copy as --multi-thread-keep-partial is set")
+		return
+	}
 	o, err := f.NewObject(ctx, remote)
 	if errors.Is(err, fs.ErrorObjectNotFound) {
 		// Assume object has been deleted
@@ -92,19 +99,27 @@ func (c *copy) checkPartial() (remoteForCopy string, inplace bool, err error) {
 	if c.ci.Inplace || c.dstFeatures.Move == nil || !c.dstFeatures.PartialUploads || strings.HasSuffix(c.remote, ".rclonelink") {
 		return remoteForCopy, true, nil
 	}
-	if len(c.ci.PartialSuffix) > 16 {
-		return remoteForCopy, true, fmt.Errorf("expecting length of --partial-suffix to be not greater than %d but got %d", 16, len(c.ci.PartialSuffix))
+	remoteForCopy, err = partialRemote(remoteForCopy, c.ci.PartialSuffix)
+	if err != nil {
+		return c.remote, true, err
+	}
+	return remoteForCopy, false, nil
+}
+
+// partialRemote returns a random temporary name for remote ending in
+// partialSuffix to upload to before renaming it to remote.
+func partialRemote(remote, partialSuffix string) (string, error) {
+	if len(partialSuffix) > 16 {
+		return remote, fmt.Errorf("expecting length of --partial-suffix to be not greater than %d but got %d", 16, len(partialSuffix))
 	}
 	// Avoid making the leaf name longer if it's already lengthy to avoid
 	// trouble with file name length limits.
-	suffix := "." + random.String(8) + c.ci.PartialSuffix
-	base := path.Base(remoteForCopy)
+	suffix := "." + random.String(8) + partialSuffix
+	base := path.Base(remote)
 	if len(base) > 100 {
-		remoteForCopy = TruncateString(remoteForCopy, len(remoteForCopy)-len(suffix)) + suffix
-	} else {
-		remoteForCopy += suffix
+		return TruncateString(remote, len(remote)-len(suffix)) + suffix, nil
 	}
-	return remoteForCopy, false, nil
+	return remote + suffix, nil
 }
 
 // Check to see if we have hit max transfer limits
@@ -157,7 +172,12 @@ func (c *copy) serverSideCopy(ctx context.Context) (actionTaken string, newDst f
 
 // Copy c.src to (c.f, c.remoteForCopy) using multiThreadCopy
 func (c *copy) multiThreadCopy(ctx context.Context, uploadOptions []fs.OpenOption) (actionTaken string, newDst fs.Object, err error) {
+	c.multiThread = true
+	if multiThreadNoWriteBuffer(ctx) {
+		uploadOptions = append(uploadOptions[:len(uploadOptions):len(uploadOptions)], &fs.NoWriteBufferOption{})
+	}
 	newDst, err = multiThreadCopy(ctx, c.f, c.remoteForCopy, c.src, c.ci.MultiThreadStreams, c.tr, uploadOptions...)
+	multiThreadBreaker.record(ctx, c.f, err)
 	if c.doUpdate {
 		actionTaken = "Multi-thread Copied (replaced existing)"
 	} else {
@@ -250,7 +270,12 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 	}
 
 	if doMultiThreadCopy(ctx, c.f, c.src) {
-		return c.multiThreadCopy(ctx, uploadOptions)
+		actionTaken, newDst, err = c.multiThreadCopy(ctx, uploadOptions)
+		if !errors.Is(err, fs.ErrorCantMultiThread) {
+			return actionTaken, newDst, err
+		}
+		fs.Debugf(c.src, "Retrying with single stream copy: %v", err)
+		c.multiThread = false
 	}
 
 	var in io.ReadCloser
@@ -263,7 +288,12 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 	if c.src.Size() == -1 {
 		return c.rcat(ctx, in)
 	}
-	return c.updateOrPut(ctx, in, uploadOptions)
+	start := time.Now()
+	actionTaken, newDst, err = c.updateOrPut(ctx, in, uploadOptions)
+	if err == nil && c.ci.MultiThreadCutoffAuto {
+		globalCutoffEstimator.add(c.src.Size(), time.Since(start))
+	}
+	return actionTaken, newDst, err
 }
 
 // Verify the copy
@@ -272,6 +302,8 @@ func (c *copy) verify(ctx context.Context, newDst fs.Object) (err error) {
 	if sizeDiffers(ctx, c.src, newDst) {
 		return fmt.Errorf("corrupted on transfer: sizes differ src(%s) %d vs dst(%s) %d", c.src.Fs(), c.src.Size(), newDst.Fs(), newDst.Size())
 	}
+	// Multi-thread copies must be verified by a hash if requested
+	requireHash := c.multiThread && c.ci.MultiThreadRequireHash
 	// Verify hashes are the same after transfer - ignoring blank hashes
 	if c.hashType != hash.None {
 		// checkHashes has logs and counts errors
@@ -279,6 +311,11 @@ func (c *copy) verify(ctx context.Context, newDst fs.Object) (err error) {
 		if !equal {
 			return fmt.Errorf("corrupted on transfer: %v hashes differ src(%s) %q vs dst(%s) %q", c.hashType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
 		}
+		if requireHash && (srcSum == "" || dstSum == "") {
+			return fmt.Errorf("unverified multi-thread transfer: %v hash missing src(%s) %q vs dst(%s) %q and --multi-thread-require-hash is set", c.hashType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
+		}
+	} else if requireHash {
+		return fmt.Errorf("unverified multi-thread transfer: no common hash between src(%s) and dst(%s) and --multi-thread-require-hash is set", c.src.Fs(), newDst.Fs())
 	}
 	return nil
 }
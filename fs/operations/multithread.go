@@ -2,21 +2,45 @@ package operations
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
 	"github.com/rclone/rclone/lib/atexit"
+	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/multipart"
+	"github.com/rclone/rclone/lib/pool"
+	"github.com/rclone/rclone/lib/readers"
 	"golang.org/x/sync/errgroup"
 )
 
 const (
 	multithreadChunkSize = 64 << 10
+	copyFileRangeSize    = 8 << 20          // max bytes to copy with each copy_file_range
+	chunkReadRetries     = 3                // times to open the source again from where a buffered chunk read failed
+	abortTimeout         = 30 * time.Second // max time to wait for the chunk writer to abort
+
+	adaptiveChunkDuration = 10 * time.Second // --multi-thread-adaptive-chunk aims for chunks which take this long
+	adaptiveChunkRound    = 1 << 20          // round adaptive chunk sizes up to a multiple of this
+	adaptiveChunkMin      = 1 << 20          // smallest adaptive chunk size
+	adaptiveChunkMax      = 1 << 30          // largest adaptive chunk size
 )
 
 // Return a boolean as to whether we should use multi thread copy for
@@ -27,95 +51,1258 @@ func doMultiThreadCopy(ctx context.Context, f fs.Fs, src fs.Object) bool {
 	// Disable multi thread if...
 
 	// ...it isn't configured
-	if ci.MultiThreadStreams <= 1 {
+	if ci.MultiThreadStreams <= 1 && !ci.MultiThreadSerialDebug {
 		return false
 	}
 	// ...if the source doesn't support it
 	if src.Fs().Features().NoMultiThreading {
 		return false
 	}
-	// ...size of object is less than cutoff
-	if src.Size() < int64(ci.MultiThreadCutoff) {
+	// ...if the source has been found to ignore ranges
+	if !sourceHonoursRange(src.Fs()) {
+		return false
+	}
+	// ...the object matches --multi-thread-exclude
+	if pattern, found := matchMultiThreadPattern(ctx, src, ci.MultiThreadExclude); found {
+		fs.Debugf(src, "multi-thread copy: using a single stream as the object matches --multi-thread-exclude %q", pattern)
 		return false
 	}
+	// ...the object has the --multi-thread-disable-key metadata
+	if multiThreadDisabledByMetadata(ctx, src, ci.MultiThreadDisableKey) {
+		fs.Debugf(src, "multi-thread copy: using a single stream as the object has the %q metadata", ci.MultiThreadDisableKey)
+		return false
+	}
+	// ...size of object is less than cutoff unless it matches
+	// --multi-thread-include
+	if src.Size() < multiThreadCutoff(ci) {
+		pattern, found := matchMultiThreadPattern(ctx, src, ci.MultiThreadInclude)
+		if !found || src.Size() < 0 {
+			return false
+		}
+		fs.Debugf(src, "multi-thread copy: ignoring the cutoff as the object matches --multi-thread-include %q", pattern)
+	}
 	// ...destination doesn't support it
 	dstFeatures := f.Features()
 	if dstFeatures.OpenChunkWriter == nil && dstFeatures.OpenWriterAt == nil {
+		logNoMultiThread(f)
 		return false
 	}
-	// ...if --multi-thread-streams not in use and source and
-	// destination are both local
-	if !ci.MultiThreadSet && dstFeatures.IsLocal && src.Fs().Features().IsLocal {
+	// ...multi-thread copies to the destination have failed too
+	// many times in a row
+	if !multiThreadBreaker.allow(ctx, f) {
+		fs.Debugf(src, "multi-thread copy: using a single stream as multi-thread copies to %v have been stopped by --multi-thread-breaker-failures", f)
 		return false
 	}
+	// ...if source and destination are both local and neither
+	// --multi-thread-local nor --multi-thread-streams are in use
+	if dstFeatures.IsLocal && src.Fs().Features().IsLocal && !ci.MultiThreadLocal && !ci.MultiThreadSet && !ci.MultiThreadSerialDebug {
+		return false
+	}
+	// ...the streams allowed by --multi-thread-total-streams are
+	// in use by other transfers
+	if ci.MultiThreadTotalStreams > 0 && multiThreadStreams.free(ci.MultiThreadTotalStreams) < 2 {
+		fs.Debugf(src, "multi-thread copy: using a single stream as --multi-thread-total-streams %d are in use", ci.MultiThreadTotalStreams)
+		return false
+	}
+	// ...the source or destination has fewer than 2 free
+	// connections with --multi-thread-check-connections
+	if free := multiThreadFreeConnections(ctx, src.Fs(), f); free >= 0 && free < 2 {
+		fs.Debugf(src, "multi-thread copy: using a single stream as only %d connections are free", free)
+		return false
+	}
+	// ...a hash is required to verify the copy and there isn't a
+	// common one
+	if ci.MultiThreadRequireHash {
+		if hashType, _ := CommonHash(ctx, f, src.Fs()); hashType == hash.None {
+			fs.Debugf(src, "multi-thread copy: using a single stream as there is no common hash to verify the transfer and --multi-thread-require-hash is set")
+			return false
+		}
+	}
 	return true
 }
 
 // state for a multi-thread copy
 type multiThreadCopyState struct {
-	ctx         context.Context
-	partSize    int64
-	size        int64
-	src         fs.Object
-	acc         *accounting.Account
-	numChunks   int
-	noBuffering bool // set to read the input without buffering
+	ctx           context.Context
+	partSize      int64
+	firstPartSize int64 // if set the size of chunk 0 which the other chunks of partSize follow
+	size          int64
+	src           fs.Object
+	acc           MultiThreadAccount
+	numChunks     int
+	noBuffering   bool                          // set to read the input without buffering
+	job           *jobs.Job                     // rc job the copy is running in, may be nil
+	retries       atomic.Int64                  // number of times the source was reopened
+	written       atomic.Int64                  // number of bytes written
+	completed     atomic.Int64                  // number of chunks written
+	buffers       chan []byte                   // optional caller supplied buffers to read chunks into
+	eta           *chunkETA                     // estimates the time remaining, may be nil
+	chunkHash     hash.Type                     // hash of each chunk to pass to a ChunkWriterWithHash
+	readerAt      fs.ReaderAtCloser             // if set, read the source with ReadAt
+	sourceSlots   *sourceSlots                  // if set, chunks reuse the source reader of their stream
+	inspector     ChunkInspector                // if set, called with each chunk before it is written
+	checkRange    bool                          // check the source only returns the range requested
+	copyFileRange atomic.Bool                   // copy local chunks with copy_file_range
+	openOptions   []fs.OpenOption               // options to open the source with as well as the range
+	readHash      *readHasher                   // if set, check the data read against the source checksums
+	readStreams   chan struct{}                 // if set, limits the number of chunks being read at once
+	writeStreams  chan struct{}                 // if set, limits the number of chunks being written at once
+	onOpen        func(info fs.ChunkWriterInfo) // if set, called once the chunk writer is open
+	rangeAlign    int64                         // if set, chunk sizes are a multiple of this
+	minPartSize   int64                         // if set, the smallest size of all chunks but the last
+	writeBuffer   int64                         // if set, io.Copy from the chunk readers writes blocks of this size
+	manifest      *manifestBuilder              // if set, collects the chunk checksums for --multi-thread-manifest
+	simulate      *simulatedFailures            // if set, chunks to fail for --multi-thread-simulate-failure
+	openLatency   atomic.Int64                  // time the first chunk took to open the source
+	clock         clock                         // source of time, the real time if nil
+	maxReadChunk  int64                         // if set, buffer at most this much of each chunk at once
+	shouldRetry   RetryClassifier               // if set, decides which chunk read errors are retried
+	sortOrder     fs.MultiThreadSort            // order to start the chunks in
+	minSpeed      fs.SizeSuffix                 // if set, stop chunks slower than this many bytes/s
+	inFlight      atomic.Int64                  // number of chunks being copied
+	peakInFlight  atomic.Int64                  // high-water mark of inFlight
+
+	mu         sync.Mutex
+	dispatched map[int]struct{} // chunk numbers which have been started
+	done       map[int]struct{} // chunk numbers which have been written in full
+	requeued   []int            // chunks cancelled with job/cancelchunk to copy again
+}
+
+type multiThreadBuffersKeyType struct{}
+
+// Context key for the caller supplied buffers
+var multiThreadBuffersKey = multiThreadBuffersKeyType{}
+
+// WithMultiThreadBuffers returns a context which makes multi-thread
+// copies read chunks which need buffering into the buffers supplied
+// rather than allocating memory for them.
+//
+// Each buffer will be taken from the channel while a chunk is being
+// copied and returned when done, so supply one buffer for each
+// stream. Each buffer must be at least as big as the chunk size used
+// (--multi-thread-chunk-size or the backend's chunk size) or the copy
+// will fail.
+func WithMultiThreadBuffers(ctx context.Context, buffers chan []byte) context.Context {
+	return context.WithValue(ctx, multiThreadBuffersKey, buffers)
+}
+
+// getMultiThreadBuffers returns the buffers from WithMultiThreadBuffers or nil
+func getMultiThreadBuffers(ctx context.Context) chan []byte {
+	buffers, _ := ctx.Value(multiThreadBuffersKey).(chan []byte)
+	return buffers
+}
+
+type multiThreadOnOpenKeyType struct{}
+
+// Context key for the OnOpen hook
+var multiThreadOnOpenKey = multiThreadOnOpenKeyType{}
+
+// WithMultiThreadOnOpen returns a context which makes multi-thread
+// copies call onOpen once the chunk writer has been opened, for
+// example to reserve quota for the upload.
+//
+// onOpen is passed the ChunkWriterInfo with the chunk size and
+// concurrency which will be used. It runs before any chunk is
+// dispatched.
+func WithMultiThreadOnOpen(ctx context.Context, onOpen func(info fs.ChunkWriterInfo)) context.Context {
+	return context.WithValue(ctx, multiThreadOnOpenKey, onOpen)
+}
+
+// getMultiThreadOnOpen returns the hook from WithMultiThreadOnOpen or nil
+func getMultiThreadOnOpen(ctx context.Context) func(info fs.ChunkWriterInfo) {
+	onOpen, _ := ctx.Value(multiThreadOnOpenKey).(func(info fs.ChunkWriterInfo))
+	return onOpen
+}
+
+// WrittenHashes are the hashes of the data written by a multi-thread
+// copy with OpenWriterAt, as reported by WithMultiThreadWrittenHashes.
+type WrittenHashes struct {
+	Chunks []map[hash.Type]string // hashes of each chunk in order - nil for chunks which weren't hashed
+	File   map[hash.Type]string   // hashes of the whole file which could be combined from the chunks
+}
+
+// writtenHashesHook is the value set by WithMultiThreadWrittenHashes
+type writtenHashesHook struct {
+	hashes  hash.Set
+	onClose func(remote string, hashes WrittenHashes)
+}
+
+type multiThreadWrittenHashesKeyType struct{}
+
+// Context key for the written hashes hook
+var multiThreadWrittenHashesKey = multiThreadWrittenHashesKeyType{}
+
+// WithMultiThreadWrittenHashes returns a context which makes
+// multi-thread copies to backends using OpenWriterAt (eg local) hash
+// each chunk with hashes as it is written, and pass the hashes to
+// onClose once the file has been closed. This lets the destination be
+// verified without reading it back.
+//
+// MD5 and most other hashes can't be combined from chunks written out
+// of order, so the hash of the whole file in File is only set for
+// CRC32, or for any hash if there was only one chunk. It is left out
+// if any chunk wasn't hashed, for example because the copy was
+// resumed.
+//
+// Hashing costs CPU and stops copy_file_range being used, so it is
+// only done if asked for.
+func WithMultiThreadWrittenHashes(ctx context.Context, hashes hash.Set, onClose func(remote string, hashes WrittenHashes)) context.Context {
+	return context.WithValue(ctx, multiThreadWrittenHashesKey, writtenHashesHook{hashes: hashes, onClose: onClose})
+}
+
+// getMultiThreadWrittenHashes returns the hook from
+// WithMultiThreadWrittenHashes or nil if it isn't set
+func getMultiThreadWrittenHashes(ctx context.Context) *writtenHashesHook {
+	hook, ok := ctx.Value(multiThreadWrittenHashesKey).(writtenHashesHook)
+	if !ok || hook.onClose == nil || hook.hashes.Count() == 0 {
+		return nil
+	}
+	return &hook
+}
+
+type multiThreadNoWriteBufferKeyType struct{}
+
+// Context key for disabling the write buffer
+var multiThreadNoWriteBufferKey = multiThreadNoWriteBufferKeyType{}
+
+// WithMultiThreadNoWriteBuffer returns a context which makes the
+// multi-thread copies made with it, eg by Copy, pass
+// fs.NoWriteBufferOption so the chunks aren't buffered before they are
+// written, overriding --multi-thread-write-buffer-size for those
+// transfers only.
+func WithMultiThreadNoWriteBuffer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, multiThreadNoWriteBufferKey, true)
+}
+
+// multiThreadNoWriteBuffer returns true if WithMultiThreadNoWriteBuffer
+// was used on ctx
+func multiThreadNoWriteBuffer(ctx context.Context) bool {
+	noWriteBuffer, _ := ctx.Value(multiThreadNoWriteBufferKey).(bool)
+	return noWriteBuffer
+}
+
+// RetryClassifier decides whether an error reading a chunk from the
+// source should be retried by opening the source again.
+type RetryClassifier func(err error) bool
+
+type multiThreadRetryClassifierKeyType struct{}
+
+// Context key for the retry classifier
+var multiThreadRetryClassifierKey = multiThreadRetryClassifierKeyType{}
+
+// WithMultiThreadRetryClassifier returns a context which makes
+// multi-thread copies use shouldRetry to decide which errors reading
+// a chunk from the source are retried, for example to retry a backend
+// specific rate limit error.
+//
+// Without it every error is retried except those marked with
+// fserrors.NoLowLevelRetryError. Errors marked like that are never
+// retried whatever shouldRetry returns. A chunk is retried at most 3
+// times.
+func WithMultiThreadRetryClassifier(ctx context.Context, shouldRetry RetryClassifier) context.Context {
+	return context.WithValue(ctx, multiThreadRetryClassifierKey, shouldRetry)
+}
+
+// getMultiThreadRetryClassifier returns the classifier from
+// WithMultiThreadRetryClassifier or nil
+func getMultiThreadRetryClassifier(ctx context.Context) RetryClassifier {
+	shouldRetry, _ := ctx.Value(multiThreadRetryClassifierKey).(RetryClassifier)
+	return shouldRetry
+}
+
+// MultiThreadAccount is the accounting a multi-thread copy does for
+// the transfer it is making. It is implemented by
+// *accounting.Account.
+type MultiThreadAccount interface {
+	// AccountRead records that n bytes of the transfer have been
+	// copied. It is called from each chunk's goroutine as the
+	// chunk is written so must be safe for concurrent use. An
+	// error, eg from --max-transfer, stops the copy.
+	AccountRead(n int) error
+	// SetChunkETA records the time to completion estimated from
+	// the chunks finished so far.
+	SetChunkETA(eta time.Duration)
+}
+
+// AccountDecorator wraps the accounting of a multi-thread copy of src
+// in acc, returning the accounting to use instead.
+//
+// The result should pass the calls on to acc so the transfer is still
+// shown in the stats and the transfer limits still work.
+type AccountDecorator func(src fs.ObjectInfo, acc MultiThreadAccount) MultiThreadAccount
+
+type multiThreadAccountDecoratorKeyType struct{}
+
+// Context key for the account decorator
+var multiThreadAccountDecoratorKey = multiThreadAccountDecoratorKeyType{}
+
+// WithMultiThreadAccountDecorator returns a context which makes
+// multi-thread copies account the bytes they copy through the
+// accounting returned by decorator, for example to collect custom
+// metrics about each transfer.
+//
+// decorator is called once for each multi-thread copy, before any
+// chunks are copied.
+func WithMultiThreadAccountDecorator(ctx context.Context, decorator AccountDecorator) context.Context {
+	return context.WithValue(ctx, multiThreadAccountDecoratorKey, decorator)
+}
+
+type multiThreadLabelKeyType struct{}
+
+// Context key for the chunk label
+var multiThreadLabelKey = multiThreadLabelKeyType{}
+
+// WithMultiThreadLabel returns a context which makes multi-thread
+// copies account the chunks they read under label, for example the
+// tenant the transfer is for.
+//
+// The bytes are counted for the label in the stats and limited by any
+// bandwidth limit set for it with accounting.SetLabelBwLimit. Unlike
+// accounting.WithLabel only the chunk reads of multi-thread copies are
+// labelled.
+func WithMultiThreadLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, multiThreadLabelKey, label)
+}
+
+// multiThreadAccount returns the accounting for a multi-thread copy of
+// src to tr, labelled with the label from WithMultiThreadLabel and
+// decorated by the decorator from WithMultiThreadAccountDecorator if
+// set.
+func multiThreadAccount(ctx context.Context, src fs.ObjectInfo, tr *accounting.Transfer) MultiThreadAccount {
+	accCtx := ctx
+	if label, _ := ctx.Value(multiThreadLabelKey).(string); label != "" {
+		accCtx = accounting.WithLabel(ctx, label)
+	}
+	var acc MultiThreadAccount = tr.Account(accCtx, nil)
+	if decorator, _ := ctx.Value(multiThreadAccountDecoratorKey).(AccountDecorator); decorator != nil {
+		acc = decorator(src, acc)
+	}
+	return acc
+}
+
+// ChunkInspector checks the data of a chunk of a multi-thread copy
+// before it is written, for example to scan it for viruses or check it
+// against a data policy.
+//
+// chunk is the number of the chunk counting from 0 and data reads the
+// whole chunk. Returning an error stops the chunk being written and
+// fails the copy without retrying it. The inspector can't change the
+// data written.
+//
+// It is called from each chunk's goroutine so must be safe for
+// concurrent use.
+type ChunkInspector func(ctx context.Context, chunk int, data io.Reader) error
+
+type multiThreadChunkInspectorKeyType struct{}
+
+// Context key for the chunk inspector
+var multiThreadChunkInspectorKey = multiThreadChunkInspectorKeyType{}
+
+// WithMultiThreadChunkInspector returns a context which makes
+// multi-thread copies pass each chunk to inspector before writing it.
+//
+// The chunks must be read into memory to be inspected, so this makes
+// multi-thread copies buffer every chunk, including those which would
+// otherwise be streamed from the source to the destination, and stops
+// local to local copies with --multi-thread-local copying the chunks
+// with copy_file_range or ReadAt/WriteAt. This costs up to a chunk of
+// memory for each stream and time for the inspector to read each chunk
+// before it is written.
+func WithMultiThreadChunkInspector(ctx context.Context, inspector ChunkInspector) context.Context {
+	return context.WithValue(ctx, multiThreadChunkInspectorKey, inspector)
+}
+
+// getMultiThreadChunkInspector returns the inspector from
+// WithMultiThreadChunkInspector or nil
+func getMultiThreadChunkInspector(ctx context.Context) ChunkInspector {
+	inspector, _ := ctx.Value(multiThreadChunkInspectorKey).(ChunkInspector)
+	return inspector
+}
+
+// inspectChunk passes the buffered chunk in to the inspector if set,
+// rewinding in afterwards
+func (mc *multiThreadCopyState) inspectChunk(ctx context.Context, chunk int, in io.ReadSeeker) error {
+	if mc.inspector == nil {
+		return nil
+	}
+	err := mc.inspector(ctx, chunk, in)
+	if err != nil {
+		return fserrors.NoRetryError(fmt.Errorf("multi-thread copy: chunk %d rejected by inspector: %w", chunk+1, err))
+	}
+	_, err = in.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to rewind chunk %d after inspecting it: %w", chunk+1, err)
+	}
+	return nil
+}
+
+// retryRead returns whether err reading a chunk should be retried
+func (mc *multiThreadCopyState) retryRead(err error) bool {
+	if fserrors.IsNoLowLevelRetryError(err) {
+		return false
+	}
+	if mc.shouldRetry != nil {
+		return mc.shouldRetry(err)
+	}
+	return true
+}
+
+// accountedBuffer is an io.ReadSeeker over a buffer which calls
+// account for every read like pool.RW does
+type accountedBuffer struct {
+	*bytes.Reader
+	account   func(n int) error
+	reads     int // count how many times the data has been read
+	accountOn int // only account on or after this read
+}
+
+// newAccountedBuffer makes an accountedBuffer reading from buf
+func newAccountedBuffer(buf []byte, account func(n int) error) *accountedBuffer {
+	return &accountedBuffer{
+		Reader:  bytes.NewReader(buf),
+		account: account,
+	}
+}
+
+// Read reads from the buffer and accounts the bytes read
+func (b *accountedBuffer) Read(p []byte) (n int, err error) {
+	// Count a read of the data if we read from the start
+	if b.Reader.Len() == int(b.Reader.Size()) {
+		b.reads++
+	}
+	n, err = b.Reader.Read(p)
+	if n > 0 && b.reads >= b.accountOn {
+		accErr := b.account(n)
+		if err == nil {
+			err = accErr
+		}
+	}
+	return n, err
+}
+
+// DelayAccounting makes sure the accounting function only gets called
+// on the i-th or later read of the data from this point (counting
+// from 1).
+func (b *accountedBuffer) DelayAccounting(i int) {
+	b.accountOn = i
+	b.reads = 0
+}
+
+// Check interfaces
+var _ pool.DelayAccountinger = (*accountedBuffer)(nil)
+
+// cancelReader is an io.ReadSeeker which returns the error from ctx
+// as soon as it is cancelled so that in-flight chunks stop quickly
+// when a sibling chunk has failed.
+type cancelReader struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+// Read returns an error if the context is cancelled or reads from
+// the underlying reader
+func (r *cancelReader) Read(p []byte) (n int, err error) {
+	err = r.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Read(p)
+}
+
+// DelayAccounting passes the call on to the underlying reader if it
+// supports it
+func (r *cancelReader) DelayAccounting(i int) {
+	if do, ok := r.ReadSeeker.(pool.DelayAccountinger); ok {
+		do.DelayAccounting(i)
+	}
+}
+
+// Check interfaces
+var _ pool.DelayAccountinger = (*cancelReader)(nil)
+
+// writeBufferReader is passed to the WriteChunk of chunk writers which
+// set ChunkWriterInfo.WriteBuffer.
+//
+// It implements io.WriterTo so io.Copy from it writes in blocks of
+// size rather than one write for each read, which helps backends
+// which would otherwise do many small socket writes.
+type writeBufferReader struct {
+	io.ReadSeeker
+	size int64
+}
+
+// WriteTo writes the rest of the reader to w in blocks of r.size
+func (r *writeBufferReader) WriteTo(w io.Writer) (n int64, err error) {
+	buf := make([]byte, r.size)
+	for {
+		nr, readErr := io.ReadFull(r.ReadSeeker, buf)
+		if nr > 0 {
+			nw, err := w.Write(buf[:nr])
+			n += int64(nw)
+			if err != nil {
+				return n, err
+			}
+			if nw != nr {
+				return n, io.ErrShortWrite
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
+// DelayAccounting passes the call on to the underlying reader if it
+// supports it
+func (r *writeBufferReader) DelayAccounting(i int) {
+	if do, ok := r.ReadSeeker.(pool.DelayAccountinger); ok {
+		do.DelayAccounting(i)
+	}
+}
+
+// Check interfaces
+var (
+	_ io.WriterTo            = (*writeBufferReader)(nil)
+	_ pool.DelayAccountinger = (*writeBufferReader)(nil)
+)
+
+// chunkReader reads the chunk being buffered from the source.
+//
+// If a read fails with an error which can be retried it opens the
+// source again from where the read got to, up to chunkReadRetries
+// times, so the part of the chunk already buffered isn't thrown away.
+// This is on top of the retries ReOpen does which are limited for
+// the whole chunk rather than for each error.
+type chunkReader struct {
+	ctx     context.Context
+	mc      *multiThreadCopyState
+	src     fs.Object // the source to open, which may be reusing a reader
+	chunk   int
+	in      *ReOpen // the current reader, closed by the caller
+	start   int64   // start of the chunk in the source
+	end     int64   // end of the chunk in the source, exclusive
+	offset  int64   // bytes read from the chunk so far
+	tries   int     // number of times the source has been opened again
+	retries int     // retries counted by the readers which have been closed
+}
+
+// Read reads from the source, opening it again on retriable errors
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	n, err = r.in.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF || r.tries >= chunkReadRetries || r.ctx.Err() != nil || !r.mc.retryRead(err) {
+		return n, err
+	}
+	r.tries++
+	fs.Debugf(r.mc.src, "multi-thread copy: chunk %v/%v: opening source again at offset %d after read error: retry %d/%d: %v", fs.LogValue("chunk", r.chunk+1), fs.LogValue("total", r.mc.numChunks), r.start+r.offset, r.tries, chunkReadRetries, err)
+	// The ReOpen has already counted the retry
+	r.retries += r.in.Retries()
+	_ = r.in.Close()
+	openOptions := append(r.mc.openOptions[:len(r.mc.openOptions):len(r.mc.openOptions)], &fs.RangeOption{Start: r.start + r.offset, End: r.end - 1})
+	in, openErr := Open(r.ctx, r.src, openOptions...)
+	if openErr != nil {
+		fs.Debugf(r.mc.src, "multi-thread copy: failed to open source again: %v", openErr)
+		return n, err
+	}
+	r.in = in
+	return n, nil
+}
+
+// Retries returns the number of times reading the source has been
+// retried after a read error
+func (r *chunkReader) Retries() int {
+	return r.retries + r.in.Retries()
+}
+
+// Sources which have been found to ignore the RangeOption
+var (
+	ignoresRangeMu sync.Mutex
+	ignoresRange   = map[string]struct{}{}
+)
+
+// sourceHonoursRange returns false if f has been found to ignore the
+// RangeOption so can't be used as a multi-thread source.
+func sourceHonoursRange(f fs.Info) bool {
+	ignoresRangeMu.Lock()
+	defer ignoresRangeMu.Unlock()
+	_, found := ignoresRange[fs.ConfigString(f)]
+	return !found
+}
+
+// setSourceIgnoresRange records that f ignores the RangeOption
+func setSourceIgnoresRange(f fs.Info) {
+	ignoresRangeMu.Lock()
+	defer ignoresRangeMu.Unlock()
+	ignoresRange[fs.ConfigString(f)] = struct{}{}
+}
+
+// Destinations which have been logged as not supporting multi-thread copies
+var (
+	noMultiThreadLoggedMu sync.Mutex
+	noMultiThreadLogged   = map[string]struct{}{}
+)
+
+// logNoMultiThread explains that f can't be written with multi-thread
+// copies, the first time it is called for each f only so as not to
+// log for every file.
+//
+// It returns true if the message was logged.
+func logNoMultiThread(f fs.Info) bool {
+	key := fs.ConfigString(f)
+	noMultiThreadLoggedMu.Lock()
+	_, found := noMultiThreadLogged[key]
+	noMultiThreadLogged[key] = struct{}{}
+	noMultiThreadLoggedMu.Unlock()
+	if found {
+		return false
+	}
+	fs.Infof(f, "multi-thread copy: files above --multi-thread-cutoff will be copied with a single stream as the backend supports neither OpenChunkWriter nor OpenWriterAt")
+	return true
+}
+
+// checkRangeHonoured reads one more byte from in, which should be at
+// the end of the requested range, to check that the source only
+// returned the data for the chunk.
+//
+// If it didn't then the source is recorded as ignoring ranges so it
+// won't be used for multi-thread copies again.
+func (mc *multiThreadCopyState) checkRangeHonoured(in *ReOpen, chunk int) error {
+	// Don't account the extra byte
+	in.SetAccounting(nil)
+	var extra [1]byte
+	n, err := in.Read(extra[:])
+	if n > 0 {
+		setSourceIgnoresRange(mc.src.Fs())
+		return fmt.Errorf("multi-thread copy: chunk %d/%d: source returned more data than requested - it doesn't support ranged reads so won't be used for multi-thread copies", chunk+1, mc.numChunks)
+	}
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("multi-thread copy: chunk %d/%d: failed to check end of range: %w", chunk+1, mc.numChunks, err)
+	}
+	return nil
+}
+
+// dispatchJitter sleeps for a random time up to jitter or until ctx
+// is cancelled.
+//
+// This is used to stagger the start of the first chunks so as not to
+// trip rate limiters by starting them all at once.
+func (mc *multiThreadCopyState) dispatchJitter(ctx context.Context, jitter time.Duration) {
+	if jitter <= 0 {
+		return
+	}
+	timer := mc.getClock().NewTimer(time.Duration(rand.Int63n(int64(jitter))))
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-ctx.Done():
+	}
+}
+
+// markDispatched records that chunk has been started, returning an
+// error if it has been seen before.
+//
+// Writing the same chunk twice would corrupt the destination for
+// some backends so this is checked for every chunk.
+func (mc *multiThreadCopyState) markDispatched(chunk int) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.dispatched == nil {
+		mc.dispatched = make(map[int]struct{}, mc.numChunks)
+	}
+	if _, found := mc.dispatched[chunk]; found {
+		return fmt.Errorf("multi-thread copy: chunk %d/%d written more than once", chunk+1, mc.numChunks)
+	}
+	mc.dispatched[chunk] = struct{}{}
+	return nil
+}
+
+// markDone records that chunk has been written in full
+func (mc *multiThreadCopyState) markDone(chunk int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.done == nil {
+		mc.done = make(map[int]struct{}, mc.numChunks)
+	}
+	mc.done[chunk] = struct{}{}
+}
+
+// doneChunks returns the chunks marked with markDone in order
+func (mc *multiThreadCopyState) doneChunks() (chunks []int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	for chunk := range mc.done {
+		chunks = append(chunks, chunk)
+	}
+	sort.Ints(chunks)
+	return chunks
+}
+
+// chunkEvent publishes an event for chunk to the rc job if anyone is
+// watching its events
+func (mc *multiThreadCopyState) chunkEvent(chunk int, state string, size int64, err error) {
+	if mc.job == nil || !mc.job.EventsWatched() {
+		return
+	}
+	data := rc.Params{
+		"object": mc.src.Remote(),
+		"chunk":  chunk,
+		"chunks": mc.numChunks,
+		"state":  state,
+		"bytes":  size,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	mc.job.AddEvent("chunk", data)
+}
+
+// waitIfPaused blocks while the rc job the copy is running in is
+// paused with job/pause
+func (mc *multiThreadCopyState) waitIfPaused(ctx context.Context) error {
+	if mc.job == nil {
+		return nil
+	}
+	return mc.job.WaitIfPaused(ctx)
+}
+
+// Check to see if we have hit the --max-transfer limit and return
+// an error if so.
+//
+// This is checked before each chunk is started so that the amount
+// transferred over the limit is bounded by one chunk per stream.
+func (mc *multiThreadCopyState) checkLimits(ctx context.Context) error {
+	ci := fs.GetConfig(ctx)
+	if ci.MaxTransfer < 0 || ci.CutoffMode != fs.CutoffModeHard {
+		return nil
+	}
+	if accounting.Stats(ctx).GetBytes() >= int64(ci.MaxTransfer) {
+		return accounting.ErrorMaxTransferLimitReachedFatal
+	}
+	return nil
+}
+
+// copyChunksSerial copies the chunks not in completedChunks one after
+// another in the calling goroutine, stopping at the first error.
+//
+// Chunks cancelled with job/cancelchunk are copied again afterwards,
+// but before finalChunk if it is set.
+func (mc *multiThreadCopyState) copyChunksSerial(ctx context.Context, completedChunks map[int]bool, writer fs.ChunkWriter, finalChunk int) error {
+	queue := mc.pendingChunks(completedChunks, finalChunk)
+rounds:
+	for len(queue) > 0 {
+		for i, chunk := range queue {
+			if chunk == finalChunk {
+				var held bool
+				if queue, held = mc.holdFinalChunk(queue, i); held {
+					continue rounds
+				}
+			}
+			err := mc.waitIfPaused(ctx)
+			if err != nil {
+				return err
+			}
+			err = mc.copyChunkCancellable(ctx, chunk, writer)
+			if err != nil {
+				return err
+			}
+		}
+		queue = mc.takeRequeued()
+	}
+	return nil
+}
+
+// adaptChunkSize copies chunk 0 then changes the size of the
+// remaining chunks of w to ones which should take
+// adaptiveChunkDuration to copy at the speed chunk 0 was copied.
+func (mc *multiThreadCopyState) adaptChunkSize(ctx context.Context, w *writerAtChunkWriter) error {
+	start := mc.now()
+	err := mc.copyChunk(ctx, 0, w)
+	if err != nil {
+		return err
+	}
+	elapsed := mc.since(start)
+	partSize := adaptiveChunkSize(mc.partSize, elapsed)
+	if partSize < mc.minPartSize {
+		partSize = mc.minPartSize
+	}
+	partSize = alignChunkSize(partSize, mc.rangeAlign)
+	if partSize == mc.partSize {
+		return nil
+	}
+	mc.firstPartSize = mc.partSize
+	mc.partSize = partSize
+	mc.numChunks = 1 + calculateNumChunks(mc.size-mc.firstPartSize, partSize)
+	w.firstChunkSize = mc.firstPartSize
+	w.chunkSize = partSize
+	w.chunks = mc.numChunks
+	fs.Debugf(mc.src, "multi-thread copy: first chunk took %v so using chunks of size %v", elapsed.Round(time.Millisecond), fs.LogValue("chunkSize", fs.SizeSuffix(partSize)))
+	return nil
+}
+
+// adaptiveChunkSize returns the chunk size which should take
+// adaptiveChunkDuration to copy if a chunk of size took elapsed.
+func adaptiveChunkSize(size int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return adaptiveChunkMax
+	}
+	chunkSize := int64(float64(size) * float64(adaptiveChunkDuration) / float64(elapsed))
+	chunkSize = (chunkSize + adaptiveChunkRound - 1) / adaptiveChunkRound * adaptiveChunkRound
+	if chunkSize < adaptiveChunkMin {
+		chunkSize = adaptiveChunkMin
+	}
+	if chunkSize > adaptiveChunkMax {
+		chunkSize = adaptiveChunkMax
+	}
+	return chunkSize
+}
+
+// plannedChunkSize returns the chunk size a copy with info would use
+// after it has been raised to the minimum and aligned
+func plannedChunkSize(ci *fs.ConfigInfo, info fs.ChunkWriterInfo) int64 {
+	chunkSize := info.ChunkSize
+	if chunkSize < info.MinChunkSize {
+		chunkSize = info.MinChunkSize
+	}
+	return alignChunkSize(chunkSize, int64(ci.MultiThreadRangeAlign))
+}
+
+// alignChunkSize returns chunkSize rounded up to a multiple of align
+// so the chunks start on an align boundary. An align <= 0 leaves
+// chunkSize alone.
+func alignChunkSize(chunkSize, align int64) int64 {
+	if align <= 0 {
+		return chunkSize
+	}
+	return (chunkSize + align - 1) / align * align
+}
+
+// startInFlight counts a chunk as being copied, raising the
+// high-water mark in peakInFlight if needed. Call the function
+// returned when the chunk is done.
+func (mc *multiThreadCopyState) startInFlight() func() {
+	n := mc.inFlight.Add(1)
+	for {
+		peak := mc.peakInFlight.Load()
+		if n <= peak || mc.peakInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return func() {
+		mc.inFlight.Add(-1)
+	}
+}
+
+// logPeakInFlight logs the most chunks which were in flight at once,
+// noting if fewer than concurrency were ever in use when there were
+// enough chunks to use them all.
+func (mc *multiThreadCopyState) logPeakInFlight(concurrency int) {
+	peak := int(mc.peakInFlight.Load())
+	if peak < concurrency && peak < mc.numChunks {
+		fs.Debugf(mc.src, "multi-thread copy: only %v of %v streams were ever in use - the copy may be limited by something other than the streams", fs.LogValue("peak", peak), fs.LogValue("streams", concurrency))
+		return
+	}
+	fs.Debugf(mc.src, "multi-thread copy: peak of %v chunks in flight with %v streams", fs.LogValue("peak", peak), fs.LogValue("streams", concurrency))
 }
 
 // Copy a single chunk into place
 func (mc *multiThreadCopyState) copyChunk(ctx context.Context, chunk int, writer fs.ChunkWriter) (err error) {
 	defer func() {
 		if err != nil {
-			fs.Debugf(mc.src, "multi-thread copy: chunk %d/%d failed: %v", chunk+1, mc.numChunks, err)
+			fs.Debugf(mc.src, "multi-thread copy: chunk %v/%v failed: %v", fs.LogValue("chunk", chunk+1), fs.LogValue("total", mc.numChunks), err)
 		}
 	}()
-	start := int64(chunk) * mc.partSize
+	err = mc.markDispatched(chunk)
+	if err != nil {
+		return err
+	}
+	err = mc.checkLimits(ctx)
+	if err != nil {
+		return err
+	}
+	err = mc.simulate.fail(chunk)
+	if err != nil {
+		return err
+	}
+	start, end := chunkRange(chunk, mc.size, mc.firstPartSize, mc.partSize)
 	if start >= mc.size {
 		return nil
 	}
-	end := start + mc.partSize
-	if end > mc.size {
-		end = mc.size
-	}
 	size := end - start
 
-	fs.Debugf(mc.src, "multi-thread copy: chunk %d/%d (%d-%d) size %v starting", chunk+1, mc.numChunks, start, end, fs.SizeSuffix(size))
+	fs.Debugf(mc.src, "multi-thread copy: chunk %v/%v (%v-%v) size %v starting", fs.LogValue("chunk", chunk+1), fs.LogValue("total", mc.numChunks), fs.LogValue("start", start), fs.LogValue("end", end), fs.LogValue("size", fs.SizeSuffix(size)))
+	mc.chunkEvent(chunk, "started", size, nil)
+	defer mc.startInFlight()()
+	defer func() {
+		if err != nil && chunkCancelled(ctx) {
+			mc.chunkEvent(chunk, "requeued", size, nil)
+		} else if err != nil {
+			mc.chunkEvent(chunk, "failed", size, err)
+		} else {
+			mc.chunkEvent(chunk, "finished", size, nil)
+		}
+	}()
+
+	// Wait for a read stream. This is released once the chunk is
+	// buffered, otherwise it is held until the chunk is written.
+	err = acquireStream(ctx, mc.readStreams)
+	if err != nil {
+		return err
+	}
+	readReleased := false
+	releaseRead := func() {
+		if !readReleased {
+			readReleased = true
+			releaseStream(mc.readStreams)
+		}
+	}
+	defer releaseRead()
+
+	// Stop the chunk if it is slower than --multi-thread-min-speed
+	ctx, wd, stopWatchdog := mc.startWatchdog(ctx, chunk)
+	defer stopWatchdog(&err)
+	account := wd.account(mc.acc.AccountRead)
+
+	// Copy directly between local files with copy_file_range or
+	// pread/pwrite if possible
+	if w, ok := writer.(*writerAtChunkWriter); ok && mc.readerAt != nil {
+		err = startChunkWrite(ctx)
+		if err != nil {
+			return err
+		}
+		err = wd.wait(func() error {
+			return acquireStream(ctx, mc.writeStreams)
+		})
+		if err != nil {
+			return err
+		}
+		defer releaseStream(mc.writeStreams)
+		var bytesWritten int64
+		err = file.ErrCopyFileRangeUnsupported
+		if mc.copyFileRange.Load() {
+			bytesWritten, err = w.copyChunkFileRange(ctx, chunk, mc.readerAt, account)
+			if errors.Is(err, file.ErrCopyFileRangeUnsupported) {
+				fs.Debugf(mc.src, "multi-thread copy: falling back to ReadAt/WriteAt: %v", err)
+				mc.copyFileRange.Store(false)
+			}
+		}
+		if errors.Is(err, file.ErrCopyFileRangeUnsupported) {
+			bytesWritten, err = w.copyChunkAt(ctx, chunk, mc.readerAt, account)
+		}
+		if err != nil {
+			return fmt.Errorf("multi-thread copy: failed to copy chunk: %w", err)
+		}
+		return mc.chunkWritten(chunk, start, end, size, bytesWritten)
+	}
 
-	rc, err := Open(ctx, mc.src, &fs.RangeOption{Start: start, End: end - 1})
+	// Time reading the chunk separately from writing it when it is
+	// buffered so the stats show which is holding the copy up
+	readStart := mc.now()
+	var readTime time.Duration
+	openOptions := append(mc.openOptions[:len(mc.openOptions):len(mc.openOptions)], &fs.RangeOption{Start: start, End: end - 1})
+	src, releaseSlot := mc.sourceSlots.acquire(mc.src)
+	defer releaseSlot()
+	rc, err := Open(ctx, src, openOptions...)
 	if err != nil {
 		return fmt.Errorf("multi-thread copy: failed to open source: %w", err)
 	}
-	defer fs.CheckClose(rc, &err)
+	mc.openLatency.CompareAndSwap(0, int64(mc.since(readStart)))
+	cr := &chunkReader{ctx: ctx, mc: mc, src: src, chunk: chunk, in: rc, start: start, end: end}
+	defer func() {
+		fs.CheckClose(cr.in, &err)
+	}()
+	defer func() {
+		if retries := int64(cr.Retries()); retries > 0 {
+			mc.retries.Add(retries)
+			accounting.Stats(ctx).AddMultiThreadChunkRetries(retries)
+		}
+	}()
+
+	// If the backend can verify the chunk hash or the manifest
+	// needs it then calculate it as we read the chunk into the
+	// buffer
+	var in io.Reader = wd.reader(readers.NewContextReader(ctx, cr))
+	var hasher *hash.MultiHasher
+	hashWriter, withHash := writer.(fs.ChunkWriterWithHash)
+	withHash = withHash && mc.chunkHash != hash.None && !mc.noBuffering
+	var hashes hash.Set
+	if withHash {
+		hashes.Add(mc.chunkHash)
+	}
+	if mc.manifest != nil {
+		hashes.Add(mc.manifest.hashType)
+	}
+	if hashes.Count() > 0 {
+		hasher, err = hash.NewMultiHasherTypes(hashes)
+		if err != nil {
+			return fmt.Errorf("multi-thread copy: failed to make chunk hasher: %w", err)
+		}
+		in = io.TeeReader(in, hasher)
+	}
 
 	var rs io.ReadSeeker
+	inFlight := size
+	windowed := false
 	if mc.noBuffering {
 		// Read directly if we are sure we aren't going to seek
 		// and account with accounting
-		rc.SetAccounting(mc.acc.AccountRead)
+		err = startChunkWrite(ctx)
+		if err != nil {
+			return err
+		}
+		rc.SetAccounting(account)
 		rs = rc
+	} else if mc.buffers != nil {
+		// Read the chunk into a caller supplied buffer
+		var buf []byte
+		err = wd.wait(func() error {
+			select {
+			case buf = <-mc.buffers:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			mc.buffers <- buf
+		}()
+		if int64(len(buf)) < size {
+			return fmt.Errorf("multi-thread copy: supplied buffer size %v is smaller than chunk size %v", fs.SizeSuffix(len(buf)), fs.SizeSuffix(size))
+		}
+		_, err = io.ReadFull(in, buf[:size])
+		if err != nil {
+			return fmt.Errorf("multi-thread copy: failed to read chunk: %w", err)
+		}
+		readTime = mc.since(readStart)
+		releaseRead()
+		err = startChunkWrite(ctx)
+		if err != nil {
+			return err
+		}
+		if mc.readHash != nil {
+			err = mc.readHash.check(ctx, chunk, start, end, bytes.NewReader(buf[:size]))
+			if err != nil {
+				return err
+			}
+		}
+		err = mc.inspectChunk(ctx, chunk, bytes.NewReader(buf[:size]))
+		if err != nil {
+			return err
+		}
+		// Account as we go
+		rs = newAccountedBuffer(buf[:size], account)
+	} else if mc.maxReadChunk > 0 && size > mc.maxReadChunk && hashes.Count() == 0 && mc.readHash == nil && mc.inspector == nil {
+		// Read the chunk in windows of --multi-thread-max-read-chunk
+		// as it is written, keeping the backend's part size. The
+		// read stream is held until the chunk is written.
+		err = startChunkWrite(ctx)
+		if err != nil {
+			return err
+		}
+		wr := newChunkWindowReader(cr, in, size, mc.maxReadChunk)
+		wr.SetAccounting(account)
+		rs = wr
+		inFlight = mc.maxReadChunk
+		windowed = true
 	} else {
 		// Read the chunk into buffered reader
 		rw := multipart.NewRW()
 		defer fs.CheckClose(rw, &err)
-		_, err = io.CopyN(rw, rc, size)
+		_, err = io.CopyN(rw, in, size)
 		if err != nil {
 			return fmt.Errorf("multi-thread copy: failed to read chunk: %w", err)
 		}
+		readTime = mc.since(readStart)
+		releaseRead()
+		err = startChunkWrite(ctx)
+		if err != nil {
+			return err
+		}
+		if mc.readHash != nil {
+			err = mc.readHash.check(ctx, chunk, start, end, rw)
+			if err != nil {
+				return err
+			}
+		}
+		err = mc.inspectChunk(ctx, chunk, rw)
+		if err != nil {
+			return err
+		}
 		// Account as we go
-		rw.SetAccounting(mc.acc.AccountRead)
+		rw.SetAccounting(account)
 		rs = rw
 	}
 
-	// Write the chunk
-	bytesWritten, err := writer.WriteChunk(ctx, chunk, rs)
+	// Check the source didn't send more than the chunk
+	if !mc.noBuffering && !windowed && mc.checkRange {
+		err = mc.checkRangeHonoured(cr.in, chunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Track the buffered bytes in the stats until they are written
+	if !mc.noBuffering {
+		stats := accounting.Stats(ctx)
+		stats.AddBytesInFlight(inFlight)
+		defer stats.AddBytesInFlight(-inFlight)
+	}
+
+	// Wait for a write stream
+	err = wd.wait(func() error {
+		return acquireStream(ctx, mc.writeStreams)
+	})
+	if err != nil {
+		return err
+	}
+	defer releaseStream(mc.writeStreams)
+
+	// Write the chunk, stopping early if another chunk fails
+	rs = &cancelReader{ctx: ctx, ReadSeeker: rs}
+	if mc.writeBuffer > 0 {
+		rs = &writeBufferReader{ReadSeeker: rs, size: mc.writeBuffer}
+	}
+	var bytesWritten int64
+	writeStart := mc.now()
+	if withHash {
+		expectedHash, _ := hasher.SumString(mc.chunkHash, false)
+		bytesWritten, err = hashWriter.WriteChunkWithHash(ctx, chunk, rs, expectedHash)
+	} else {
+		bytesWritten, err = writer.WriteChunk(ctx, chunk, rs)
+	}
 	if err != nil {
 		return fmt.Errorf("multi-thread copy: failed to write chunk: %w", err)
 	}
+	if !mc.noBuffering && !windowed {
+		accounting.Stats(ctx).AddMultiThreadChunkTimes(readTime, mc.since(writeStart))
+	}
+	err = mc.chunkWritten(chunk, start, end, size, bytesWritten)
+	if err != nil {
+		return err
+	}
+	if mc.manifest != nil {
+		return mc.manifest.add(chunk, hasher)
+	}
+	return nil
+}
+
+// acquireStream waits for a free stream in streams, returning an
+// error if ctx is cancelled first. A nil streams is unlimited.
+func acquireStream(ctx context.Context, streams chan struct{}) error {
+	if streams == nil {
+		return nil
+	}
+	select {
+	case streams <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseStream returns a stream acquired with acquireStream
+func releaseStream(streams chan struct{}) {
+	if streams != nil {
+		<-streams
+	}
+}
+
+// chunkWritten checks and records that bytesWritten bytes of chunk
+// (start-end) of size bytes have been written
+func (mc *multiThreadCopyState) chunkWritten(chunk int, start, end, size, bytesWritten int64) error {
+	err := checkChunkSize(chunk, mc.numChunks, size, bytesWritten)
+	if err != nil {
+		return err
+	}
+
+	mc.written.Add(bytesWritten)
+	mc.completed.Add(1)
+	mc.markDone(chunk)
+	if mc.eta != nil {
+		mc.acc.SetChunkETA(mc.eta.done(mc.now()))
+	}
+	fs.Debugf(mc.src, "multi-thread copy: chunk %v/%v (%v-%v) size %v finished", fs.LogValue("chunk", chunk+1), fs.LogValue("total", mc.numChunks), fs.LogValue("start", start), fs.LogValue("end", end), fs.LogValue("bytes", fs.SizeSuffix(bytesWritten)))
+	return nil
+}
 
-	fs.Debugf(mc.src, "multi-thread copy: chunk %d/%d (%d-%d) size %v finished", chunk+1, mc.numChunks, start, end, fs.SizeSuffix(bytesWritten))
+// checkChunkSize checks that n, the number of bytes written for
+// chunk, is the expected size, returning an error if not.
+func checkChunkSize(chunk int, numChunks int, size int64, n int64) error {
+	if n < 0 {
+		return fmt.Errorf("multi-thread copy: chunk %d/%d: wrote invalid byte count %d for the expected %d bytes", chunk+1, numChunks, n, size)
+	} else if n > size {
+		return fmt.Errorf("multi-thread copy: chunk %d/%d: wrote %d bytes which is %d more than the expected %d bytes", chunk+1, numChunks, n, n-size, size)
+	} else if n < size {
+		return fmt.Errorf("multi-thread copy: chunk %d/%d: wrote %d bytes which is %d fewer than the expected %d bytes", chunk+1, numChunks, n, size-n, size)
+	}
 	return nil
 }
 
+// chunkRange returns the start and end offsets of chunk when size
+// bytes are split into chunks of chunkSize.
+//
+// If firstChunkSize is set then chunk 0 is firstChunkSize long and
+// the chunks of chunkSize follow it.
+func chunkRange(chunk int, size, firstChunkSize, chunkSize int64) (start, end int64) {
+	switch {
+	case firstChunkSize <= 0:
+		start = int64(chunk) * chunkSize
+		end = start + chunkSize
+	case chunk == 0:
+		end = firstChunkSize
+	default:
+		start = firstChunkSize + int64(chunk-1)*chunkSize
+		end = start + chunkSize
+	}
+	if start > size {
+		start = size
+	}
+	if end > size {
+		end = size
+	}
+	return start, end
+}
+
+// chooseConcurrency returns the number of streams to use given the
+// concurrency the backend asked for and --multi-thread-streams, logging
+// which won and why.
+//
+// A backend concurrency of 0 means the backend has no preference so
+// --multi-thread-streams is used. Otherwise the backend concurrency
+// is used if it is higher than --multi-thread-streams or if
+// --multi-thread-streams wasn't set explicitly, unless
+// --multi-thread-streams-strict is set.
+//
+// A negative backend concurrency is an error.
+func chooseConcurrency(ci *fs.ConfigInfo, src fs.ObjectInfo, backend, streams int) (int, error) {
+	switch {
+	case backend < 0:
+		return 0, fmt.Errorf("multi-thread copy: backend returned invalid concurrency %d", backend)
+	case backend == 0:
+		fs.Debugf(src, "multi-thread copy: using --multi-thread-streams %d as the backend has no preferred concurrency", streams)
+		return streams, nil
+	case backend == streams:
+		return streams, nil
+	case !ci.MultiThreadSet:
+		fs.Debugf(src, "multi-thread copy: using backend concurrency of %d instead of --multi-thread-streams %d as --multi-thread-streams wasn't set", backend, streams)
+		return backend, nil
+	case ci.MultiThreadStreamsStrict:
+		fs.Debugf(src, "multi-thread copy: using --multi-thread-streams %d instead of backend concurrency of %d as --multi-thread-streams-strict is set", streams, backend)
+		return streams, nil
+	case backend > streams:
+		fs.Infof(src, "multi-thread copy: using backend concurrency of %d instead of --multi-thread-streams %d as it is higher - set --multi-thread-streams-strict to use %d", backend, streams, streams)
+		return backend, nil
+	default:
+		fs.Debugf(src, "multi-thread copy: using --multi-thread-streams %d instead of backend concurrency of %d as it is higher", streams, backend)
+		return streams, nil
+	}
+}
+
 // Given a file size and a chunkSize
 // it returns the number of chunks, so that chunkSize * numChunks >= size
 func calculateNumChunks(size int64, chunkSize int64) int {
@@ -126,11 +1313,60 @@ func calculateNumChunks(size int64, chunkSize int64) int {
 	return int(numChunks)
 }
 
+// MultiThreadCopyResult describes how a multi-thread copy went
+type MultiThreadCopyResult struct {
+	Chunks          int                  // number of chunks the file was split into
+	ChunkSize       int64                // size of the chunks - the last may be smaller
+	Concurrency     int                  // number of chunks copied in parallel
+	Retries         int                  // number of times the source was reopened after a read error
+	Bytes           int64                // number of bytes written to the destination
+	Duration        time.Duration        // time taken for the copy
+	WriteMethod     string               // "OpenChunkWriter" or "OpenWriterAt" - how the destination was written
+	Manifest        *MultiThreadManifest // the chunks written with their checksums if --multi-thread-manifest or WithMultiThreadManifest is set
+	PeakInFlight    int                  // most chunks which were being copied at once
+	CompletedChunks []int                // chunks which were written in full, in order
+	Partial         fs.Object            // the partly written destination kept by --multi-thread-keep-partial if the copy was cancelled
+}
+
 // Copy src to (f, remote) using streams download threads. It tries to use the OpenChunkWriter feature
 // and if that's not available it creates an adapter using OpenWriterAt
 func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object, concurrency int, tr *accounting.Transfer, options ...fs.OpenOption) (newDst fs.Object, err error) {
+	newDst, _, err = MultiThreadCopyWithResult(ctx, f, remote, src, concurrency, tr, options...)
+	return newDst, err
+}
+
+// MultiThreadCopyWithResult copies src to (f, remote) using
+// concurrency streams in the same way as the multi-thread copies done
+// by Copy, accounting the transfer to tr.
+//
+// f must support OpenChunkWriter or OpenWriterAt.
+//
+// It returns a description of how the copy went in result. This is
+// never nil and is filled in as far as the copy got if an error is
+// returned.
+func MultiThreadCopyWithResult(ctx context.Context, f fs.Fs, remote string, src fs.Object, concurrency int, tr *accounting.Transfer, options ...fs.OpenOption) (newDst fs.Object, result *MultiThreadCopyResult, err error) {
+	result = &MultiThreadCopyResult{}
+	clock := getMultiThreadClock(ctx)
+	startTime := clock.Now()
+	defer func() {
+		result.Duration = clock.Now().Sub(startTime)
+	}()
+	newDst, err = multiThreadCopyResult(ctx, f, remote, src, concurrency, tr, result, options...)
+	return newDst, result, err
+}
+
+// multiThreadCopyResult does the work for MultiThreadCopyWithResult filling in result
+func multiThreadCopyResult(ctx context.Context, f fs.Fs, remote string, src fs.Object, concurrency int, tr *accounting.Transfer, result *MultiThreadCopyResult, options ...fs.OpenOption) (newDst fs.Object, err error) {
 	openChunkWriter := f.Features().OpenChunkWriter
 	ci := fs.GetConfig(ctx)
+
+	// Check the destination may be overwritten before doing any work
+	err = checkMultiThreadOverwrite(ctx, f, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	result.WriteMethod = "OpenChunkWriter"
 	noBuffering := false
 	usingOpenWriterAt := false
 	if openChunkWriter == nil {
@@ -143,6 +1379,7 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		fs.Debugf(src, "multi-thread copy: disabling buffering because destination uses OpenWriterAt")
 		noBuffering = true
 		usingOpenWriterAt = true
+		result.WriteMethod = "OpenWriterAt"
 	} else if src.Fs().Features().IsLocal {
 		// If the source fs is local we don't need to buffer
 		fs.Debugf(src, "multi-thread copy: disabling buffering because source is local disk")
@@ -160,39 +1397,212 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 	if src.Size() == 0 {
 		return nil, fmt.Errorf("multi-thread copy: can't copy zero sized file")
 	}
+	simulate, err := parseSimulatedFailures(ci.MultiThreadSimulateFailure)
+	if err != nil {
+		return nil, fserrors.NoRetryError(err)
+	}
+	if simulate != nil {
+		fs.Logf(src, "multi-thread copy: simulating chunk failures with --multi-thread-simulate-failure %q", ci.MultiThreadSimulateFailure)
+	}
+
+	// For local to local copies read the source with ReadAt so the
+	// chunks can be copied with pread/pwrite
+	var readerAt fs.ReaderAtCloser
+	if usingOpenWriterAt && ci.MultiThreadLocal && src.Fs().Features().IsLocal && f.Features().IsLocal && getMultiThreadChunkInspector(ctx) == nil {
+		if do, ok := src.(fs.OpenReaderAter); ok {
+			readerAt, err = do.OpenReaderAt(ctx)
+			if err != nil {
+				fs.Debugf(src, "multi-thread copy: not using ReadAt as failed to open source: %v", err)
+				readerAt = nil
+			} else {
+				fs.Debugf(src, "multi-thread copy: copying chunks with ReadAt/WriteAt")
+				defer fs.CheckClose(readerAt, &err)
+			}
+		}
+	}
+
+	// Write to a temporary name and rename it into place when done so
+	// readers never see a partially written file, unless Copy is
+	// already doing this
+	finalRemote := remote
+	if ci.MultiThreadAtomic && usingOpenWriterAt {
+		features := f.Features()
+		if features.Move == nil || !features.PartialUploads {
+			fs.Debugf(src, "multi-thread copy: ignoring --multi-thread-atomic as %v doesn't show partial uploads or can't rename them", f)
+		} else if !strings.HasSuffix(remote, ci.PartialSuffix) {
+			remote, err = partialRemote(remote, ci.PartialSuffix)
+			if err != nil {
+				return nil, fserrors.NoRetryError(err)
+			}
+			fs.Debugf(src, "multi-thread copy: writing to %q then renaming to %q", remote, finalRemote)
+		}
+	}
+
+	// If resuming uploads see if there is an upload to resume
+	resumeKey := ""
+	resuming := false
+	if ci.MultiThreadResume && !usingOpenWriterAt {
+		resumeKey = multiThreadResumeKey(ctx, f, remote, src)
+		if state := loadMultiThreadResumeState(resumeKey); state != nil {
+			fs.Debugf(src, "multi-thread copy: attempting to resume upload %q", state.UploadID)
+			options = append(options[:len(options):len(options)], &fs.ResumeUploadOption{UploadID: state.UploadID})
+			resuming = true
+		}
+	}
+
+	// Upload a single chunk in one request if the backend can, rather
+	// than paying for the requests to start and finish a multipart
+	// upload. This is decided before opening the chunk writer so no
+	// upload is started.
+	if plan := f.Features().PlanChunkWriter; plan != nil && !usingOpenWriterAt && !resuming {
+		planInfo, err := plan(ctx, remote, src, options...)
+		if err != nil {
+			fs.Debugf(src, "multi-thread copy: failed to plan chunk writer: %v", err)
+		} else if planInfo.SingleShotUpload && calculateNumChunks(src.Size(), plannedChunkSize(ci, planInfo)) == 1 {
+			fs.Debugf(src, "multi-thread copy: using a single shot upload as there is only 1 chunk")
+			return nil, fmt.Errorf("multi-thread copy: only 1 chunk: %w", fs.ErrorCantMultiThread)
+		}
+	}
 
 	info, chunkWriter, err := openChunkWriter(ctx, remote, src, options...)
+	if errors.Is(err, fs.ErrorCantMultiThread) {
+		// Let the caller retry with a single stream
+		fs.Debugf(src, "multi-thread copy: backend can't use multi-thread transfer: %v", err)
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("multi-thread copy: failed to open chunk writer: %w", err)
 	}
 
+	// Save the upload ID so we can resume it if we fail
+	leavePartsOnError := info.LeavePartsOnError
+	if resumeKey != "" && info.UploadID != "" {
+		err = saveMultiThreadResumeState(resumeKey, &multiThreadResumeState{Remote: remote, UploadID: info.UploadID})
+		if err != nil {
+			fs.Errorf(src, "multi-thread copy: failed to save resume state: %v", err)
+		} else {
+			leavePartsOnError = true
+			updateMultiThreadDirResumeState(f, remote, resumeKey, false)
+		}
+	}
+
 	uploadCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	uploadedOK := false
 	defer atexit.OnError(&err, func() {
 		cancel()
-		if info.LeavePartsOnError || uploadedOK {
+		if leavePartsOnError || uploadedOK {
+			return
+		}
+		// err is nil if we are being called at exit
+		if ci.MultiThreadKeepPartial && usingOpenWriterAt && (err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+			keepPartialFile(ctx, f, remote, src, chunkWriter, result)
+			return
+		}
+		if ci.MultiThreadKeepPartsOnError {
+			fs.Logf(src, "multi-thread copy: not cleaning up failed transfer as --multi-thread-keep-parts-on-error is set - the parts written will be left on the destination and may cost money until removed")
 			return
 		}
 		fs.Debugf(src, "multi-thread copy: cancelling transfer on exit")
-		abortErr := chunkWriter.Abort(ctx)
+		// ctx is usually cancelled if we are aborting so use a
+		// fresh one so the abort can still reach the backend
+		abortCtx, abortCancel := context.WithTimeout(fs.CopyConfig(context.Background(), ctx), abortTimeout)
+		defer abortCancel()
+		abortErr := chunkWriter.Abort(abortCtx)
 		if abortErr != nil {
 			fs.Debugf(src, "multi-thread copy: abort failed: %v", abortErr)
 		}
 	})()
 
+	// If the chunk writer promises not to seek its chunks then
+	// stream them rather than buffering them
+	if info.NoSeekNeeded && !noBuffering {
+		fs.Debugf(src, "multi-thread copy: disabling buffering because chunk writer has set NoSeekNeeded")
+		noBuffering = true
+	}
+
+	// The chunks need buffering to calculate their hashes before
+	// they are written
+	if _, ok := chunkWriter.(fs.ChunkWriterWithHash); ok && info.ChunkHashType != hash.None && noBuffering {
+		fs.Debugf(src, "multi-thread copy: enabling buffering to calculate %v hash of chunks", info.ChunkHashType)
+		noBuffering = false
+	}
+	if ci.MultiThreadChecksumOnRead && noBuffering {
+		fs.Debugf(src, "multi-thread copy: enabling buffering to check the source reads")
+		noBuffering = false
+	}
+	inspector := getMultiThreadChunkInspector(ctx)
+	if inspector != nil && noBuffering {
+		fs.Debugf(src, "multi-thread copy: enabling buffering to inspect the chunks")
+		noBuffering = false
+	}
+	manifestCollector := getMultiThreadManifestCollector(ctx)
+	wantManifest := ci.MultiThreadManifest || manifestCollector != nil
+	if wantManifest && noBuffering {
+		fs.Debugf(src, "multi-thread copy: enabling buffering to calculate the chunk hashes for the manifest")
+		noBuffering = false
+	}
+
+	// Don't use chunks smaller than the backend supports
+	if info.MinChunkSize > 0 && info.ChunkSize < info.MinChunkSize {
+		if ci.MultiThreadChunkSizeSet {
+			return nil, fserrors.NoRetryError(fmt.Errorf("multi-thread copy: --multi-thread-chunk-size %v is below the minimum chunk size %v for %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(info.MinChunkSize), f))
+		}
+		fs.Logf(src, "multi-thread copy: increasing chunk size %v to the minimum chunk size %v for %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(info.MinChunkSize), f)
+		info.ChunkSize = info.MinChunkSize
+	}
+
+	// Make the chunks bigger if there would be more than the backend supports
+	if info.MaxChunks > 0 && calculateNumChunks(src.Size(), info.ChunkSize) > info.MaxChunks {
+		chunkSize := (src.Size() + int64(info.MaxChunks) - 1) / int64(info.MaxChunks)
+		fs.Debugf(src, "multi-thread copy: increasing chunk size %v to %v as %v supports at most %d chunks", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(chunkSize), f, info.MaxChunks)
+		info.ChunkSize = chunkSize
+	}
+
+	// Align the chunks to the source's preferred range boundaries
+	rangeAlign := int64(ci.MultiThreadRangeAlign)
+	if chunkSize := alignChunkSize(info.ChunkSize, rangeAlign); chunkSize != info.ChunkSize {
+		fs.Debugf(src, "multi-thread copy: increasing chunk size %v to %v to align to --multi-thread-range-align %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(chunkSize), fs.SizeSuffix(rangeAlign))
+		info.ChunkSize = chunkSize
+	}
+
 	if info.ChunkSize > src.Size() {
 		fs.Debugf(src, "multi-thread copy: chunk size %v was bigger than source file size %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(src.Size()))
 		info.ChunkSize = src.Size()
 	}
 
-	// Use the backend concurrency if it is higher than --multi-thread-streams or if --multi-thread-streams wasn't set explicitly
-	if !ci.MultiThreadSet || info.Concurrency > concurrency {
-		fs.Debugf(src, "multi-thread copy: using backend concurrency of %d instead of --multi-thread-streams %d", info.Concurrency, concurrency)
-		concurrency = info.Concurrency
+	// Make sure the adapter agrees with any changes to the chunk size
+	if w, ok := chunkWriter.(*writerAtChunkWriter); ok && w.chunkSize != info.ChunkSize {
+		w.setChunkSize(info.ChunkSize)
+	}
+
+	concurrency, err = chooseConcurrency(ci, src, info.Concurrency, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and write with different numbers of streams if requested
+	readStreams, writeStreams := concurrency, concurrency
+	if ci.MultiThreadReadStreams > 0 {
+		readStreams = ci.MultiThreadReadStreams
+	}
+	if ci.MultiThreadWriteStreams > 0 {
+		writeStreams = ci.MultiThreadWriteStreams
+	}
+	readStreams = limitStreamsToConnections(ctx, src.Fs(), "read", readStreams)
+	writeStreams = limitStreamsToConnections(ctx, f, "write", writeStreams)
+	if readStreams != writeStreams {
+		fs.Debugf(src, "multi-thread copy: using %d read streams and %d write streams", readStreams, writeStreams)
+	}
+	concurrency = readStreams
+	if writeStreams > concurrency {
+		concurrency = writeStreams
 	}
 
 	numChunks := calculateNumChunks(src.Size(), info.ChunkSize)
+
+	// This means a file of a single chunk is copied by the serial
+	// path below without starting any goroutines
 	if concurrency > numChunks {
 		fs.Debugf(src, "multi-thread copy: number of streams %d was bigger than number of chunks %d", concurrency, numChunks)
 		concurrency = numChunks
@@ -202,6 +1612,22 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		concurrency = 1
 	}
 
+	// Copy the chunks strictly in order if requested
+	if ci.MultiThreadSerialDebug && concurrency != 1 {
+		fs.Debugf(src, "multi-thread copy: using 1 stream instead of %d because --multi-thread-serial-debug is set", concurrency)
+		concurrency = 1
+	}
+
+	// Share --multi-thread-total-streams with the other transfers
+	reserved := multiThreadStreams.reserve(ci.MultiThreadTotalStreams, concurrency)
+	defer func() {
+		multiThreadStreams.release(reserved)
+	}()
+	if reserved < concurrency {
+		fs.Debugf(src, "multi-thread copy: using %d streams instead of %d as other transfers are using the rest of --multi-thread-total-streams %d", reserved, concurrency, ci.MultiThreadTotalStreams)
+		concurrency = reserved
+	}
+
 	g, gCtx := errgroup.WithContext(uploadCtx)
 	g.SetLimit(concurrency)
 
@@ -212,40 +1638,325 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		partSize:    info.ChunkSize,
 		numChunks:   numChunks,
 		noBuffering: noBuffering,
+		chunkHash:   info.ChunkHashType,
+		readerAt:    readerAt,
+		checkRange:  ci.MultiThreadCheckRange,
+		simulate:    simulate,
+		clock:       getMultiThreadClock(ctx),
+	}
+	if ci.MultiThreadMaxReadChunk > 0 && !noBuffering && info.ChunkSize > int64(ci.MultiThreadMaxReadChunk) {
+		mc.maxReadChunk = int64(ci.MultiThreadMaxReadChunk)
+		fs.Debugf(src, "multi-thread copy: reading chunks of %v in windows of %v", fs.SizeSuffix(info.ChunkSize), ci.MultiThreadMaxReadChunk)
+	}
+	mc.copyFileRange.Store(readerAt != nil && ci.MultiThreadCopyFileRange && file.CopyFileRangeImplemented)
+	// Pass the chunks to chunk writers which ask for it in blocks
+	// of --multi-thread-write-buffer-size
+	if info.WriteBuffer {
+		mc.writeBuffer = int64(ci.MultiThreadWriteBufferSize)
+		for _, option := range options {
+			if _, ok := option.(*fs.NoWriteBufferOption); ok && mc.writeBuffer > 0 {
+				fs.Debugf(src, "multi-thread copy: write buffer disabled by %v", option)
+				mc.writeBuffer = 0
+			}
+		}
+		if mc.writeBuffer > 0 {
+			fs.Debugf(src, "multi-thread copy: write buffer set to %v", fs.SizeSuffix(mc.writeBuffer))
+		}
+	}
+	// Only limit the reads or writes if they are fewer than the
+	// chunks being copied at once
+	if readStreams < concurrency {
+		mc.readStreams = make(chan struct{}, readStreams)
 	}
+	if writeStreams < concurrency {
+		mc.writeStreams = make(chan struct{}, writeStreams)
+	}
+	// Open the source with the same headers as a single stream copy
+	for _, option := range ci.DownloadHeaders {
+		mc.openOptions = append(mc.openOptions, option)
+	}
+	mc.job, _ = jobs.GetJob(ctx)
+	mc.buffers = getMultiThreadBuffers(ctx)
+	mc.onOpen = getMultiThreadOnOpen(ctx)
+	mc.shouldRetry = getMultiThreadRetryClassifier(ctx)
+	mc.sortOrder = ci.MultiThreadSort
+	mc.minSpeed = ci.MultiThreadMinSpeed
+	mc.inspector = inspector
+	if ci.MultiThreadReuseReader && readerAt == nil {
+		mc.sourceSlots = newSourceSlots(gCtx, src)
+		defer mc.sourceSlots.close()
+	}
+	if mc.sortOrder != fs.MultiThreadSortDefault {
+		fs.Debugf(src, "multi-thread copy: copying chunks in %v order", mc.sortOrder)
+	}
+	mc.rangeAlign = rangeAlign
+	mc.minPartSize = info.MinChunkSize
+	result.Chunks = numChunks
+	result.ChunkSize = info.ChunkSize
+	result.Concurrency = concurrency
+	tr.SetStreams(concurrency)
+	tr.SetWriteMethod(result.WriteMethod)
+	defer func() {
+		result.Retries = int(mc.retries.Load())
+		result.Bytes = mc.written.Load()
+		result.PeakInFlight = int(mc.peakInFlight.Load())
+		result.CompletedChunks = mc.doneChunks()
+		tr.SetPeakStreams(result.PeakInFlight)
+	}()
 
 	// Make accounting
-	mc.acc = tr.Account(gCtx, nil)
+	mc.acc = multiThreadAccount(gCtx, src, tr)
 
-	fs.Debugf(src, "Starting multi-thread copy with %d chunks of size %v with %v parallel streams", mc.numChunks, fs.SizeSuffix(mc.partSize), concurrency)
-	for chunk := 0; chunk < mc.numChunks; chunk++ {
-		// Fail fast, in case an errgroup managed function returns an error
-		if gCtx.Err() != nil {
-			break
+	// Chunks already written in a resumed upload
+	completedChunks := make(map[int]bool, len(info.CompletedChunks))
+	for _, chunk := range info.CompletedChunks {
+		completedChunks[chunk] = true
+	}
+	if len(completedChunks) > 0 {
+		fs.Infof(src, "multi-thread copy: resuming upload with %v/%v chunks already written", fs.LogValue("completed", len(completedChunks)), fs.LogValue("total", mc.numChunks))
+	}
+	mc.eta = newChunkETA(mc.numChunks-len(completedChunks), mc.now())
+	mc.completed.Store(int64(len(completedChunks)))
+
+	// Tell the embedder the chunk writer is open before dispatching any chunks
+	if mc.onOpen != nil {
+		openInfo := info
+		openInfo.Concurrency = concurrency
+		mc.onOpen(openInfo)
+	}
+
+	// Use content defined chunks if the chunk boundaries are ours to choose
+	cdcWriter, cdc := chunkWriter.(*writerAtChunkWriter)
+	cdc = cdc && ci.MultiThreadCDC && inspector == nil
+	if ci.MultiThreadCDC && inspector != nil {
+		fs.Debugf(src, "multi-thread copy: ignoring --multi-thread-cdc as the chunks are being inspected")
+	}
+	if ci.MultiThreadCDC && !cdc {
+		fs.Debugf(src, "multi-thread copy: ignoring --multi-thread-cdc as destination doesn't use OpenWriterAt")
+	}
+	// Content defined chunks can be any size so can't keep to a minimum
+	if cdc && info.MinChunkSize > 0 {
+		fs.Debugf(src, "multi-thread copy: ignoring --multi-thread-cdc as %v has a minimum chunk size %v", f, fs.SizeSuffix(info.MinChunkSize))
+		cdc = false
+	}
+
+	// Check the data read from the source if requested
+	if ci.MultiThreadChecksumOnRead {
+		if cdc || readerAt != nil {
+			fs.Debugf(src, "multi-thread copy: ignoring --multi-thread-checksum-on-read as the source isn't read in chunks")
+		} else {
+			mc.readHash, err = newReadHasher(ctx, src, len(completedChunks) == 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Checksum the chunks for the manifest if requested
+	if wantManifest {
+		if cdc || readerAt != nil {
+			fs.Debugf(src, "multi-thread copy: not making a manifest as the source isn't read in chunks")
+		} else {
+			hashType := info.ChunkHashType
+			if hashType == hash.None {
+				hashType = hash.MD5
+			}
+			mc.manifest = newManifestBuilder(hashType)
+		}
+	}
+
+	// Size the remaining chunks from the speed of the first one if
+	// the chunk boundaries are ours to choose
+	if w, ok := chunkWriter.(*writerAtChunkWriter); ok && !cdc && ci.MultiThreadAdaptiveChunk && len(completedChunks) == 0 && mc.numChunks > 1 {
+		err = mc.adaptChunkSize(gCtx, w)
+		if err != nil {
+			return nil, err
+		}
+		completedChunks[0] = true
+		mc.eta = newChunkETA(mc.numChunks-1, mc.now())
+		result.Chunks = mc.numChunks
+		result.ChunkSize = mc.partSize
+		if concurrency > mc.numChunks-1 {
+			concurrency = mc.numChunks - 1
+			g.SetLimit(concurrency)
+			result.Concurrency = concurrency
+			tr.SetStreams(concurrency)
+		}
+	}
+
+	// Choose the number of streams from the bandwidth-delay product
+	// measured on the first chunk
+	if ci.MultiThreadStreamsAuto && !cdc && mc.readerAt == nil && len(completedChunks) == 0 && concurrency > 1 && mc.numChunks > 1 {
+		streams, err := mc.autoStreams(gCtx, chunkWriter, concurrency, bwLimitBandwidth(ci, mc.now()), int64(ci.MultiThreadStreamWindow))
+		if err != nil {
+			return nil, err
+		}
+		completedChunks[0] = true
+		mc.eta = newChunkETA(mc.numChunks-1, mc.now())
+		if streams < concurrency {
+			if reserved > streams {
+				multiThreadStreams.release(reserved - streams)
+				reserved = streams
+			}
+			concurrency = streams
+			g.SetLimit(concurrency)
+			result.Concurrency = concurrency
+			tr.SetStreams(concurrency)
 		}
-		chunk := chunk
-		g.Go(func() error {
-			return mc.copyChunk(gCtx, chunk, chunkWriter)
-		})
 	}
 
-	err = g.Wait()
+	fs.Debugf(src, "Starting multi-thread copy with %v chunks of size %v with %v parallel streams", fs.LogValue("total", mc.numChunks), fs.LogValue("chunkSize", fs.SizeSuffix(mc.partSize)), fs.LogValue("streams", concurrency))
+	// If the backend needs the final chunk written last then hold
+	// it back until all the preceding chunks have been written
+	finalChunk := -1
+	if info.FinalChunkLast {
+		finalChunk = mc.numChunks - 1
+	}
+	if cdc {
+		fs.Debugf(src, "multi-thread copy: using content defined chunks with %v parallel streams", fs.LogValue("streams", concurrency))
+		cdcErr := mc.copyCDC(gCtx, g, cdcWriter, newCDCChunker(cdcAverageSize))
+		// Wait for the chunks in progress even if reading failed
+		err = g.Wait()
+		if err == nil {
+			err = cdcErr
+		}
+		mc.numChunks = int(mc.completed.Load())
+		result.Chunks = mc.numChunks
+		result.ChunkSize = 0
+	} else if concurrency == 1 {
+		// Copy the chunks in order without starting any goroutines
+		err = mc.copyChunksSerial(gCtx, completedChunks, chunkWriter, finalChunk)
+	} else {
+		var preceding sync.WaitGroup
+		var pauseErr error
+		dispatched := 0
+		inflight := newInflightBytes(int64(ci.MultiThreadMaxInflightBytes))
+		if inflight != nil {
+			fs.Debugf(src, "multi-thread copy: limiting chunks in flight to %v", fs.LogValue("maxInflight", ci.MultiThreadMaxInflightBytes))
+		}
+		queue := mc.pendingChunks(completedChunks, finalChunk)
+	rounds:
+		for len(queue) > 0 {
+			for i, chunk := range queue {
+				if chunk == finalChunk {
+					fs.Debugf(src, "multi-thread copy: waiting for preceding chunks to be written before writing the final chunk")
+					preceding.Wait()
+					var held bool
+					if queue, held = mc.holdFinalChunk(queue, i); held {
+						continue rounds
+					}
+				}
+				// Stagger the start of the initial batch of chunks
+				if dispatched > 0 && dispatched < concurrency {
+					mc.dispatchJitter(gCtx, ci.MultiThreadDispatchJitter)
+				}
+				dispatched++
+				// Stop dispatching chunks while the rc job is paused
+				pauseErr = mc.waitIfPaused(gCtx)
+				if pauseErr != nil {
+					break rounds
+				}
+				// Fail fast, in case an errgroup managed function returns an error
+				if gCtx.Err() != nil {
+					break rounds
+				}
+				// Wait for room under --multi-thread-max-inflight-bytes
+				start, end := chunkRange(chunk, mc.size, mc.firstPartSize, mc.partSize)
+				acquired, err := inflight.acquire(gCtx, end-start)
+				if err != nil {
+					break rounds
+				}
+				// Wait for a goroutine under --multi-thread-max-goroutines
+				releaseGoroutine, err := multiThreadGoroutines.acquire(gCtx, ci.MultiThreadMaxGoroutines)
+				if err != nil {
+					inflight.release(acquired)
+					break rounds
+				}
+				chunk := chunk
+				preceding.Add(1)
+				g.Go(func() error {
+					defer preceding.Done()
+					defer releaseGoroutine()
+					defer inflight.release(acquired)
+					return mc.copyChunkCancellable(gCtx, chunk, chunkWriter)
+				})
+			}
+			// Copy the chunks cancelled with job/cancelchunk
+			// once the rest have finished
+			preceding.Wait()
+			queue = mc.takeRequeued()
+		}
+		err = g.Wait()
+		if err == nil {
+			err = pauseErr
+		}
+		if err == nil && len(queue) > 0 {
+			err = mc.errChunksNotCopied(gCtx, queue)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil, fmt.Errorf("multi-thread copy: cancelled after %d/%d chunks completed: %w", mc.completed.Load(), mc.numChunks, err)
+	}
 	if err != nil {
 		return nil, err
 	}
-	err = chunkWriter.Close(ctx)
+	if mc.readHash != nil {
+		err = mc.readHash.finish()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = closeChunkWriter(ctx, chunkWriter, ci.MultiThreadFinalizeTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("multi-thread copy: failed to close object after copy: %w", err)
+		return nil, err
 	}
 	uploadedOK = true // file is definitely uploaded OK so no need to abort
+	if resumeKey != "" {
+		removeMultiThreadResumeState(resumeKey)
+		updateMultiThreadDirResumeState(f, remote, resumeKey, true)
+	}
 
 	obj, err := f.NewObject(ctx, remote)
 	if err != nil {
 		return nil, fmt.Errorf("multi-thread copy: failed to find object after copy: %w", err)
 	}
 
-	// OpenWriterAt doesn't set metadata so we need to set it on completion
-	if usingOpenWriterAt {
+	// Check the backend finalized the object with the right size
+	if obj.Size() >= 0 && obj.Size() != src.Size() {
+		if removeErr := obj.Remove(ctx); removeErr != nil {
+			fs.Errorf(obj, "multi-thread copy: failed to remove wrongly sized object: %v", removeErr)
+		}
+		return nil, fmt.Errorf("multi-thread copy: destination is %d bytes after finalizing but source is %d bytes", obj.Size(), src.Size())
+	}
+
+	// Check the backend put the chunks at the right offsets
+	if _, isWriterAt := chunkWriter.(*writerAtChunkWriter); ci.MultiThreadCheckBoundaries && !isWriterAt && mc.numChunks > 1 {
+		err = mc.checkBoundaries(ctx, obj, concurrency)
+		if err != nil {
+			if removeErr := obj.Remove(ctx); removeErr != nil {
+				fs.Errorf(obj, "multi-thread copy: failed to remove corrupted object: %v", removeErr)
+			}
+			return nil, err
+		}
+	}
+
+	if ci.MultiThreadVerify {
+		err = multiThreadVerify(ctx, src, obj, info.ChunkSize, concurrency, chunkWriter)
+		if err != nil {
+			if removeErr := obj.Remove(ctx); removeErr != nil {
+				fs.Errorf(obj, "multi-thread copy: failed to remove corrupted object: %v", removeErr)
+			}
+			return nil, err
+		}
+	}
+
+	if mc.manifest != nil {
+		mc.finishManifest(ctx, f, finalRemote, result, manifestCollector)
+	}
+
+	// Set the metadata on completion if the chunk writer didn't
+	// set it from the options it was opened with
+	if info.MetadataAfterClose {
 		setModTime := true
 		if ci.Metadata {
 			do, ok := obj.(fs.SetMetadataer)
@@ -267,64 +1978,638 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 			}
 		}
 		if setModTime {
+			// Wrapping backends may wrap the errors saying
+			// the modification time can't be set
 			err = obj.SetModTime(ctx, src.ModTime(ctx))
-			switch err {
-			case nil, fs.ErrorCantSetModTime, fs.ErrorCantSetModTimeWithoutDelete:
-			default:
+			if errors.Is(err, fs.ErrorCantSetModTime) || errors.Is(err, fs.ErrorCantSetModTimeWithoutDelete) {
+				fs.Debugf(obj, "multi-thread copy: can't set modification time: %v", err)
+			} else if err != nil {
 				return nil, fmt.Errorf("multi-thread copy: failed to set modification time: %w", err)
 			}
 		}
 	}
 
-	fs.Debugf(src, "Finished multi-thread copy with %d parts of size %v", mc.numChunks, fs.SizeSuffix(mc.partSize))
+	// Rename the temporary file into place
+	if remote != finalRemote {
+		movedObj, err := f.Features().Move(ctx, obj, finalRemote)
+		if err != nil {
+			if removeErr := obj.Remove(ctx); removeErr != nil {
+				fs.Errorf(obj, "multi-thread copy: failed to remove temporary file: %v", removeErr)
+			}
+			return nil, fmt.Errorf("multi-thread copy: failed to rename temporary file to %q: %w", finalRemote, err)
+		}
+		fs.Debugf(src, "multi-thread copy: renamed %q to %q", remote, finalRemote)
+		obj = movedObj
+	}
+
+	fs.Debugf(src, "Finished multi-thread copy with %v parts of size %v", fs.LogValue("total", mc.numChunks), fs.LogValue("chunkSize", fs.SizeSuffix(mc.partSize)))
+	mc.logPeakInFlight(concurrency)
 	return obj, nil
 }
 
+// keepPartialFile closes the chunk writer of a cancelled OpenWriterAt
+// copy without removing the file for --multi-thread-keep-partial and
+// returns the file in result.Partial.
+//
+// result.CompletedChunks says which chunks of it were written so a
+// later run can copy just the others.
+func keepPartialFile(ctx context.Context, f fs.Fs, remote string, src fs.Object, chunkWriter fs.ChunkWriter, result *MultiThreadCopyResult) {
+	// ctx is usually cancelled so use a fresh one
+	keepCtx, keepCancel := context.WithTimeout(fs.CopyConfig(context.Background(), ctx), abortTimeout)
+	defer keepCancel()
+	err := chunkWriter.Close(keepCtx)
+	if err != nil {
+		fs.Errorf(src, "multi-thread copy: failed to close partial file: %v", err)
+	}
+	result.Partial, err = f.NewObject(keepCtx, remote)
+	if err != nil {
+		fs.Errorf(src, "multi-thread copy: failed to find partial file: %v", err)
+	}
+	fs.Logf(src, "multi-thread copy: keeping partial file %q with %v/%v chunks written as --multi-thread-keep-partial is set", remote, fs.LogValue("completed", len(result.CompletedChunks)), fs.LogValue("total", result.Chunks))
+}
+
+// multiThreadVerify reads dst back and checks its hash against the
+// hash of src.
+//
+// CRC32 is preferred if the source supports it as it can be read in
+// parallel chunks of partSize and the results combined, otherwise the
+// destination is read sequentially.
+func multiThreadVerify(ctx context.Context, src fs.Object, dst fs.Object, partSize int64, concurrency int, chunkWriter fs.ChunkWriter) error {
+	hashType := multiThreadVerifyHashType(src.Fs().Hashes())
+	if hashType == hash.None {
+		fs.Debugf(src, "multi-thread copy: not verifying as source has no hashes")
+		return nil
+	}
+	srcSum, err := src.Hash(ctx, hashType)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to read source %v hash: %w", hashType, err)
+	}
+	if srcSum == "" {
+		fs.Debugf(src, "multi-thread copy: not verifying as source has no %v hash", hashType)
+		return nil
+	}
+	// Use the hash of the data as it was written if possible
+	// rather than reading the destination back
+	var dstSum string
+	if w, ok := chunkWriter.(*writerAtChunkWriter); ok && w.hashes.Contains(hashType) {
+		dstSum, err = w.writtenSum(hashType)
+		if err != nil {
+			fs.Debugf(src, "multi-thread copy: reading destination back as can't use hash of data written: %v", err)
+		}
+	}
+	if dstSum == "" {
+		dstSum, err = multiThreadHash(ctx, dst, hashType, partSize, concurrency)
+		if err != nil {
+			return fmt.Errorf("multi-thread copy: failed to read destination %v hash: %w", hashType, err)
+		}
+	}
+	if !hash.Equals(srcSum, dstSum) {
+		return fmt.Errorf("multi-thread copy: corrupted on transfer: %v hashes differ src %q vs dst %q", hashType, srcSum, dstSum)
+	}
+	fs.Debugf(src, "multi-thread copy: verified %v hash %q", hashType, dstSum)
+	return nil
+}
+
+// multiThreadVerifyHashType returns the hash to verify with from the
+// source hashes, preferring CRC32 as it can be read in parallel.
+func multiThreadVerifyHashType(hashes hash.Set) hash.Type {
+	if hashes.Contains(hash.CRC32) {
+		return hash.CRC32
+	}
+	return hashes.GetOne()
+}
+
+// MultiThreadVerifyError is returned by VerifyMultiThread when the
+// destination doesn't match the source.
+type MultiThreadVerifyError struct {
+	Remote   string    // name of the destination object
+	SrcSize  int64     // size of the source
+	DstSize  int64     // size of the destination
+	HashType hash.Type // hash compared - None if the sizes differ
+	SrcHash  string    // hash of the source
+	DstHash  string    // hash read from the destination
+}
+
+// Error returns a description of the mismatch
+func (e *MultiThreadVerifyError) Error() string {
+	if e.HashType == hash.None {
+		return fmt.Sprintf("multi-thread verify: %s: sizes differ src %d vs dst %d", e.Remote, e.SrcSize, e.DstSize)
+	}
+	return fmt.Sprintf("multi-thread verify: %s: %v hashes differ src %q vs dst %q (size %d)", e.Remote, e.HashType, e.SrcHash, e.DstHash, e.DstSize)
+}
+
+// VerifyMultiThread checks the object at remote on f matches src
+// without transferring it again.
+//
+// The destination is read back and its hash compared with the hash
+// of src. If the source supports CRC32 the destination is read in
+// parallel chunks of --multi-thread-chunk-size using
+// --multi-thread-streams streams.
+//
+// If the destination doesn't match then a *MultiThreadVerifyError is
+// returned describing the mismatch.
+func VerifyMultiThread(ctx context.Context, f fs.Fs, remote string, src fs.Object) error {
+	ci := fs.GetConfig(ctx)
+	dst, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return fmt.Errorf("multi-thread verify: failed to find destination: %w", err)
+	}
+	srcSize, dstSize := src.Size(), dst.Size()
+	if srcSize >= 0 && srcSize != dstSize {
+		return &MultiThreadVerifyError{
+			Remote:  remote,
+			SrcSize: srcSize,
+			DstSize: dstSize,
+		}
+	}
+	hashType := multiThreadVerifyHashType(src.Fs().Hashes())
+	if hashType == hash.None {
+		return fmt.Errorf("multi-thread verify: %s: source has no hashes: %w", remote, hash.ErrUnsupported)
+	}
+	srcSum, err := src.Hash(ctx, hashType)
+	if err != nil {
+		return fmt.Errorf("multi-thread verify: failed to read source %v hash: %w", hashType, err)
+	}
+	if srcSum == "" {
+		return fmt.Errorf("multi-thread verify: %s: source has no %v hash: %w", remote, hashType, hash.ErrUnsupported)
+	}
+	dstSum, err := multiThreadHash(ctx, dst, hashType, int64(ci.MultiThreadChunkSize), ci.MultiThreadStreams)
+	if err != nil {
+		return fmt.Errorf("multi-thread verify: failed to read destination %v hash: %w", hashType, err)
+	}
+	if !hash.Equals(srcSum, dstSum) {
+		return &MultiThreadVerifyError{
+			Remote:   remote,
+			SrcSize:  srcSize,
+			DstSize:  dstSize,
+			HashType: hashType,
+			SrcHash:  srcSum,
+			DstHash:  dstSum,
+		}
+	}
+	fs.Debugf(dst, "multi-thread verify: verified %v hash %q", hashType, dstSum)
+	return nil
+}
+
+// multiThreadHash reads o to calculate its hashType hash.
+//
+// For CRC32 this reads the object in parallel chunks of partSize
+// using concurrency streams and combines the results, for other hash
+// types it reads the object sequentially.
+func multiThreadHash(ctx context.Context, o fs.Object, hashType hash.Type, partSize int64, concurrency int) (string, error) {
+	size := o.Size()
+	if hashType != hash.CRC32 || partSize <= 0 || size <= partSize {
+		in, err := Open(ctx, o)
+		if err != nil {
+			return "", err
+		}
+		sums, err := hash.StreamTypes(in, hash.NewHashSet(hashType))
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		return sums[hashType], nil
+	}
+
+	numChunks := calculateNumChunks(size, partSize)
+	crcs := make([]uint32, numChunks)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for chunk := 0; chunk < numChunks; chunk++ {
+		chunk := chunk
+		g.Go(func() (err error) {
+			start, end := chunkRange(chunk, size, 0, partSize)
+			in, err := Open(gCtx, o, &fs.RangeOption{Start: start, End: end - 1})
+			if err != nil {
+				return err
+			}
+			defer fs.CheckClose(in, &err)
+			h := crc32.NewIEEE()
+			n, err := io.Copy(h, in)
+			if err != nil {
+				return err
+			}
+			if n != end-start {
+				return fmt.Errorf("chunk %d/%d: expected %d bytes but read %d", chunk+1, numChunks, end-start, n)
+			}
+			crcs[chunk] = h.Sum32()
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return "", err
+	}
+	crc := crcs[0]
+	for chunk := 1; chunk < numChunks; chunk++ {
+		start, end := chunkRange(chunk, size, 0, partSize)
+		crc = hash.CombineCRC32(crc, crcs[chunk], end-start)
+	}
+	return fmt.Sprintf("%08x", crc), nil
+}
+
+// checkMultiThreadOverwrite returns an error if (f, remote) exists and
+// --immutable or --ignore-existing say it mustn't be overwritten.
+//
+// Copy has already checked this when it decided to transfer the file
+// but the destination may have appeared since.
+func checkMultiThreadOverwrite(ctx context.Context, f fs.Fs, remote string) error {
+	ci := fs.GetConfig(ctx)
+	var flag string
+	switch {
+	case ci.Immutable:
+		flag = "--immutable"
+	case ci.IgnoreExisting:
+		flag = "--ignore-existing"
+	default:
+		return nil
+	}
+	_, err := f.NewObject(ctx, remote)
+	if err == nil {
+		return fserrors.NoRetryError(fmt.Errorf("multi-thread copy: not overwriting existing destination as %s is set", flag))
+	}
+	if !errors.Is(err, fs.ErrorObjectNotFound) {
+		return fmt.Errorf("multi-thread copy: failed to check destination: %w", err)
+	}
+	return nil
+}
+
+// closeChunkWriter finalizes the chunkWriter, giving up after timeout
+// if it is > 0.
+func closeChunkWriter(ctx context.Context, chunkWriter fs.ChunkWriter, timeout time.Duration) (err error) {
+	closeCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		closeCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err = chunkWriter.Close(closeCtx)
+	if err == nil {
+		return nil
+	}
+	if timeout > 0 && errors.Is(closeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("multi-thread copy: timed out after %v finalizing object (--multi-thread-finalize-timeout): %w", timeout, err)
+	}
+	return fmt.Errorf("multi-thread copy: failed to close object after copy: %w", err)
+}
+
 // writerAtChunkWriter converts a WriterAtCloser into a ChunkWriter
 type writerAtChunkWriter struct {
 	remote          string
 	size            int64
 	writerAt        fs.WriterAtCloser
 	chunkSize       int64
+	firstChunkSize  int64 // if set the size of chunk 0 which the other chunks of chunkSize follow
 	chunks          int
 	writeBufferSize int64
+	adaptiveBuffer  bool // if set, grow the write buffer up to writeBufferSize as needed
 	f               fs.Fs
 	closed          bool
+	hashes          hash.Set // if set, hash the chunks with these as they are written
+	fsync           bool     // if set, sync the file to storage before closing it
+
+	onClose func(remote string, hashes WrittenHashes) // if set, called with the hashes once closed
+
+	mu   sync.Mutex
+	sums map[int]map[hash.Type]string // hashes of the chunks written if hashes is set
+}
+
+// newChunkHasher returns a hasher for the chunk being written or nil
+// if w isn't hashing the chunks
+func (w *writerAtChunkWriter) newChunkHasher() (*hash.MultiHasher, error) {
+	if w.hashes.Count() == 0 {
+		return nil, nil
+	}
+	hasher, err := hash.NewMultiHasherTypes(w.hashes)
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread copy: failed to make chunk hasher: %w", err)
+	}
+	return hasher, nil
+}
+
+// addChunkSums records the hashes of chunkNumber from hasher
+func (w *writerAtChunkWriter) addChunkSums(chunkNumber int, hasher *hash.MultiHasher) {
+	if hasher == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sums == nil {
+		w.sums = make(map[int]map[hash.Type]string, w.chunks)
+	}
+	w.sums[chunkNumber] = hasher.Sums()
+}
+
+// chunkSum returns the hash of type ty of chunkNumber as it was
+// written or "" if it wasn't hashed.
+func (w *writerAtChunkWriter) chunkSum(chunkNumber int, ty hash.Type) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sums[chunkNumber][ty]
+}
+
+// writtenSum returns the hash of type ty of the whole file combined
+// from the hashes of the chunks as they were written.
+//
+// Only CRC32 can be combined and all the chunks must have been hashed.
+func (w *writerAtChunkWriter) writtenSum(ty hash.Type) (string, error) {
+	if ty != hash.CRC32 || !w.hashes.Contains(ty) {
+		return "", fmt.Errorf("can't combine %v hashes of the chunks", ty)
+	}
+	var crc uint32
+	for chunk := 0; chunk < w.chunks; chunk++ {
+		sum := w.chunkSum(chunk, ty)
+		if sum == "" {
+			return "", fmt.Errorf("chunk %d wasn't hashed", chunk+1)
+		}
+		chunkCRC, err := strconv.ParseUint(sum, 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("bad %v hash %q of chunk %d: %w", ty, sum, chunk+1, err)
+		}
+		if chunk == 0 {
+			crc = uint32(chunkCRC)
+			continue
+		}
+		start, end := chunkRange(chunk, w.size, w.firstChunkSize, w.chunkSize)
+		crc = hash.CombineCRC32(crc, uint32(chunkCRC), end-start)
+	}
+	return fmt.Sprintf("%08x", crc), nil
+}
+
+// writtenHashes returns the hashes of the chunks written and of the
+// whole file where they can be combined
+func (w *writerAtChunkWriter) writtenHashes() WrittenHashes {
+	hashes := WrittenHashes{
+		Chunks: make([]map[hash.Type]string, w.chunks),
+		File:   map[hash.Type]string{},
+	}
+	w.mu.Lock()
+	for chunk := range hashes.Chunks {
+		hashes.Chunks[chunk] = w.sums[chunk]
+	}
+	w.mu.Unlock()
+	if w.chunks == 1 {
+		for ty, sum := range hashes.Chunks[0] {
+			hashes.File[ty] = sum
+		}
+	} else if sum, err := w.writtenSum(hash.CRC32); err == nil {
+		hashes.File[hash.CRC32] = sum
+	}
+	return hashes
+}
+
+// setChunkSize changes the size of the chunks w is written in
+func (w *writerAtChunkWriter) setChunkSize(chunkSize int64) {
+	w.chunkSize = chunkSize
+	w.chunks = calculateNumChunks(w.size, chunkSize)
 }
 
 // WriteChunk writes chunkNumber from reader
 func (w *writerAtChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
-	fs.Debugf(w.remote, "writing chunk %v", chunkNumber)
+	fs.Debugf(w.remote, "writing chunk %v", fs.LogValue("chunk", chunkNumber))
 
-	bytesToWrite := w.chunkSize
-	if chunkNumber == (w.chunks-1) && w.size%w.chunkSize != 0 {
-		bytesToWrite = w.size % w.chunkSize
-	}
+	offset, end := chunkRange(chunkNumber, w.size, w.firstChunkSize, w.chunkSize)
+	bytesToWrite := end - offset
 
-	var writer io.Writer = io.NewOffsetWriter(w.writerAt, int64(chunkNumber)*w.chunkSize)
-	if w.writeBufferSize > 0 {
+	var writer io.Writer = &chunkOffsetWriter{writerAt: w.writerAt, chunkNumber: chunkNumber, numChunks: w.chunks, offset: offset}
+	if w.writeBufferSize > 0 && w.adaptiveBuffer {
+		writer = newAdaptiveBufferWriter(writer, int(w.writeBufferSize))
+	} else if w.writeBufferSize > 0 {
 		writer = bufio.NewWriterSize(writer, int(w.writeBufferSize))
 	}
-	n, err := io.Copy(writer, reader)
+	// Don't write more than the chunk so we don't overwrite the next one
+	in := io.LimitReader(reader, bytesToWrite)
+	hasher, err := w.newChunkHasher()
 	if err != nil {
 		return -1, err
 	}
-	if n != bytesToWrite {
-		return -1, fmt.Errorf("expected to write %v bytes for chunk %v, but wrote %v bytes", bytesToWrite, chunkNumber, n)
+	if hasher != nil {
+		in = io.TeeReader(in, hasher)
+	}
+	n, err := io.Copy(writer, in)
+	if err != nil {
+		return -1, err
 	}
 	// if we were buffering, flush to disk
-	switch w := writer.(type) {
-	case *bufio.Writer:
+	if w, ok := writer.(interface{ Flush() error }); ok {
 		er2 := w.Flush()
 		if er2 != nil {
-			return -1, fmt.Errorf("multi-thread copy: flush failed: %w", err)
+			return -1, fmt.Errorf("multi-thread copy: flush failed: %w", er2)
+		}
+	}
+	// Check for excess data in the reader, reading at most one byte
+	// so a source which ignores the range isn't read to the end
+	if n == bytesToWrite {
+		extra, err := io.CopyN(io.Discard, reader, 1)
+		if err != nil && err != io.EOF {
+			return -1, err
+		}
+		if extra > 0 {
+			return -1, fmt.Errorf("multi-thread copy: chunk %d/%d: source has more than the expected %d bytes", chunkNumber+1, w.chunks, bytesToWrite)
+		}
+	}
+	err = checkChunkSize(chunkNumber, w.chunks, bytesToWrite, n)
+	if err != nil {
+		return -1, err
+	}
+	w.addChunkSums(chunkNumber, hasher)
+	return n, nil
+}
+
+// chunkOffsetWriter is an io.Writer which writes chunkNumber to
+// writerAt starting at offset, annotating any errors with where they
+// happened.
+type chunkOffsetWriter struct {
+	writerAt    io.WriterAt
+	chunkNumber int
+	numChunks   int
+	offset      int64
+}
+
+// Write writes p at the current offset
+func (cw *chunkOffsetWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.writerAt.WriteAt(p, cw.offset)
+	if err != nil {
+		err = writeAtError(cw.chunkNumber, cw.numChunks, cw.offset+int64(n), err)
+	}
+	cw.offset += int64(n)
+	return n, err
+}
+
+// writeAtError wraps err from a write of chunkNumber of numChunks at
+// offset so the error says which part of the file failed. numChunks
+// is 0 if the number of chunks isn't known. The original error, eg an
+// *os.PathError for a closed file, can still be found with errors.Is
+// and errors.As.
+func writeAtError(chunkNumber, numChunks int, offset int64, err error) error {
+	if numChunks <= 0 {
+		return fmt.Errorf("multi-thread copy: chunk %d: failed to write at offset %d: %w", chunkNumber+1, offset, err)
+	}
+	return fmt.Errorf("multi-thread copy: chunk %d/%d: failed to write at offset %d: %w", chunkNumber+1, numChunks, offset, err)
+}
+
+// copyChunkAt copies chunkNumber from readerAt at the same offset
+// using ReadAt and WriteAt directly. For local files these are pread
+// and pwrite so there is no need to open and seek a reader for each
+// chunk.
+//
+// account is called after each write to report progress.
+func (w *writerAtChunkWriter) copyChunkAt(ctx context.Context, chunkNumber int, readerAt io.ReaderAt, account func(n int) error) (n int64, err error) {
+	fs.Debugf(w.remote, "copying chunk %v with ReadAt/WriteAt", fs.LogValue("chunk", chunkNumber))
+
+	offset, end := chunkRange(chunkNumber, w.size, w.firstChunkSize, w.chunkSize)
+	bytesToWrite := end - offset
+
+	hasher, err := w.newChunkHasher()
+	if err != nil {
+		return 0, err
+	}
+	if hasher != nil {
+		defer func() {
+			if err == nil && n == bytesToWrite {
+				w.addChunkSums(chunkNumber, hasher)
+			}
+		}()
+	}
+
+	bufSize := w.writeBufferSize
+	if bufSize <= 0 {
+		bufSize = multithreadChunkSize
+	}
+	if bufSize > bytesToWrite {
+		bufSize = bytesToWrite
+	}
+	buf := make([]byte, bufSize)
+	for n < bytesToWrite {
+		// Stop early if the copy has been cancelled
+		err = ctx.Err()
+		if err != nil {
+			return n, err
+		}
+		p := buf
+		if remaining := bytesToWrite - n; remaining < int64(len(p)) {
+			p = p[:remaining]
+		}
+		nr, readErr := readerAt.ReadAt(p, offset+n)
+		if nr > 0 {
+			nw, err := w.writerAt.WriteAt(p[:nr], offset+n)
+			if err != nil {
+				return n, writeAtError(chunkNumber, w.chunks, offset+n+int64(nw), err)
+			}
+			if hasher != nil {
+				_, _ = hasher.Write(p[:nr])
+			}
+			n += int64(nr)
+			err = account(nr)
+			if err != nil {
+				return n, err
+			}
+		}
+		if readErr == io.EOF {
+			// A short chunk is reported by the caller
+			break
+		} else if readErr != nil {
+			return n, readErr
+		}
+	}
+	return n, nil
+}
+
+// copyChunkFileRange copies chunkNumber from readerAt at the same
+// offset using copy_file_range so the data doesn't pass through user
+// space.
+//
+// It returns file.ErrCopyFileRangeUnsupported without copying
+// anything if both ends aren't regular files or the kernel can't do
+// the copy.
+//
+// account is called after each copy to report progress.
+func (w *writerAtChunkWriter) copyChunkFileRange(ctx context.Context, chunkNumber int, readerAt io.ReaderAt, account func(n int) error) (n int64, err error) {
+	in, inOK := readerAt.(*os.File)
+	out, outOK := w.writerAt.(*os.File)
+	if !inOK || !outOK || !isRegularFile(in) || !isRegularFile(out) {
+		return 0, file.ErrCopyFileRangeUnsupported
+	}
+	// The data doesn't pass through us to be hashed
+	if w.hashes.Count() > 0 {
+		return 0, fmt.Errorf("hashing the chunks: %w", file.ErrCopyFileRangeUnsupported)
+	}
+	fs.Debugf(w.remote, "copying chunk %v with copy_file_range", fs.LogValue("chunk", chunkNumber))
+
+	offset, end := chunkRange(chunkNumber, w.size, w.firstChunkSize, w.chunkSize)
+	bytesToWrite := end - offset
+	for n < bytesToWrite {
+		// Stop early if the copy has been cancelled
+		err = ctx.Err()
+		if err != nil {
+			return n, err
+		}
+		size := bytesToWrite - n
+		if size > copyFileRangeSize {
+			size = copyFileRangeSize
+		}
+		var nc int
+		nc, err = file.CopyFileRange(out, in, offset+n, int(size))
+		// Unsupported at the start of the chunk means fall back
+		// to another way of copying it
+		if errors.Is(err, file.ErrCopyFileRangeUnsupported) && n == 0 {
+			return n, err
+		}
+		if errors.Is(err, file.ErrCopyFileRangeUnsupported) {
+			err = fmt.Errorf("copy_file_range failed part way through chunk: %v", err)
+		}
+		if err != nil {
+			return n, writeAtError(chunkNumber, w.chunks, offset+n, err)
+		}
+		if nc == 0 {
+			// A short chunk is reported by the caller
+			break
+		}
+		n += int64(nc)
+		err = account(nc)
+		if err != nil {
+			return n, err
 		}
 	}
 	return n, nil
 }
 
+// isRegularFile returns true if f is a regular file
+func isRegularFile(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode().IsRegular()
+}
+
 // Close the chunk writing
+//
+// If --multi-thread-fsync is set the file is synced to storage first
+// so it survives a crash once the copy has been reported as done.
 func (w *writerAtChunkWriter) Close(ctx context.Context) error {
+	wasClosed := w.closed
+	if !wasClosed && w.fsync {
+		err := w.sync()
+		if err != nil {
+			if closeErr := w.close(); closeErr != nil {
+				fs.Errorf(w.remote, "multi-thread copy: failed to close file after sync failed: %v", closeErr)
+			}
+			return err
+		}
+	}
+	err := w.close()
+	if err == nil && !wasClosed && w.onClose != nil {
+		w.onClose(w.remote, w.writtenHashes())
+	}
+	return err
+}
+
+// close closes the file without syncing it
+func (w *writerAtChunkWriter) close() error {
 	if w.closed {
 		return nil
 	}
@@ -332,30 +2617,102 @@ func (w *writerAtChunkWriter) Close(ctx context.Context) error {
 	return w.writerAt.Close()
 }
 
+// syncer is implemented by files which can be synced to storage, eg
+// *os.File
+type syncer interface {
+	Sync() error
+}
+
+// sync syncs the file to storage if it supports it
+func (w *writerAtChunkWriter) sync() error {
+	s, ok := w.writerAt.(syncer)
+	if !ok {
+		fs.Debugf(w.remote, "multi-thread copy: not syncing file as %T can't be synced", w.writerAt)
+		return nil
+	}
+	fs.Debugf(w.remote, "multi-thread copy: syncing file")
+	err := s.Sync()
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to sync file: %w", err)
+	}
+	return nil
+}
+
 // Abort the chunk writing
 func (w *writerAtChunkWriter) Abort(ctx context.Context) error {
-	err := w.Close(ctx)
+	// No need to sync a file which is about to be removed
+	err := w.close()
 	if err != nil {
 		fs.Errorf(w.remote, "multi-thread copy: failed to close file before aborting: %v", err)
 	}
 	obj, err := w.f.NewObject(ctx, w.remote)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		// Nothing was created so there is nothing to clean up
+		fs.Debugf(w.remote, "multi-thread copy: no temp file to remove when aborting chunk writer")
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("multi-thread copy: failed to find temp file when aborting chunk writer: %w", err)
 	}
 	return obj.Remove(ctx)
 }
 
+// checkFreeSpace checks there is enough free space on a local
+// destination f to write size bytes.
+//
+// This means we error out with a clear message before preallocating
+// the file rather than failing part way through the transfer.
+func checkFreeSpace(ctx context.Context, f fs.Fs, size int64) error {
+	features := f.Features()
+	if !features.IsLocal || features.About == nil {
+		return nil
+	}
+	usage, err := features.About(ctx)
+	if err != nil {
+		fs.Debugf(f, "multi-thread copy: failed to read free space: %v", err)
+		return nil
+	}
+	if usage == nil || usage.Free == nil {
+		return nil
+	}
+	if free := *usage.Free; free < size {
+		return fserrors.NoRetryError(fmt.Errorf("multi-thread copy: insufficient space on destination: need %v but only %v free", fs.SizeSuffix(size), fs.SizeSuffix(free)))
+	}
+	return nil
+}
+
 // openChunkWriterFromOpenWriterAt adapts an OpenWriterAtFn into an OpenChunkWriterFn using chunkSize and writeBufferSize
 func openChunkWriterFromOpenWriterAt(openWriterAt fs.OpenWriterAtFn, chunkSize int64, writeBufferSize int64, f fs.Fs) fs.OpenChunkWriterFn {
 	return func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
 		ci := fs.GetConfig(ctx)
 
+		err = checkFreeSpace(ctx, f, src.Size())
+		if err != nil {
+			return info, nil, err
+		}
+
 		writerAt, err := openWriterAt(ctx, remote, src.Size())
 		if err != nil {
 			return info, nil, err
 		}
 
-		if writeBufferSize > 0 {
+		// Use the chunk size set on the remote if any
+		chunkSize := chunkSize
+		if size, ok := fs.MultiThreadChunkSize(f); ok {
+			fs.Debugf(src.Remote(), "multi-thread copy: using %s %v from the config of %v", fs.MultiThreadChunkSizeKey, size, f)
+			chunkSize = int64(size)
+		}
+
+		writeBufferSize := writeBufferSize
+		for _, option := range options {
+			if _, ok := option.(*fs.NoWriteBufferOption); ok && writeBufferSize > 0 {
+				fs.Debugf(src.Remote(), "multi-thread copy: write buffer disabled by %v", option)
+				writeBufferSize = 0
+			}
+		}
+		if writeBufferSize > 0 && ci.MultiThreadAdaptiveBuffer {
+			fs.Debugf(src.Remote(), "multi-thread copy: write buffer growing up to %v as needed", writeBufferSize)
+		} else if writeBufferSize > 0 {
 			fs.Debugf(src.Remote(), "multi-thread copy: write buffer set to %v", writeBufferSize)
 		}
 
@@ -366,11 +2723,28 @@ func openChunkWriterFromOpenWriterAt(openWriterAt fs.OpenWriterAtFn, chunkSize i
 			chunks:          calculateNumChunks(src.Size(), chunkSize),
 			writerAt:        writerAt,
 			writeBufferSize: writeBufferSize,
+			adaptiveBuffer:  ci.MultiThreadAdaptiveBuffer,
 			f:               f,
+			fsync:           ci.MultiThreadFsync,
+		}
+		// Hash the chunks as they are written so --multi-thread-verify
+		// doesn't have to read the destination back
+		if ci.MultiThreadVerify && multiThreadVerifyHashType(src.Fs().Hashes()) == hash.CRC32 {
+			chunkWriter.hashes = hash.NewHashSet(hash.CRC32)
+		}
+		// Hash the chunks for WithMultiThreadWrittenHashes if set
+		if hook := getMultiThreadWrittenHashes(ctx); hook != nil {
+			chunkWriter.hashes.Add(hook.hashes.Array()...)
+			chunkWriter.onClose = hook.onClose
 		}
 		info = fs.ChunkWriterInfo{
-			ChunkSize:   chunkSize,
-			Concurrency: ci.MultiThreadStreams,
+			ChunkSize:          chunkSize,
+			Concurrency:        ci.MultiThreadStreams,
+			MetadataAfterClose: true, // OpenWriterAt doesn't set metadata
+		}
+		// Use the minimum chunk size set on the remote if any
+		if size, ok := fs.MultiThreadMinChunkSize(f); ok {
+			info.MinChunkSize = int64(size)
 		}
 		return info, chunkWriter, nil
 	}
@@ -1,20 +1,34 @@
 package operations
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
 	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/readers"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
@@ -28,17 +42,25 @@ func TestDoMultiThreadCopy(t *testing.T) {
 	f, err := mockfs.NewFs(ctx, "potato", "", nil)
 	require.NoError(t, err)
 	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
-	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
-	require.NoError(t, err)
-	src.SetFs(srcFs)
+	srcFs := setTestSrcFs(ctx, t, src)
 
 	oldStreams := ci.MultiThreadStreams
 	oldCutoff := ci.MultiThreadCutoff
 	oldIsSet := ci.MultiThreadSet
+	oldSerialDebug := ci.MultiThreadSerialDebug
+	oldLocal := ci.MultiThreadLocal
+	oldRequireHash := ci.MultiThreadRequireHash
+	oldTotalStreams := ci.MultiThreadTotalStreams
+	oldInclude, oldExclude := ci.MultiThreadInclude, ci.MultiThreadExclude
 	defer func() {
+		ci.MultiThreadInclude, ci.MultiThreadExclude = oldInclude, oldExclude
+		ci.MultiThreadTotalStreams = oldTotalStreams
+		ci.MultiThreadRequireHash = oldRequireHash
+		ci.MultiThreadLocal = oldLocal
 		ci.MultiThreadStreams = oldStreams
 		ci.MultiThreadCutoff = oldCutoff
 		ci.MultiThreadSet = oldIsSet
+		ci.MultiThreadSerialDebug = oldSerialDebug
 	}()
 
 	ci.MultiThreadStreams, ci.MultiThreadCutoff = 4, 50
@@ -77,6 +99,10 @@ func TestDoMultiThreadCopy(t *testing.T) {
 	assert.True(t, doMultiThreadCopy(ctx, f, src))
 	ci.MultiThreadSet = false
 	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadLocal = true
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadLocal = false
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
 	srcFs.Features().IsLocal = false
 	assert.True(t, doMultiThreadCopy(ctx, f, src))
 	srcFs.Features().IsLocal = true
@@ -86,154 +112,2756 @@ func TestDoMultiThreadCopy(t *testing.T) {
 	srcFs.Features().IsLocal = false
 	assert.True(t, doMultiThreadCopy(ctx, f, src))
 
-	srcFs.Features().NoMultiThreading = true
-	assert.False(t, doMultiThreadCopy(ctx, f, src))
-	srcFs.Features().NoMultiThreading = false
-	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().NoMultiThreading = true
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().NoMultiThreading = false
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadStreams = 1
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadSerialDebug = true
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	f.Features().IsLocal = true
+	srcFs.Features().IsLocal = true
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	f.Features().IsLocal = false
+	srcFs.Features().IsLocal = false
+	ci.MultiThreadSerialDebug = false
+	ci.MultiThreadStreams = 2
+
+	ci.MultiThreadRequireHash = true
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	f.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.MD5))
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.MD5, hash.SHA1))
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadRequireHash = false
+	f.(*mockfs.Fs).SetHashes(hash.Set(hash.None))
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadTotalStreams = 4
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	reserved := multiThreadStreams.reserve(4, 3)
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	multiThreadStreams.release(reserved)
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadTotalStreams = 0
+
+	ci.MultiThreadExclude = []string{"*.zip", "*.TXT"}
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadExclude = []string{"text/*"}
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadExclude = []string{"video/*", "*.zip"}
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadCutoff = 200
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadInclude = []string{"*.txt"}
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadInclude = []string{"text/plain"}
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadExclude = []string{"*.txt"}
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadExclude = nil
+	ci.MultiThreadInclude = []string{"[", "*.mp4"}
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadInclude = nil
+	ci.MultiThreadCutoff = 50
+}
+
+func TestDoMultiThreadCopyDisableKey(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadStreams, ci.MultiThreadCutoff = 4, 50
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		panic("don't call me")
+	}
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	src := &metadataObject{
+		ContentMockObject: mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone),
+		meta:              fs.Metadata{"rclone-no-multi-thread": ""},
+	}
+	src.SetFs(srcFs)
+
+	// The metadata is ignored without --metadata
+	assert.Equal(t, "rclone-no-multi-thread", ci.MultiThreadDisableKey)
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.Metadata = true
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadDisableKey = "Single-Stream"
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	src.meta = fs.Metadata{"single-stream": "true"}
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadDisableKey = ""
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+}
+
+func TestMultithreadLogNoMultiThread(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato-no-multi-thread", "", nil)
+	require.NoError(t, err)
+	g, err := mockfs.NewFs(ctx, "sausage-no-multi-thread", "", nil)
+	require.NoError(t, err)
+	assert.True(t, logNoMultiThread(f))
+	assert.False(t, logNoMultiThread(f))
+	assert.True(t, logNoMultiThread(g))
+	assert.False(t, logNoMultiThread(f))
+}
+
+func TestMultithreadCalculateNumChunks(t *testing.T) {
+	for _, test := range []struct {
+		size          int64
+		chunkSize     int64
+		wantNumChunks int
+	}{
+		{size: 1, chunkSize: multithreadChunkSize, wantNumChunks: 1},
+		{size: 1 << 20, chunkSize: 1, wantNumChunks: 1 << 20},
+		{size: 1 << 20, chunkSize: 2, wantNumChunks: 1 << 19},
+		{size: (1 << 20) + 1, chunkSize: 2, wantNumChunks: (1 << 19) + 1},
+		{size: (1 << 20) - 1, chunkSize: 2, wantNumChunks: 1 << 19},
+	} {
+		t.Run(fmt.Sprintf("%+v", test), func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size: test.size,
+			}
+			mc.numChunks = calculateNumChunks(test.size, test.chunkSize)
+			assert.Equal(t, test.wantNumChunks, mc.numChunks)
+		})
+	}
+}
+
+func TestMultithreadDuplicateChunk(t *testing.T) {
+	ctx := context.Background()
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	mc := &multiThreadCopyState{
+		size:      100,
+		partSize:  50,
+		numChunks: 2,
+		src:       src,
+	}
+	require.NoError(t, mc.markDispatched(0))
+	require.NoError(t, mc.markDispatched(1))
+	err := mc.copyChunk(ctx, 1, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chunk 2/2 written more than once")
+}
+
+func TestMultithreadHash(t *testing.T) {
+	ctx := context.Background()
+	contents := []byte(random.String(1000))
+	src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+	for _, hashType := range []hash.Type{hash.CRC32, hash.MD5} {
+		want, err := src.Hash(ctx, hashType)
+		require.NoError(t, err)
+		for _, partSize := range []int64{1, 7, 100, 999, 1000, 1001} {
+			t.Run(fmt.Sprintf("%v,partSize=%d", hashType, partSize), func(t *testing.T) {
+				got, err := multiThreadHash(ctx, src, hashType, partSize, 4)
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			})
+		}
+	}
+}
+
+func TestVerifyMultiThread(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 7
+	ci.MultiThreadStreams = 4
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	srcFs.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.CRC32))
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	src.SetFs(srcFs)
+
+	newDst := func(t *testing.T, contents []byte) fs.Fs {
+		f, err := mockfs.NewFs(ctx, "potato", "", nil)
+		require.NoError(t, err)
+		f.(*mockfs.Fs).AddObject(mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone))
+		return f
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		require.NoError(t, VerifyMultiThread(ctx, newDst(t, contents), remote, src))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		f, err := mockfs.NewFs(ctx, "potato", "", nil)
+		require.NoError(t, err)
+		err = VerifyMultiThread(ctx, f, remote, src)
+		assert.True(t, errors.Is(err, fs.ErrorObjectNotFound), err)
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		err := VerifyMultiThread(ctx, newDst(t, contents[:99]), remote, src)
+		var verifyErr *MultiThreadVerifyError
+		require.True(t, errors.As(err, &verifyErr), err)
+		assert.Equal(t, hash.None, verifyErr.HashType)
+		assert.Equal(t, int64(100), verifyErr.SrcSize)
+		assert.Equal(t, int64(99), verifyErr.DstSize)
+		assert.Contains(t, err.Error(), "sizes differ src 100 vs dst 99")
+	})
+
+	t.Run("Hash", func(t *testing.T) {
+		corrupt := append([]byte{}, contents...)
+		corrupt[50] ^= 0xFF
+		err := VerifyMultiThread(ctx, newDst(t, corrupt), remote, src)
+		var verifyErr *MultiThreadVerifyError
+		require.True(t, errors.As(err, &verifyErr), err)
+		assert.Equal(t, hash.CRC32, verifyErr.HashType)
+		wantSrc, _ := src.Hash(ctx, hash.CRC32)
+		assert.Equal(t, wantSrc, verifyErr.SrcHash)
+		assert.NotEqual(t, verifyErr.SrcHash, verifyErr.DstHash)
+		assert.Contains(t, err.Error(), "crc32 hashes differ")
+	})
+
+	t.Run("NoHashes", func(t *testing.T) {
+		srcFs.(*mockfs.Fs).SetHashes(hash.Set(hash.None))
+		defer srcFs.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.CRC32))
+		err := VerifyMultiThread(ctx, newDst(t, contents), remote, src)
+		assert.True(t, errors.Is(err, hash.ErrUnsupported), err)
+	})
+}
+
+func TestMultithreadCheckFreeSpace(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+
+	free := int64(99)
+	f.Features().About = func(ctx context.Context) (*fs.Usage, error) {
+		return &fs.Usage{Free: &free}, nil
+	}
+	openWriterAtCalled := false
+	openWriterAt := func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		openWriterAtCalled = true
+		return nil, errors.New("not called in test")
+	}
+	openChunkWriter := openChunkWriterFromOpenWriterAt(openWriterAt, 64, 0, f)
+
+	// Not local so no check
+	_, _, err = openChunkWriter(ctx, "file.txt", src)
+	assert.EqualError(t, err, "not called in test")
+	assert.True(t, openWriterAtCalled)
+
+	// Local with insufficient space
+	f.Features().IsLocal = true
+	openWriterAtCalled = false
+	_, _, err = openChunkWriter(ctx, "file.txt", src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient space on destination")
+	assert.False(t, openWriterAtCalled)
+
+	// Local with enough space
+	free = 100
+	_, _, err = openChunkWriter(ctx, "file.txt", src)
+	assert.EqualError(t, err, "not called in test")
+	assert.True(t, openWriterAtCalled)
+}
+
+func TestMultithreadWriterAtRemoteChunkSize(t *testing.T) {
+	ctx := context.Background()
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	openWriterAt := func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return &memWriterAt{}, nil
+	}
+
+	for _, test := range []struct {
+		remote  string
+		want    int64
+		wantMin int64
+	}{
+		{remote: ":mockfs:", want: 64},
+		{remote: ":mockfs,multi_thread_chunk_size=25B:", want: 25},
+		{remote: ":mockfs,multi_thread_min_chunk_size=40B:", want: 64, wantMin: 40},
+	} {
+		t.Run(test.remote, func(t *testing.T) {
+			f, err := fs.NewFs(ctx, test.remote)
+			require.NoError(t, err)
+			openChunkWriter := openChunkWriterFromOpenWriterAt(openWriterAt, 64, 0, f)
+			info, writer, err := openChunkWriter(ctx, "file.txt", src)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, info.ChunkSize)
+			assert.Equal(t, test.want, writer.(*writerAtChunkWriter).chunkSize)
+			assert.Equal(t, test.wantMin, info.MinChunkSize)
+		})
+	}
+}
+
+func TestMultithreadChunkEvent(t *testing.T) {
+	ctx := context.Background()
+	src := mockobject.New("file.txt")
+	_, _, err := jobs.NewJob(ctx, func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		job, ok := jobs.GetJob(ctx)
+		require.True(t, ok)
+		mc := &multiThreadCopyState{
+			src:       src,
+			numChunks: 2,
+			job:       job,
+		}
+
+		// Not published until watched
+		mc.chunkEvent(0, "started", 50, nil)
+		out, err := rc.Calls.Get("job/events").Fn(ctx, rc.Params{"jobid": job.ID})
+		require.NoError(t, err)
+		assert.Len(t, out["events"], 0)
+
+		mc.chunkEvent(0, "finished", 50, nil)
+		mc.chunkEvent(1, "failed", 50, errors.New("potato"))
+		out, err = rc.Calls.Get("job/events").Fn(ctx, rc.Params{"jobid": job.ID})
+		require.NoError(t, err)
+		events := out["events"].([]jobs.Event)
+		require.Len(t, events, 2)
+		assert.Equal(t, "chunk", events[0].Type)
+		assert.Equal(t, rc.Params{"object": "file.txt", "chunk": 0, "chunks": 2, "state": "finished", "bytes": int64(50)}, events[0].Data)
+		assert.Equal(t, "failed", events[1].Data["state"])
+		assert.Equal(t, "potato", events[1].Data["error"])
+		return nil, nil
+	}, rc.Params{})
+	require.NoError(t, err)
+}
+
+// memWriterAt is an in memory fs.WriterAtCloser
+type memWriterAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, end-int64(len(w.buf)))...)
+	}
+	return len(append(w.buf[off:off], p...)), nil
+}
+
+func (w *memWriterAt) Close() error {
+	return nil
+}
+
+// newTestWriterAtChunkWriter returns a writerAtChunkWriter for a size
+// byte "file.txt" split into chunks of chunkSize which writes to
+// writerAt
+func newTestWriterAtChunkWriter(size, chunkSize int64, writerAt fs.WriterAtCloser) *writerAtChunkWriter {
+	return &writerAtChunkWriter{
+		remote:    "file.txt",
+		size:      size,
+		chunkSize: chunkSize,
+		chunks:    calculateNumChunks(size, chunkSize),
+		writerAt:  writerAt,
+	}
+}
+
+func TestMultithreadWriterAtAbort(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+
+	t.Run("NeverCreated", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(100, 25, &memWriterAt{})
+		w.f = f
+		require.NoError(t, w.Abort(ctx))
+		assert.True(t, w.closed)
+	})
+
+	t.Run("Created", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(100, 25, &memWriterAt{})
+		w.f = f
+		f.(*mockfs.Fs).AddObject(mockobject.New("file.txt").WithContent([]byte("potato"), mockobject.SeekModeNone))
+		// mockobject can't be removed so this fails after finding it
+		err := w.Abort(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not implemented")
+	})
+}
+
+// syncWriterAt is a memWriterAt which records whether it was synced
+// before being closed
+type syncWriterAt struct {
+	memWriterAt
+	syncErr       error
+	synced        bool
+	closed        bool
+	syncedAtClose bool
+}
+
+func (w *syncWriterAt) Sync() error {
+	w.synced = true
+	return w.syncErr
+}
+
+func (w *syncWriterAt) Close() error {
+	w.closed = true
+	w.syncedAtClose = w.synced
+	return nil
+}
+
+func TestMultithreadWriterAtFsync(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	newWriter := func(fsync bool, syncErr error) (*writerAtChunkWriter, *syncWriterAt) {
+		out := &syncWriterAt{syncErr: syncErr}
+		w := newTestWriterAtChunkWriter(100, 25, out)
+		w.f = f
+		w.fsync = fsync
+		return w, out
+	}
+
+	t.Run("Off", func(t *testing.T) {
+		w, out := newWriter(false, nil)
+		require.NoError(t, w.Close(ctx))
+		assert.True(t, out.closed)
+		assert.False(t, out.synced)
+	})
+
+	t.Run("On", func(t *testing.T) {
+		w, out := newWriter(true, nil)
+		require.NoError(t, w.Close(ctx))
+		assert.True(t, out.closed)
+		assert.True(t, out.syncedAtClose)
+	})
+
+	t.Run("SyncError", func(t *testing.T) {
+		w, out := newWriter(true, errors.New("sync failed"))
+		err := w.Close(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to sync file: sync failed")
+		assert.True(t, out.closed)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		w, out := newWriter(true, nil)
+		require.NoError(t, w.Abort(ctx))
+		assert.True(t, out.closed)
+		assert.False(t, out.synced)
+	})
+
+	t.Run("NoSync", func(t *testing.T) {
+		w, _ := newWriter(true, nil)
+		w.writerAt = &memWriterAt{}
+		require.NoError(t, w.Close(ctx))
+	})
+}
+
+// closedWriterAt is a fs.WriterAtCloser which accepts limit bytes then
+// fails as if the file had been closed underneath it
+type closedWriterAt struct {
+	memWriterAt
+	limit int64
+}
+
+func (w *closedWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if int64(len(p)) > w.limit {
+		n, _ := w.memWriterAt.WriteAt(p[:w.limit], off)
+		w.limit = 0
+		return n, &os.PathError{Op: "write", Path: "file.txt", Err: os.ErrClosed}
+	}
+	w.limit -= int64(len(p))
+	return w.memWriterAt.WriteAt(p, off)
+}
+
+func TestMultithreadWriterAtWriteError(t *testing.T) {
+	ctx := context.Background()
+	checkErr := func(t *testing.T, err error) {
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "chunk 3/4: failed to write at offset 60")
+		assert.True(t, errors.Is(err, os.ErrClosed))
+		var pathErr *os.PathError
+		assert.True(t, errors.As(err, &pathErr))
+	}
+
+	for _, writeBufferSize := range []int64{0, 5} {
+		t.Run(fmt.Sprintf("WriteChunk/Buffer=%d", writeBufferSize), func(t *testing.T) {
+			w := newTestWriterAtChunkWriter(100, 25, &closedWriterAt{limit: 10})
+			w.writeBufferSize = writeBufferSize
+			_, err := w.WriteChunk(ctx, 2, bytes.NewReader(make([]byte, 25)))
+			checkErr(t, err)
+		})
+	}
+
+	t.Run("CopyChunkAt", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(100, 25, &closedWriterAt{limit: 10})
+		_, err := w.copyChunkAt(ctx, 2, bytes.NewReader(make([]byte, 100)), func(int) error { return nil })
+		checkErr(t, err)
+	})
+}
+
+func TestMultithreadWriterAtChunkSize(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name    string
+		chunk   int
+		data    string
+		wantErr string
+	}{
+		{name: "OK", chunk: 0, data: "0123456789"},
+		{name: "OKLast", chunk: 2, data: "01234"},
+		{name: "Under", chunk: 0, data: "012345678", wantErr: "chunk 1/3: wrote 9 bytes which is 1 fewer than the expected 10 bytes"},
+		{name: "Over", chunk: 0, data: "0123456789AB", wantErr: "chunk 1/3: source has more than the expected 10 bytes"},
+		{name: "OverLast", chunk: 2, data: "012345", wantErr: "chunk 3/3: source has more than the expected 5 bytes"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			writerAt := &memWriterAt{}
+			w := newTestWriterAtChunkWriter(25, 10, writerAt)
+			in := strings.NewReader(test.data)
+			n, err := w.WriteChunk(ctx, test.chunk, in)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				assert.Equal(t, int64(-1), n)
+				// Check at most one byte past the chunk was read
+				if test.name == "Over" {
+					assert.Equal(t, 1, in.Len())
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, int64(len(test.data)), n)
+			}
+			// Check we never write past the end of the chunk
+			assert.LessOrEqual(t, len(writerAt.buf), (test.chunk+1)*10)
+		})
+	}
+}
+
+func TestMultithreadWriterAtNoWriteBuffer(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(25)), mockobject.SeekModeNone)
+	openWriterAt := func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return &memWriterAt{}, nil
+	}
+	openChunkWriter := openChunkWriterFromOpenWriterAt(openWriterAt, 10, 1024, f)
+
+	_, writer, err := openChunkWriter(ctx, "file.txt", src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), writer.(*writerAtChunkWriter).writeBufferSize)
+
+	_, writer, err = openChunkWriter(ctx, "file.txt", src, &fs.NoWriteBufferOption{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), writer.(*writerAtChunkWriter).writeBufferSize)
+}
+
+// maxWriteAt is a mockfsWriterAt which records the largest write
+type maxWriteAt struct {
+	mockfsWriterAt
+	max atomic.Int64
+}
+
+func (w *maxWriteAt) WriteAt(p []byte, off int64) (int, error) {
+	for {
+		old := w.max.Load()
+		if int64(len(p)) <= old || w.max.CompareAndSwap(old, int64(len(p))) {
+			break
+		}
+	}
+	return w.mockfsWriterAt.WriteAt(p, off)
+}
+
+func TestMultithreadCopyNoWriteBuffer(t *testing.T) {
+	const remote = "file.txt"
+	const writeBufferSize = 1024
+	contents := []byte(random.String(256 << 10))
+	for _, noWriteBuffer := range []bool{false, true} {
+		t.Run(fmt.Sprint(noWriteBuffer), func(t *testing.T) {
+			ctx, ci := fs.AddConfig(context.Background())
+			ci.MultiThreadStreams = 2
+			ci.MultiThreadCutoff = 1
+			ci.MultiThreadChunkSize = 64 << 10
+			ci.MultiThreadWriteBufferSize = writeBufferSize
+			if noWriteBuffer {
+				ctx = WithMultiThreadNoWriteBuffer(ctx)
+			}
+			src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			writerAt := &maxWriteAt{mockfsWriterAt: mockfsWriterAt{f: f.(*mockfs.Fs), remote: remote}}
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return writerAt, nil
+			}
+
+			dst, err := Copy(ctx, f, nil, remote, src)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, contents, writerAt.buf)
+			if noWriteBuffer {
+				assert.Greater(t, writerAt.max.Load(), int64(writeBufferSize))
+			} else {
+				assert.LessOrEqual(t, writerAt.max.Load(), int64(writeBufferSize))
+			}
+		})
+	}
+}
+
+func TestMultithreadWriterAtCopyChunkAt(t *testing.T) {
+	ctx := context.Background()
+	data := "0123456789ABCDEFGHIJKLMNO"
+	writerAt := &memWriterAt{}
+	w := newTestWriterAtChunkWriter(int64(len(data)), 10, writerAt)
+	w.writeBufferSize = 3
+	var accounted int
+	account := func(n int) error {
+		accounted += n
+		return nil
+	}
+	// Copy the chunks out of order
+	for _, chunk := range []int{2, 0, 1} {
+		n, err := w.copyChunkAt(ctx, chunk, strings.NewReader(data), account)
+		require.NoError(t, err)
+		if chunk == 2 {
+			assert.Equal(t, int64(5), n)
+		} else {
+			assert.Equal(t, int64(10), n)
+		}
+	}
+	assert.Equal(t, data, string(writerAt.buf))
+	assert.Equal(t, len(data), accounted)
+
+	// Check a short source is reported
+	n, err := w.copyChunkAt(ctx, 2, strings.NewReader(data[:22]), account)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// Check cancelling the context stops the copy
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = w.copyChunkAt(ctx, 0, strings.NewReader(data), account)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestMultithreadWriterAtCopyChunkFileRange(t *testing.T) {
+	ctx := context.Background()
+	data := "0123456789ABCDEFGHIJKLMNO"
+	w := newTestWriterAtChunkWriter(int64(len(data)), 10, &memWriterAt{})
+	noop := func(n int) error { return nil }
+
+	// Check it isn't used if the ends aren't files
+	_, err := w.copyChunkFileRange(ctx, 0, strings.NewReader(data), noop)
+	assert.True(t, errors.Is(err, file.ErrCopyFileRangeUnsupported))
+
+	if !file.CopyFileRangeImplemented {
+		t.Skip("copy_file_range not implemented on this OS")
+	}
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(srcPath, []byte(data), 0666))
+	in, err := os.Open(srcPath)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, in.Close()) }()
+	dstPath := filepath.Join(dir, "dst")
+	out, err := os.Create(dstPath)
+	require.NoError(t, err)
+	w.writerAt = out
+
+	var accounted int
+	account := func(n int) error {
+		accounted += n
+		return nil
+	}
+	for _, chunk := range []int{2, 0, 1} {
+		_, err = w.copyChunkFileRange(ctx, chunk, in, account)
+		if errors.Is(err, file.ErrCopyFileRangeUnsupported) {
+			t.Skip("copy_file_range not supported by the kernel or filesystem")
+		}
+		require.NoError(t, err)
+	}
+	require.NoError(t, out.Close())
+	got, err := os.ReadFile(dstPath)
+	require.NoError(t, err)
+	assert.Equal(t, data, string(got))
+	assert.Equal(t, len(data), accounted)
+
+	// A failed write says which chunk and offset failed
+	readOnly, err := os.Open(dstPath)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, readOnly.Close()) }()
+	w.writerAt = readOnly
+	_, err = w.copyChunkFileRange(ctx, 1, in, account)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chunk 2/3: failed to write at offset 10")
+	assert.False(t, errors.Is(err, file.ErrCopyFileRangeUnsupported))
+}
+
+// badChunkWriter is a fs.ChunkWriter which reports writing the wrong number of bytes
+type badChunkWriter struct {
+	fs.ChunkWriter
+	delta int64
+}
+
+func (w badChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	n, err := io.Copy(io.Discard, reader)
+	return n + w.delta, err
+}
+
+func TestMultithreadCopyChunkSize(t *testing.T) {
+	ctx := context.Background()
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	for _, test := range []struct {
+		delta   int64
+		wantErr string
+	}{
+		{delta: 0},
+		{delta: -1, wantErr: "chunk 2/2: wrote 49 bytes which is 1 fewer than the expected 50 bytes"},
+		{delta: 1, wantErr: "chunk 2/2: wrote 51 bytes which is 1 more than the expected 50 bytes"},
+		{delta: -51, wantErr: "chunk 2/2: wrote invalid byte count -1 for the expected 50 bytes"},
+	} {
+		t.Run(fmt.Sprint(test.delta), func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size:        100,
+				partSize:    50,
+				numChunks:   2,
+				src:         src,
+				noBuffering: true,
+				acc:         tr.Account(ctx, nil),
+			}
+			err := mc.copyChunk(ctx, 1, badChunkWriter{delta: test.delta})
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				// Bad counts aren't added to the bytes written
+				assert.Equal(t, int64(0), mc.written.Load())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, int64(50), mc.written.Load())
+			}
+		})
+	}
+}
+
+func TestMultithreadChunkRange(t *testing.T) {
+	for _, test := range []struct {
+		chunk          int
+		size           int64
+		firstChunkSize int64
+		chunkSize      int64
+		wantStart      int64
+		wantEnd        int64
+	}{
+		{chunk: 0, size: 25, chunkSize: 10, wantStart: 0, wantEnd: 10},
+		{chunk: 1, size: 25, chunkSize: 10, wantStart: 10, wantEnd: 20},
+		{chunk: 2, size: 25, chunkSize: 10, wantStart: 20, wantEnd: 25},
+		{chunk: 3, size: 25, chunkSize: 10, wantStart: 25, wantEnd: 25},
+		{chunk: 0, size: 25, firstChunkSize: 5, chunkSize: 10, wantStart: 0, wantEnd: 5},
+		{chunk: 1, size: 25, firstChunkSize: 5, chunkSize: 10, wantStart: 5, wantEnd: 15},
+		{chunk: 2, size: 25, firstChunkSize: 5, chunkSize: 10, wantStart: 15, wantEnd: 25},
+		{chunk: 0, size: 3, firstChunkSize: 5, chunkSize: 10, wantStart: 0, wantEnd: 3},
+		// Exact multiples of the chunk size
+		{chunk: 0, size: 30, chunkSize: 10, wantStart: 0, wantEnd: 10},
+		{chunk: 2, size: 30, chunkSize: 10, wantStart: 20, wantEnd: 30},
+		{chunk: 3, size: 30, chunkSize: 10, wantStart: 30, wantEnd: 30},
+		{chunk: 3, size: 25, firstChunkSize: 5, chunkSize: 10, wantStart: 25, wantEnd: 25},
+		{chunk: 0, size: 10, chunkSize: 10, wantStart: 0, wantEnd: 10},
+		{chunk: 0, size: 5, firstChunkSize: 5, chunkSize: 10, wantStart: 0, wantEnd: 5},
+		{chunk: 1, size: 5, firstChunkSize: 5, chunkSize: 10, wantStart: 5, wantEnd: 5},
+		// Remainders of one byte
+		{chunk: 3, size: 31, chunkSize: 10, wantStart: 30, wantEnd: 31},
+		{chunk: 1, size: 11, chunkSize: 10, wantStart: 10, wantEnd: 11},
+		{chunk: 1, size: 6, firstChunkSize: 5, chunkSize: 10, wantStart: 5, wantEnd: 6},
+		// Empty files
+		{chunk: 0, size: 0, chunkSize: 10, wantStart: 0, wantEnd: 0},
+		{chunk: 0, size: 0, firstChunkSize: 5, chunkSize: 10, wantStart: 0, wantEnd: 0},
+	} {
+		t.Run(fmt.Sprintf("chunk=%d,size=%d,first=%d", test.chunk, test.size, test.firstChunkSize), func(t *testing.T) {
+			start, end := chunkRange(test.chunk, test.size, test.firstChunkSize, test.chunkSize)
+			assert.Equal(t, test.wantStart, start)
+			assert.Equal(t, test.wantEnd, end)
+		})
+	}
+}
+
+func TestMultithreadChooseConcurrency(t *testing.T) {
+	src := mockobject.New("file.txt")
+	for _, test := range []struct {
+		name    string
+		set     bool
+		strict  bool
+		backend int
+		streams int
+		want    int
+		wantErr bool
+	}{
+		{name: "Same", set: true, backend: 4, streams: 4, want: 4},
+		{name: "NotSetLower", backend: 2, streams: 4, want: 2},
+		{name: "NotSetHigher", backend: 8, streams: 4, want: 8},
+		{name: "NotSetStrict", strict: true, backend: 8, streams: 4, want: 8},
+		{name: "SetLower", set: true, backend: 8, streams: 2, want: 8},
+		{name: "SetHigher", set: true, backend: 2, streams: 8, want: 8},
+		{name: "StrictLower", set: true, strict: true, backend: 8, streams: 2, want: 2},
+		{name: "StrictHigher", set: true, strict: true, backend: 2, streams: 8, want: 8},
+		{name: "NoPreference", backend: 0, streams: 4, want: 4},
+		{name: "NoPreferenceSet", set: true, backend: 0, streams: 2, want: 2},
+		{name: "NoPreferenceStrict", set: true, strict: true, backend: 0, streams: 8, want: 8},
+		{name: "Negative", backend: -1, streams: 4, wantErr: true},
+		{name: "NegativeSet", set: true, backend: -4, streams: 4, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ci := fs.NewConfig()
+			ci.MultiThreadSet = test.set
+			ci.MultiThreadStreamsStrict = test.strict
+			got, err := chooseConcurrency(ci, src, test.backend, test.streams)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid concurrency")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestMultithreadAdaptiveChunkSize(t *testing.T) {
+	assert.Equal(t, int64(100<<20), adaptiveChunkSize(10<<20, time.Second))
+	assert.Equal(t, int64(2<<20), adaptiveChunkSize(1<<20+1, adaptiveChunkDuration))
+	assert.Equal(t, int64(adaptiveChunkMin), adaptiveChunkSize(1, adaptiveChunkDuration))
+	assert.Equal(t, int64(adaptiveChunkMax), adaptiveChunkSize(1<<30, time.Millisecond))
+	assert.Equal(t, int64(adaptiveChunkMax), adaptiveChunkSize(1<<20, 0))
+}
+
+func TestMultithreadAdaptChunkSize(t *testing.T) {
+	ctx := context.Background()
+	const size = 3 << 20
+	contents := []byte(random.String(size))
+	src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	writerAt := &memWriterAt{}
+	w := newTestWriterAtChunkWriter(size, 512<<10, writerAt)
+	mc := &multiThreadCopyState{
+		size:        size,
+		partSize:    512 << 10,
+		numChunks:   6,
+		src:         src,
+		noBuffering: true,
+		acc:         tr.Account(ctx, nil),
+		clock:       newFakeClock(),
+	}
+
+	// The first chunk takes no time so the rest should go in one chunk
+	require.NoError(t, mc.adaptChunkSize(ctx, w))
+	assert.Equal(t, int64(512<<10), mc.firstPartSize)
+	assert.Equal(t, int64(adaptiveChunkMax), mc.partSize)
+	assert.Equal(t, 2, mc.numChunks)
+	assert.Equal(t, mc.numChunks, w.chunks)
+
+	require.NoError(t, mc.copyChunk(ctx, 1, w))
+	assert.Equal(t, int64(size), mc.written.Load())
+	assert.Equal(t, contents, writerAt.buf)
+}
+
+// cancellingChunkWriter is a fs.ChunkWriter which cancels the
+// context part way through reading a chunk as if a sibling chunk had
+// failed
+type cancellingChunkWriter struct {
+	fs.ChunkWriter
+	cancel context.CancelFunc
+	read   int64
+}
+
+func (w *cancellingChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	n, err := io.CopyN(io.Discard, reader, 10)
+	w.read += n
+	if err != nil {
+		return w.read, err
+	}
+	w.cancel()
+	n, err = io.Copy(io.Discard, reader)
+	w.read += n
+	return w.read, err
+}
+
+func TestMultithreadCopyChunkCancel(t *testing.T) {
+	ctx := context.Background()
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	for _, noBuffering := range []bool{false, true} {
+		t.Run(fmt.Sprint(noBuffering), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			mc := &multiThreadCopyState{
+				size:        100,
+				partSize:    50,
+				numChunks:   2,
+				src:         src,
+				noBuffering: noBuffering,
+				acc:         tr.Account(ctx, nil),
+			}
+			w := &cancellingChunkWriter{cancel: cancel}
+			err := mc.copyChunk(ctx, 1, w)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.Canceled), err)
+			assert.Equal(t, int64(10), w.read)
+		})
+	}
+}
+
+// inFlightChunkWriter is a fs.ChunkWriter which records the bytes in
+// flight while the chunk is being written
+type inFlightChunkWriter struct {
+	fs.ChunkWriter
+	inFlight int64
+}
+
+func (w *inFlightChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	w.inFlight = accounting.Stats(ctx).BytesInFlight()
+	return io.Copy(io.Discard, reader)
+}
+
+func TestMultithreadCopyChunkBytesInFlight(t *testing.T) {
+	ctx := accounting.WithStatsGroup(context.Background(), "multithread-in-flight")
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	tr := accounting.Stats(ctx).NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	for _, test := range []struct {
+		noBuffering bool
+		want        int64
+	}{
+		{noBuffering: false, want: 50},
+		{noBuffering: true, want: 0},
+	} {
+		t.Run(fmt.Sprint(test.noBuffering), func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size:        100,
+				partSize:    50,
+				numChunks:   2,
+				src:         src,
+				noBuffering: test.noBuffering,
+				acc:         tr.Account(ctx, nil),
+			}
+			w := &inFlightChunkWriter{}
+			require.NoError(t, mc.copyChunk(ctx, 1, w))
+			assert.Equal(t, test.want, w.inFlight)
+			assert.Equal(t, int64(0), accounting.Stats(ctx).BytesInFlight())
+		})
+	}
+}
+
+// hashChunkWriter is a fs.ChunkWriterWithHash which records the
+// hashes it was passed
+type hashChunkWriter struct {
+	fs.ChunkWriter
+	hashes map[int]string
+}
+
+func (w *hashChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	return w.WriteChunkWithHash(ctx, chunkNumber, reader, "")
+}
+
+func (w *hashChunkWriter) WriteChunkWithHash(ctx context.Context, chunkNumber int, reader io.ReadSeeker, expectedHash string) (int64, error) {
+	w.hashes[chunkNumber] = expectedHash
+	return io.Copy(io.Discard, reader)
+}
+
+var _ fs.ChunkWriterWithHash = (*hashChunkWriter)(nil)
+
+func TestMultithreadCopyChunkWithHash(t *testing.T) {
+	ctx := context.Background()
+	contents := []byte(random.String(100))
+	src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	chunkMD5 := fmt.Sprintf("%x", md5.Sum(contents[50:]))
+	for _, test := range []struct {
+		name        string
+		chunkHash   hash.Type
+		noBuffering bool
+		want        string
+	}{
+		{name: "MD5", chunkHash: hash.MD5, want: chunkMD5},
+		{name: "None", chunkHash: hash.None, want: ""},
+		{name: "NoBuffering", chunkHash: hash.MD5, noBuffering: true, want: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size:        100,
+				partSize:    50,
+				numChunks:   2,
+				src:         src,
+				noBuffering: test.noBuffering,
+				chunkHash:   test.chunkHash,
+				acc:         tr.Account(ctx, nil),
+			}
+			w := &hashChunkWriter{hashes: map[int]string{}}
+			require.NoError(t, mc.copyChunk(ctx, 1, w))
+			assert.Equal(t, map[int]string{1: test.want}, w.hashes)
+		})
+	}
+}
+
+// flakyObject returns readers which fail after reading every bytes
+type flakyObject struct {
+	*mockobject.ContentMockObject
+	every int
+	opens atomic.Int32
+}
+
+func (o *flakyObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.opens.Add(1)
+	in, err := o.ContentMockObject.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyReadCloser{ReadCloser: in, left: o.every}, nil
+}
+
+type flakyReadCloser struct {
+	io.ReadCloser
+	left int
+}
+
+func (r *flakyReadCloser) Read(p []byte) (n int, err error) {
+	if r.left <= 0 {
+		return 0, errors.New("flaky read failure")
+	}
+	if len(p) > r.left {
+		p = p[:r.left]
+	}
+	n, err = r.ReadCloser.Read(p)
+	r.left -= n
+	return n, err
+}
+
+func TestMultithreadCopyChunkReadRetries(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	// Make ReOpen give up after the first read error
+	ci.LowLevelRetries = 1
+	contents := []byte(random.String(100))
+	chunkMD5 := fmt.Sprintf("%x", md5.Sum(contents[50:]))
+	isFlaky := func(err error) bool {
+		return strings.Contains(err.Error(), "flaky")
+	}
+	for _, test := range []struct {
+		name        string
+		every       int
+		shouldRetry RetryClassifier
+		opens       int32
+		retries     int64
+		wantErr     bool
+	}{
+		{name: "OK", every: 100, opens: 1, retries: 0},
+		{name: "Retried", every: 15, opens: 4, retries: 3},
+		{name: "TooManyRetries", every: 10, opens: 1 + chunkReadRetries, retries: 1 + chunkReadRetries, wantErr: true},
+		{name: "ClassifiedRetry", every: 15, shouldRetry: isFlaky, opens: 4, retries: 3},
+		{name: "ClassifiedNoRetry", every: 15, shouldRetry: fserrors.ShouldRetry, opens: 1, retries: 1, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := ctx
+			if test.shouldRetry != nil {
+				ctx = WithMultiThreadRetryClassifier(ctx, test.shouldRetry)
+			}
+			src := &flakyObject{
+				ContentMockObject: mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone),
+				every:             test.every,
+			}
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			mc := &multiThreadCopyState{
+				size:        100,
+				partSize:    50,
+				numChunks:   2,
+				src:         src,
+				chunkHash:   hash.MD5,
+				acc:         tr.Account(ctx, nil),
+				shouldRetry: getMultiThreadRetryClassifier(ctx),
+			}
+			w := &hashChunkWriter{hashes: map[int]string{}}
+			err := mc.copyChunk(ctx, 1, w)
+			assert.Equal(t, test.opens, src.opens.Load())
+			assert.Equal(t, test.retries, mc.retries.Load())
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "flaky read failure")
+				return
+			}
+			require.NoError(t, err)
+			// The data read from each open was kept
+			assert.Equal(t, map[int]string{1: chunkMD5}, w.hashes)
+		})
+	}
+}
+
+func TestMultithreadDispatchJitter(t *testing.T) {
+	ctx := context.Background()
+	mc := &multiThreadCopyState{}
+
+	start := time.Now()
+	mc.dispatchJitter(ctx, 0)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	mc.dispatchJitter(ctx, 100*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	start = time.Now()
+	mc.dispatchJitter(ctx, time.Hour)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// noRangeObject is an fs.Object which ignores the RangeOption
+type noRangeObject struct {
+	fs.Object
+}
+
+func (o noRangeObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	return o.Object.Open(ctx)
+}
+
+func TestMultithreadCopyChunkCheckRange(t *testing.T) {
+	ctx := context.Background()
+	obj := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	srcFs, err := mockfs.NewFs(ctx, "norange", "", nil)
+	require.NoError(t, err)
+	obj.SetFs(srcFs)
+	defer func() {
+		ignoresRangeMu.Lock()
+		delete(ignoresRange, fs.ConfigString(srcFs))
+		ignoresRangeMu.Unlock()
+	}()
+	tr := accounting.GlobalStats().NewTransfer(obj, nil)
+	defer tr.Done(ctx, nil)
+	for _, test := range []struct {
+		name    string
+		src     fs.Object
+		wantErr string
+	}{
+		{name: "Ranged", src: obj},
+		{name: "NotRanged", src: noRangeObject{obj}, wantErr: "source returned more data than requested"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size:       100,
+				partSize:   50,
+				numChunks:  2,
+				src:        test.src,
+				checkRange: true,
+				acc:        tr.Account(ctx, nil),
+			}
+			err := mc.copyChunk(ctx, 0, badChunkWriter{})
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				assert.False(t, sourceHonoursRange(srcFs))
+			} else {
+				require.NoError(t, err)
+				assert.True(t, sourceHonoursRange(srcFs))
+			}
+		})
+	}
+}
+
+func TestMultithreadAccountedBuffer(t *testing.T) {
+	var accounted int
+	account := func(n int) error {
+		accounted += n
+		return nil
+	}
+	b := newAccountedBuffer([]byte("0123456789"), account)
+	got, err := io.ReadAll(b)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(got))
+	assert.Equal(t, 10, accounted)
+
+	// Only account the second read through
+	accounted = 0
+	b.DelayAccounting(2)
+	_, err = b.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	_, err = io.ReadAll(b)
+	require.NoError(t, err)
+	assert.Equal(t, 0, accounted)
+	_, err = b.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	_, err = io.ReadAll(b)
+	require.NoError(t, err)
+	assert.Equal(t, 10, accounted)
+}
+
+func TestMultithreadCopyChunkBuffers(t *testing.T) {
+	ctx := context.Background()
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	for _, test := range []struct {
+		bufSize int
+		wantErr string
+	}{
+		{bufSize: 50},
+		{bufSize: 49, wantErr: "supplied buffer size 49 is smaller than chunk size 50"},
+	} {
+		t.Run(fmt.Sprint(test.bufSize), func(t *testing.T) {
+			buffers := make(chan []byte, 1)
+			buffers <- make([]byte, test.bufSize)
+			mc := &multiThreadCopyState{
+				size:      100,
+				partSize:  50,
+				numChunks: 2,
+				src:       src,
+				acc:       tr.Account(ctx, nil),
+				buffers:   getMultiThreadBuffers(WithMultiThreadBuffers(ctx, buffers)),
+			}
+			err := mc.copyChunk(ctx, 1, badChunkWriter{})
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			// Check the buffer was returned
+			assert.Len(t, buffers, 1)
+		})
+	}
+}
+
+// slowCloseChunkWriter is a fs.ChunkWriter which doesn't finish
+// closing until its context is done
+type slowCloseChunkWriter struct {
+	fs.ChunkWriter
+}
+
+func (w slowCloseChunkWriter) Close(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestMultithreadCloseChunkWriterTimeout(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	err := closeChunkWriter(ctx, slowCloseChunkWriter{}, 10*time.Millisecond)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "timed out after 10ms finalizing object")
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = closeChunkWriter(ctx, slowCloseChunkWriter{}, 0)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Contains(t, err.Error(), "failed to close object after copy")
+}
+
+// Skip if not multithread, returning the chunkSize otherwise
+func skipIfNotMultithread(ctx context.Context, t *testing.T, r *fstest.Run) int {
+	features := r.Fremote.Features()
+	if features.OpenChunkWriter == nil && features.OpenWriterAt == nil {
+		t.Skip("multithread writing not supported")
+	}
+
+	// Only support one hash for the local backend otherwise we end up spending a huge amount of CPU on hashing!
+	if r.Fremote.Features().IsLocal {
+		oldHashes := hash.SupportOnly([]hash.Type{r.Fremote.Hashes().GetOne()})
+		t.Cleanup(func() {
+			_ = hash.SupportOnly(oldHashes)
+		})
+	}
+
+	ci := fs.GetConfig(ctx)
+	chunkSize := int(ci.MultiThreadChunkSize)
+	if features.OpenChunkWriter != nil {
+		//OpenChunkWriter func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
+		const fileName = "chunksize-probe"
+		src := object.NewStaticObjectInfo(fileName, time.Now(), int64(100*fs.Mebi), true, nil, nil)
+		info, writer, err := features.OpenChunkWriter(ctx, fileName, src)
+		require.NoError(t, err)
+		chunkSize = int(info.ChunkSize)
+		err = writer.Abort(ctx)
+		require.NoError(t, err)
+	}
+	return chunkSize
+}
+
+func TestMultithreadCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	chunkSize := skipIfNotMultithread(ctx, t, r)
+	// Check every other transfer for metadata
+	checkMetadata := false
+	ctx, ci := fs.AddConfig(ctx)
+
+	for _, upload := range []bool{false, true} {
+		for _, test := range []struct {
+			size    int
+			streams int
+		}{
+			{size: chunkSize*2 - 1, streams: 2},
+			{size: chunkSize * 2, streams: 2},
+			{size: chunkSize*2 + 1, streams: 2},
+		} {
+			checkMetadata = !checkMetadata
+			ci.Metadata = checkMetadata
+			fileName := fmt.Sprintf("test-multithread-copy-%v-%d-%d", upload, test.size, test.streams)
+			t.Run(fmt.Sprintf("upload=%v,size=%v,streams=%v", upload, test.size, test.streams), func(t *testing.T) {
+				if *fstest.SizeLimit > 0 && int64(test.size) > *fstest.SizeLimit {
+					t.Skipf("exceeded file size limit %d > %d", test.size, *fstest.SizeLimit)
+				}
+				var (
+					contents     = random.String(test.size)
+					t1           = fstest.Time("2001-02-03T04:05:06.499999999Z")
+					file1        fstest.Item
+					src, dst     fs.Object
+					err          error
+					testMetadata = fs.Metadata{
+						// System metadata supported by all backends
+						"mtime": t1.Format(time.RFC3339Nano),
+						// User metadata
+						"potato": "jersey",
+					}
+				)
+
+				var fSrc, fDst fs.Fs
+				if upload {
+					file1 = r.WriteFile(fileName, contents, t1)
+					r.CheckRemoteItems(t)
+					r.CheckLocalItems(t, file1)
+					fDst, fSrc = r.Fremote, r.Flocal
+				} else {
+					file1 = r.WriteObject(ctx, fileName, contents, t1)
+					r.CheckRemoteItems(t, file1)
+					r.CheckLocalItems(t)
+					fDst, fSrc = r.Flocal, r.Fremote
+				}
+				src, err = fSrc.NewObject(ctx, fileName)
+				require.NoError(t, err)
+
+				do, canSetMetadata := src.(fs.SetMetadataer)
+				if checkMetadata && canSetMetadata {
+					// Set metadata on the source if required
+					err := do.SetMetadata(ctx, testMetadata)
+					if err == fs.ErrorNotImplemented {
+						canSetMetadata = false
+					} else {
+						require.NoError(t, err)
+						fstest.CheckEntryMetadata(ctx, t, r.Flocal, src, testMetadata)
+					}
+				}
+
+				accounting.GlobalStats().ResetCounters()
+				tr := accounting.GlobalStats().NewTransfer(src, nil)
+
+				defer func() {
+					tr.Done(ctx, err)
+				}()
+
+				var result *MultiThreadCopyResult
+				dst, result, err = MultiThreadCopyWithResult(ctx, fDst, fileName, src, test.streams, tr)
+				require.NoError(t, err)
+				require.NotNil(t, result)
+				assert.Equal(t, src.Size(), result.Bytes)
+				assert.Equal(t, calculateNumChunks(src.Size(), result.ChunkSize), result.Chunks)
+				assert.GreaterOrEqual(t, result.Concurrency, 1)
+				assert.Equal(t, 0, result.Retries)
+				assert.Greater(t, result.Duration, time.Duration(0))
+
+				assert.Equal(t, src.Size(), dst.Size())
+				assert.Equal(t, fileName, dst.Remote())
+				fstest.CheckListingWithPrecision(t, fSrc, []fstest.Item{file1}, nil, fs.GetModifyWindow(ctx, fDst, fSrc))
+				fstest.CheckListingWithPrecision(t, fDst, []fstest.Item{file1}, nil, fs.GetModifyWindow(ctx, fDst, fSrc))
+
+				if checkMetadata && canSetMetadata && fDst.Features().ReadMetadata {
+					fstest.CheckEntryMetadata(ctx, t, fDst, dst, testMetadata)
+				}
+
+				require.NoError(t, dst.Remove(ctx))
+				require.NoError(t, src.Remove(ctx))
+
+			})
+		}
+	}
+}
+
+// Make sure --max-transfer stops the multi-thread copy with a bounded overshoot
+func TestMultithreadCopyMaxTransfer(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadChunkSize = fs.SizeSuffix(fs.Mebi)
+	chunkSize := skipIfNotMultithread(ctx, t, r)
+	const streams = 2
+	size := 16 * chunkSize
+
+	if *fstest.SizeLimit > 0 && int64(size) > *fstest.SizeLimit {
+		t.Skipf("exceeded file size limit %d > %d", size, *fstest.SizeLimit)
+	}
+
+	const fileName = "test-multithread-max-transfer"
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	file1 := r.WriteFile(fileName, random.String(size), t1)
+	r.CheckLocalItems(t, file1)
+
+	src, err := r.Flocal.NewObject(ctx, fileName)
+	require.NoError(t, err)
+	accounting.GlobalStats().ResetCounters()
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+
+	ci.MaxTransfer = fs.SizeSuffix(4 * chunkSize)
+	ci.CutoffMode = fs.CutoffModeHard
+	dst, err := multiThreadCopy(ctx, r.Fremote, fileName, src, streams, tr)
+	assert.True(t, errors.Is(err, accounting.ErrorMaxTransferLimitReached), "unexpected error: %v", err)
+	assert.Nil(t, dst)
+	assert.LessOrEqual(t, accounting.GlobalStats().GetBytes(), int64(ci.MaxTransfer)+int64(streams*chunkSize))
+}
+
+// Check that if the backend can't multi-thread the object the copy
+// falls back to a single stream
+func TestMultithreadCopyCantMultiThread(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadStreams = 2
+	ci.MultiThreadSet = true
+	ci.MultiThreadCutoff = 1
+	ci.MultiThreadLocal = true
+
+	features := r.Flocal.Features()
+	oldOpenChunkWriter := features.OpenChunkWriter
+	features.OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return fs.ChunkWriterInfo{}, nil, fs.ErrorCantMultiThread
+	}
+	defer func() {
+		features.OpenChunkWriter = oldOpenChunkWriter
+	}()
+
+	const fileName = "test-multithread-cant-multithread"
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	file1 := r.WriteObject(ctx, fileName, random.String(100), t1)
+	r.CheckRemoteItems(t, file1)
+
+	src, err := r.Fremote.NewObject(ctx, fileName)
+	require.NoError(t, err)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	_, err = multiThreadCopy(ctx, r.Flocal, fileName, src, 2, tr)
+	tr.Done(ctx, nil)
+	assert.True(t, errors.Is(err, fs.ErrorCantMultiThread), "unexpected error: %v", err)
+
+	dst, err := Copy(ctx, r.Flocal, nil, fileName, src)
+	require.NoError(t, err)
+	assert.Equal(t, src.Size(), dst.Size())
+	r.CheckLocalItems(t, file1)
+}
+
+// orderChunkWriter is a fs.ChunkWriter which records the order the
+// chunks were written in
+type orderChunkWriter struct {
+	f       *mockfs.Fs
+	remote  string
+	last    int
+	mu      sync.Mutex
+	order   []int
+	written int64
+}
+
+func (w *orderChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	// Make the final chunk quicker than the others
+	if chunkNumber != w.last {
+		time.Sleep(100 * time.Millisecond)
+	}
+	n, err := io.Copy(io.Discard, reader)
+	w.mu.Lock()
+	w.order = append(w.order, chunkNumber)
+	w.written += n
+	w.mu.Unlock()
+	return n, err
+}
+
+func (w *orderChunkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.f.AddObject(&patternObject{Object: mockobject.New(w.remote), f: w.f, size: w.written})
+	return nil
+}
+
+func (w *orderChunkWriter) Abort(ctx context.Context) error {
+	return nil
+}
+
+func (w *orderChunkWriter) setFs(f *mockfs.Fs) {
+	w.f = f
+}
+
+// testChunkWriter is a fs.ChunkWriter which adds the object it writes
+// to the Fs set with setFs when it is closed
+type testChunkWriter interface {
+	fs.ChunkWriter
+	setFs(f *mockfs.Fs)
+}
+
+// testChunkWriterInfo is the ChunkWriterInfo most of the tests use,
+// which splits a 100 byte file into 4 chunks
+var testChunkWriterInfo = fs.ChunkWriterInfo{
+	ChunkSize:   25,
+	Concurrency: 4,
+}
+
+// setTestSrcFs sets src to come from a "sausage" mockfs, so it isn't
+// on the same Fs as the destinations the tests make, and returns it
+func setTestSrcFs(ctx context.Context, t *testing.T, src *mockobject.ContentMockObject) fs.Fs {
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	src.SetFs(srcFs)
+	return srcFs
+}
+
+// newTestChunkWriterFs returns a "potato" mockfs whose OpenChunkWriter
+// returns info and w, which writes its object to that Fs
+func newTestChunkWriterFs(ctx context.Context, t *testing.T, info fs.ChunkWriterInfo, w testChunkWriter) fs.Fs {
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	w.setFs(f.(*mockfs.Fs))
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return info, w, nil
+	}
+	return f
+}
+
+func TestMultithreadCopyFinalChunkLast(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &orderChunkWriter{remote: remote, last: 3}
+	f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+		ChunkSize:      25,
+		Concurrency:    4,
+		FinalChunkLast: true,
+	}, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	require.Len(t, w.order, 4)
+	assert.Equal(t, 3, w.order[3], "final chunk written before preceding chunks: %v", w.order)
+}
+
+// streamChunkWriter records whether the chunks it was passed were
+// streamed from the source rather than buffered
+type streamChunkWriter struct {
+	orderChunkWriter
+	mu       sync.Mutex
+	streamed []bool
+}
+
+func (w *streamChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	_, streamed := reader.(*cancelReader).ReadSeeker.(*ReOpen)
+	w.mu.Lock()
+	w.streamed = append(w.streamed, streamed)
+	w.mu.Unlock()
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func TestMultithreadCopyNoSeekNeeded(t *testing.T) {
+	for _, noSeekNeeded := range []bool{false, true} {
+		t.Run(fmt.Sprint(noSeekNeeded), func(t *testing.T) {
+			ctx := context.Background()
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &streamChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:    25,
+				Concurrency:  4,
+				NoSeekNeeded: noSeekNeeded,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, []bool{noSeekNeeded, noSeekNeeded, noSeekNeeded, noSeekNeeded}, w.streamed)
+		})
+	}
+}
+
+// writeCountChunkWriter counts the writes io.Copy makes from the
+// chunk readers, like a backend writing the chunks to a socket
+type writeCountChunkWriter struct {
+	orderChunkWriter
+	writes atomic.Int64
+}
+
+func (w *writeCountChunkWriter) Write(p []byte) (int, error) {
+	w.writes.Add(1)
+	return len(p), nil
+}
+
+func (w *writeCountChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	n, err := io.Copy(struct{ io.Writer }{w}, reader)
+	w.mu.Lock()
+	w.order = append(w.order, chunkNumber)
+	w.written += n
+	w.mu.Unlock()
+	return n, err
+}
+
+func TestMultithreadCopyWriteBuffer(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadWriteBufferSize = 128 * 1024
+	const remote = "file.txt"
+	const size = 1024 * 1024
+	src := mockobject.New(remote).WithContent([]byte(random.String(size)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	for _, test := range []struct {
+		name        string
+		writeBuffer bool
+		options     []fs.OpenOption
+		maxWrites   int64
+	}{
+		{name: "Off", writeBuffer: false},
+		{name: "On", writeBuffer: true, maxWrites: size / (128 * 1024)},
+		{name: "Disabled", writeBuffer: true, options: []fs.OpenOption{&fs.NoWriteBufferOption{}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := &writeCountChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:   size / 4,
+				Concurrency: 4,
+				WriteBuffer: test.writeBuffer,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr, test.options...)
+			require.NoError(t, err)
+			assert.Equal(t, int64(size), dst.Size())
+			if test.maxWrites > 0 {
+				assert.LessOrEqual(t, w.writes.Load(), test.maxWrites)
+			} else {
+				// io.Copy writes at most 32k at once without the buffer
+				assert.GreaterOrEqual(t, w.writes.Load(), int64(size/(32*1024)))
+			}
+		})
+	}
+}
+
+// progressChunkWriter reads each chunk in quarters recording how many
+// bytes have been accounted after each one
+type progressChunkWriter struct {
+	orderChunkWriter
+	progress [][]int64
+}
+
+func (w *progressChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	stats := accounting.GlobalStats()
+	start := stats.GetBytes()
+	var progress []int64
+	buf := make([]byte, 25)
+	var n int64
+	for {
+		nr, err := io.ReadFull(reader, buf)
+		n += int64(nr)
+		if nr > 0 {
+			progress = append(progress, stats.GetBytes()-start)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	w.mu.Lock()
+	w.progress = append(w.progress, progress)
+	w.written += n
+	w.mu.Unlock()
+	return n, nil
+}
+
+func TestMultithreadCopyChunkTimes(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	for _, test := range []struct {
+		name         string
+		noSeekNeeded bool
+		wantChunks   int64
+	}{
+		{name: "Buffered", wantChunks: 4},
+		{name: "NotBuffered", noSeekNeeded: true, wantChunks: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := accounting.WithStatsGroup(ctx, "TestMultithreadCopyChunkTimes"+test.name)
+			w := &orderChunkWriter{remote: remote, last: -1}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:    25,
+				Concurrency:  4,
+				NoSeekNeeded: test.noSeekNeeded,
+			}, w)
+
+			tr := accounting.Stats(ctx).NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			_, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+
+			read, write, chunks := accounting.Stats(ctx).MultiThreadChunkTimes()
+			assert.Equal(t, test.wantChunks, chunks)
+			if chunks > 0 {
+				// Each chunk write sleeps for 100ms
+				assert.GreaterOrEqual(t, write, time.Duration(chunks)*100*time.Millisecond)
+				assert.Less(t, read, write)
+			}
+		})
+	}
+}
+
+func TestMultithreadCopyProgress(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(200)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	buffers := make(chan []byte, 1)
+	buffers <- make([]byte, 100)
+	for _, test := range []struct {
+		name         string
+		ctx          context.Context
+		noSeekNeeded bool
+	}{
+		{name: "Buffered", ctx: ctx},
+		{name: "Buffers", ctx: WithMultiThreadBuffers(ctx, buffers)},
+		{name: "Streamed", ctx: ctx, noSeekNeeded: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := &progressChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote}}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:    100,
+				Concurrency:  1,
+				NoSeekNeeded: test.noSeekNeeded,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			_, err := multiThreadCopy(test.ctx, f, remote, src, 1, tr)
+			require.NoError(t, err)
+
+			// The bytes are accounted as the chunk writer reads
+			// them rather than in one go for each chunk
+			assert.Equal(t, [][]int64{{25, 50, 75, 100}, {25, 50, 75, 100}}, w.progress)
+		})
+	}
+}
+
+func TestMultithreadCopyMinChunkSize(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		chunkSizeSet bool
+		wantErr      bool
+	}{
+		{name: "Clamp", chunkSizeSet: false, wantErr: false},
+		{name: "Explicit", chunkSizeSet: true, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadChunkSizeSet = test.chunkSizeSet
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &orderChunkWriter{remote: remote, last: -1}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:    10,
+				Concurrency:  4,
+				MinChunkSize: 25,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Nil(t, dst)
+				assert.Contains(t, err.Error(), "--multi-thread-chunk-size 10 is below the minimum chunk size 25")
+				assert.Len(t, w.order, 0)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Len(t, w.order, 4)
+		})
+	}
+}
+
+func TestMultithreadCopyWriterAtMinChunkSize(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 25
+	ci.MultiThreadStreams = 2
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	f, err := fs.NewFs(ctx, ":mockfs,multi_thread_min_chunk_size=40B:")
+	require.NoError(t, err)
+	f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+	}
+
+	// Only the last chunk is smaller than the minimum
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 2, tr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), result.ChunkSize)
+	assert.Equal(t, 3, result.Chunks)
+	in, err := dst.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, in.Close())
+	require.NoError(t, err)
+	assert.Equal(t, contents, got)
+}
+
+func TestMultithreadAdaptChunkSizeMin(t *testing.T) {
+	ctx := context.Background()
+	const size = 3 << 20
+	src := mockobject.New("file.txt").WithContent([]byte(random.String(size)), mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	w := newTestWriterAtChunkWriter(size, 512<<10, &memWriterAt{})
+	clock := newFakeClock()
+	mc := &multiThreadCopyState{
+		size:        size,
+		partSize:    512 << 10,
+		minPartSize: 3 << 19,
+		numChunks:   6,
+		src:         src,
+		noBuffering: true,
+		clock:       clock,
+	}
+	mc.acc = &clockAccount{MultiThreadAccount: tr.Account(ctx, nil), clock: clock, took: adaptiveChunkDuration / 2}
+
+	// The speed asks for 1 MiB chunks but the minimum is 1.5 MiB
+	require.NoError(t, mc.adaptChunkSize(ctx, w))
+	assert.Equal(t, int64(3<<19), mc.partSize)
+	assert.Equal(t, 3, mc.numChunks)
+	assert.Equal(t, mc.numChunks, w.chunks)
+}
+
+// goroutineChunkWriter is a fs.ChunkWriter which records the most
+// goroutines running while writing a chunk
+type goroutineChunkWriter struct {
+	orderChunkWriter
+	maxGoroutines int
+}
+
+func (w *goroutineChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	n := runtime.NumGoroutine()
+	w.mu.Lock()
+	if n > w.maxGoroutines {
+		w.maxGoroutines = n
+	}
+	w.mu.Unlock()
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func TestMultithreadCopySerialChunks(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	for _, test := range []struct {
+		size        int
+		concurrency int
+	}{
+		{size: 25, concurrency: 1},
+		{size: 50, concurrency: 2},
+	} {
+		t.Run(fmt.Sprint(test.size), func(t *testing.T) {
+			src := mockobject.New(remote).WithContent([]byte(random.String(test.size)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &goroutineChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:   25,
+				Concurrency: 64,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			before := runtime.NumGoroutine()
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 64, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, test.concurrency, result.Chunks)
+			assert.Equal(t, test.concurrency, result.Concurrency)
+			assert.Len(t, w.order, test.concurrency)
+			assert.Equal(t, "OpenChunkWriter", result.WriteMethod)
+			assert.Equal(t, "OpenChunkWriter", tr.WriteMethod())
+			// Both chunks of a 2 chunk file should be copied at once
+			assert.Equal(t, test.concurrency, result.PeakInFlight)
+			if test.concurrency == 1 {
+				// Only the accounting goroutine should have been
+				// started, and the atexit handler if this is the
+				// first transfer
+				assert.LessOrEqual(t, w.maxGoroutines, before+2)
+			}
+		})
+	}
+}
+
+func TestMultithreadCopyPeakInFlight(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	for _, streams := range []int{2, 4} {
+		t.Run(fmt.Sprintf("Streams=%d", streams), func(t *testing.T) {
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			// Every chunk takes 100ms so all the streams are used
+			w := &orderChunkWriter{remote: remote, last: -1}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:   25,
+				Concurrency: streams,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, streams, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, streams, result.Concurrency)
+			assert.Equal(t, streams, result.PeakInFlight)
+			assert.Equal(t, streams, tr.PeakStreams())
+		})
+	}
+}
+
+// failChunkWriter is an orderChunkWriter which fails to write chunk
+// failAt and records whether it was aborted
+type failChunkWriter struct {
+	orderChunkWriter
+	failAt  int
+	aborted atomic.Bool
+}
+
+func (w *failChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	if chunkNumber == w.failAt {
+		return 0, errors.New("potato")
+	}
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func (w *failChunkWriter) Abort(ctx context.Context) error {
+	w.aborted.Store(true)
+	return nil
+}
+
+// abortCtxChunkWriter cancels the copy while writing failAt and
+// records the context Abort is called with
+type abortCtxChunkWriter struct {
+	orderChunkWriter
+	failAt   int
+	cancel   context.CancelFunc
+	abortCtx context.Context
+	abortErr error // abortCtx.Err() during Abort
+}
+
+func (w *abortCtxChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	if chunkNumber == w.failAt {
+		w.cancel()
+		return 0, context.Canceled
+	}
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func (w *abortCtxChunkWriter) Abort(ctx context.Context) error {
+	w.abortCtx = ctx
+	w.abortErr = ctx.Err()
+	return w.abortErr
+}
+
+func TestMultithreadCopyAbortCancelled(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadStreams = 7
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(50)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &abortCtxChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, failAt: 0, cancel: cancel}
+	f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+		ChunkSize:   25,
+		Concurrency: 1,
+	}, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, err := multiThreadCopy(copyCtx, f, remote, src, 1, tr)
+	require.Error(t, err)
+	require.Error(t, copyCtx.Err())
+
+	// The abort should get a live context with a deadline and the config
+	require.NotNil(t, w.abortCtx, "not aborted")
+	assert.NoError(t, w.abortErr)
+	_, ok := w.abortCtx.Deadline()
+	assert.True(t, ok, "no deadline")
+	assert.Equal(t, 7, fs.GetConfig(w.abortCtx).MultiThreadStreams)
+}
+
+func TestMultithreadCopySerialChunksError(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(50)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &failChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, failAt: 0}
+	f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+		ChunkSize:   25,
+		Concurrency: 1,
+	}, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 1, tr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "potato")
+	assert.Nil(t, dst)
+	// The chunks after the failed one weren't written
+	assert.Len(t, w.order, 0)
+	assert.True(t, w.aborted.Load())
+}
+
+func TestMultithreadCopySingleShotUpload(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	for _, test := range []struct {
+		name             string
+		chunkSize        int64
+		singleShotUpload bool
+		wantSingleShot   bool
+	}{
+		{name: "OneChunk", chunkSize: 100, singleShotUpload: true, wantSingleShot: true},
+		{name: "OneChunkNotSupported", chunkSize: 100},
+		{name: "FourChunks", chunkSize: 25, singleShotUpload: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			w := &failChunkWriter{orderChunkWriter: orderChunkWriter{f: f.(*mockfs.Fs), remote: remote, last: -1}, failAt: -1}
+			info := fs.ChunkWriterInfo{
+				ChunkSize:        test.chunkSize,
+				Concurrency:      4,
+				SingleShotUpload: test.singleShotUpload,
+			}
+			opened := false
+			f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+				opened = true
+				return info, w, nil
+			}
+			f.Features().PlanChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, error) {
+				return info, nil
+			}
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if test.wantSingleShot {
+				assert.True(t, errors.Is(err, fs.ErrorCantMultiThread), "unexpected error: %v", err)
+				assert.Nil(t, dst)
+				// No upload was started
+				assert.False(t, opened)
+				assert.False(t, w.aborted.Load())
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, dst)
+			assert.True(t, opened)
+			assert.False(t, w.aborted.Load())
+			assert.Equal(t, int64(100), w.written)
+		})
+	}
+}
+
+// fileWriterAt is a memWriterAt which adds the data written to f as
+// remote when it is closed
+type fileWriterAt struct {
+	memWriterAt
+	f      *mockfs.Fs
+	remote string
+}
+
+func (w *fileWriterAt) Close() error {
+	w.f.AddObject(mockobject.New(w.remote).WithContent(w.buf, mockobject.SeekModeNone))
+	return nil
+}
+
+func TestMultithreadCopyKeepPartial(t *testing.T) {
+	const remote = "file.txt"
+	for _, keepPartial := range []bool{false, true} {
+		t.Run(fmt.Sprintf("KeepPartial=%v", keepPartial), func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadChunkSize = 25
+			ci.MultiThreadStreams = 1
+			ci.MultiThreadKeepPartial = keepPartial
+			contents := []byte(random.String(100))
+			content := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, content)
+			// Block reading chunk 2 so it can be cancelled
+			src := &blockingOpenObject{ContentMockObject: content, blockStart: 50, opened: make(chan struct{})}
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				<-src.opened
+				cancel()
+			}()
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 1, tr)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.Canceled))
+			assert.Nil(t, dst)
+			assert.Equal(t, []int{0, 1}, result.CompletedChunks)
+			if !keepPartial {
+				assert.Nil(t, result.Partial)
+				return
+			}
+			require.NotNil(t, result.Partial)
+			assert.Equal(t, remote, result.Partial.Remote())
+			in, err := result.Partial.Open(context.Background())
+			require.NoError(t, err)
+			data, err := io.ReadAll(in)
+			require.NoError(t, err)
+			require.NoError(t, in.Close())
+			require.GreaterOrEqual(t, len(data), 50)
+			assert.Equal(t, contents[:50], data[:50])
+		})
+	}
+}
+
+func TestMultithreadCopyBackendConcurrency(t *testing.T) {
+	const remote = "file.txt"
+	for _, test := range []struct {
+		backend int
+		want    int
+		wantErr bool
+	}{
+		{backend: 0, want: 3},
+		{backend: 2, want: 2},
+		{backend: -1, wantErr: true},
+	} {
+		t.Run(fmt.Sprintf("Backend=%d", test.backend), func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadSet = false
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &failChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, failAt: -1}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:   25,
+				Concurrency: test.backend,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 3, tr)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid concurrency -1")
+				assert.Nil(t, dst)
+				assert.True(t, w.aborted.Load())
+				assert.Len(t, w.order, 0)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, test.want, result.Concurrency)
+			assert.Len(t, w.order, 4)
+		})
+	}
+}
+
+func TestMultithreadCopyKeepPartsOnError(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	for _, keep := range []bool{false, true} {
+		t.Run(fmt.Sprint(keep), func(t *testing.T) {
+			ci.MultiThreadKeepPartsOnError = keep
+			w := &failChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, failAt: 2}
+			f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			_, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "potato")
+			assert.Equal(t, !keep, w.aborted.Load())
+		})
+	}
+}
+
+// activeChunkWriter is an orderChunkWriter which records the most
+// chunks written at once
+type activeChunkWriter struct {
+	orderChunkWriter
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func (w *activeChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	active := w.active.Add(1)
+	defer w.active.Add(-1)
+	for {
+		maxActive := w.maxActive.Load()
+		if active <= maxActive || w.maxActive.CompareAndSwap(maxActive, active) {
+			break
+		}
+	}
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func TestMultithreadCopyReadWriteStreams(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		readStreams     int
+		writeStreams    int
+		wantConcurrency int
+		wantMaxWrites   int32
+	}{
+		{name: "Default", wantConcurrency: 4, wantMaxWrites: 4},
+		{name: "MoreReads", readStreams: 6, writeStreams: 2, wantConcurrency: 6, wantMaxWrites: 2},
+		{name: "MoreWrites", readStreams: 1, writeStreams: 3, wantConcurrency: 3, wantMaxWrites: 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadReadStreams = test.readStreams
+			ci.MultiThreadWriteStreams = test.writeStreams
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(240)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &activeChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:   10,
+				Concurrency: 4,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, test.wantConcurrency, result.Concurrency)
+			assert.Len(t, w.order, 24)
+			assert.LessOrEqual(t, w.maxActive.Load(), test.wantMaxWrites)
+		})
+	}
+}
+
+// headerObject is an fs.Object which needs an X-Auth header to open
+type headerObject struct {
+	fs.Object
+	opens atomic.Int32
+}
+
+func (o *headerObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	for _, option := range options {
+		if header, ok := option.(*fs.HTTPOption); ok && header.Key == "X-Auth" && header.Value == "potato" {
+			o.opens.Add(1)
+			return o.Object.Open(ctx, options...)
+		}
+	}
+	return nil, errors.New("401 unauthorized: missing X-Auth header")
+}
+
+func TestMultithreadCopyDownloadHeaders(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DownloadHeaders = []*fs.HTTPOption{{Key: "X-Auth", Value: "potato"}}
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	srcObj := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, srcObj)
+	src := &headerObject{Object: srcObj}
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Len(t, w.order, 4)
+	assert.Equal(t, int32(4), src.opens.Load())
+}
+
+func TestMultithreadCopyNoOverwrite(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		set    func(ci *fs.ConfigInfo)
+		exists bool
+		want   string
+	}{
+		{name: "Immutable", set: func(ci *fs.ConfigInfo) { ci.Immutable = true }, exists: true, want: "--immutable"},
+		{name: "IgnoreExisting", set: func(ci *fs.ConfigInfo) { ci.IgnoreExisting = true }, exists: true, want: "--ignore-existing"},
+		{name: "Missing", set: func(ci *fs.ConfigInfo) { ci.Immutable = true }, exists: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			test.set(ci)
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			if test.exists {
+				f.(*mockfs.Fs).AddObject(mockobject.New(remote))
+			}
+			opened := false
+			f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+				opened = true
+				return fs.ChunkWriterInfo{
+					ChunkSize:   25,
+					Concurrency: 4,
+				}, &orderChunkWriter{f: f.(*mockfs.Fs), remote: remote, last: -1}, nil
+			}
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if test.want != "" {
+				require.Error(t, err)
+				assert.Nil(t, dst)
+				assert.Contains(t, err.Error(), "not overwriting existing destination as "+test.want+" is set")
+				assert.False(t, opened, "chunk writer opened")
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, dst)
+			assert.True(t, opened)
+		})
+	}
+}
+
+// mockfsWriterAt is a memWriterAt which adds the object to f when
+// closed
+type mockfsWriterAt struct {
+	memWriterAt
+	f      *mockfs.Fs
+	remote string
+}
+
+func (w *mockfsWriterAt) Close() error {
+	w.f.AddObject(mockobject.New(w.remote).WithContent(w.buf, mockobject.SeekModeNone))
+	return nil
+}
+
+func TestMultithreadWriterAtChunkHashes(t *testing.T) {
+	ctx := context.Background()
+	contents := []byte(random.String(100))
+	const chunkSize = 30
+
+	writeChunks := func(t *testing.T, w *writerAtChunkWriter, chunks ...int) {
+		// write in reverse to check the order doesn't matter
+		for i := len(chunks) - 1; i >= 0; i-- {
+			chunk := chunks[i]
+			start, end := chunkRange(chunk, w.size, 0, chunkSize)
+			_, err := w.WriteChunk(ctx, chunk, bytes.NewReader(contents[start:end]))
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("Combined", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(int64(len(contents)), chunkSize, &memWriterAt{})
+		w.hashes = hash.NewHashSet(hash.CRC32)
+		writeChunks(t, w, 0, 1, 2, 3)
+		sum, err := w.writtenSum(hash.CRC32)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%08x", crc32.ChecksumIEEE(contents)), sum)
+		assert.Equal(t, fmt.Sprintf("%08x", crc32.ChecksumIEEE(contents[90:])), w.chunkSum(3, hash.CRC32))
+	})
+
+	t.Run("MissingChunk", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(int64(len(contents)), chunkSize, &memWriterAt{})
+		w.hashes = hash.NewHashSet(hash.CRC32)
+		writeChunks(t, w, 0, 1, 3)
+		_, err := w.writtenSum(hash.CRC32)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "chunk 3 wasn't hashed")
+	})
+
+	t.Run("NotCombinable", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(int64(len(contents)), chunkSize, &memWriterAt{})
+		w.hashes = hash.NewHashSet(hash.CRC32, hash.MD5)
+		writeChunks(t, w, 0, 1, 2, 3)
+		_, err := w.writtenSum(hash.MD5)
+		assert.Error(t, err)
+		sum := md5.Sum(contents[:chunkSize])
+		assert.Equal(t, fmt.Sprintf("%x", sum), w.chunkSum(0, hash.MD5))
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		w := newTestWriterAtChunkWriter(int64(len(contents)), chunkSize, &memWriterAt{})
+		w.hashes = 0
+		writeChunks(t, w, 0, 1, 2, 3)
+		assert.Equal(t, "", w.chunkSum(0, hash.CRC32))
+		_, err := w.writtenSum(hash.CRC32)
+		assert.Error(t, err)
+	})
+}
+
+func TestMultithreadCopyWrittenHashes(t *testing.T) {
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	md5Sum := func(data []byte) string {
+		sum := md5.Sum(data)
+		return fmt.Sprintf("%x", sum)
+	}
+	for _, test := range []struct {
+		chunkSize int
+		chunks    int
+		file      map[hash.Type]string
+	}{
+		{chunkSize: 30, chunks: 4, file: map[hash.Type]string{
+			hash.CRC32: fmt.Sprintf("%08x", crc32.ChecksumIEEE(contents)),
+		}},
+		{chunkSize: 1000, chunks: 1, file: map[hash.Type]string{
+			hash.CRC32: fmt.Sprintf("%08x", crc32.ChecksumIEEE(contents)),
+			hash.MD5:   md5Sum(contents),
+		}},
+	} {
+		t.Run(fmt.Sprint(test.chunkSize), func(t *testing.T) {
+			ctx, ci := fs.AddConfig(context.Background())
+			ci.MultiThreadChunkSize = fs.SizeSuffix(test.chunkSize)
+			src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return &mockfsWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+			}
+
+			var got []WrittenHashes
+			ctx = WithMultiThreadWrittenHashes(ctx, hash.NewHashSet(hash.CRC32, hash.MD5), func(gotRemote string, hashes WrittenHashes) {
+				assert.Equal(t, remote, gotRemote)
+				got = append(got, hashes)
+			})
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+
+			require.Len(t, got, 1)
+			require.Len(t, got[0].Chunks, test.chunks)
+			for chunk, sums := range got[0].Chunks {
+				start, end := chunkRange(chunk, int64(len(contents)), 0, int64(test.chunkSize))
+				assert.Equal(t, md5Sum(contents[start:end]), sums[hash.MD5], "chunk %d", chunk)
+			}
+			assert.Equal(t, test.file, got[0].File)
+		})
+	}
+}
+
+func TestMultithreadCopyChunkSizeBiggerThanFile(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 1000
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	writerAt := &mockfsWriterAt{f: f.(*mockfs.Fs), remote: remote}
+	f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return writerAt, nil
+	}
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Equal(t, 1, result.Chunks)
+	assert.Equal(t, int64(100), result.ChunkSize)
+	assert.Equal(t, int64(100), result.Bytes)
+	assert.Equal(t, contents, writerAt.buf)
+}
+
+func TestMultithreadCopyAtomic(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadAtomic = true
+	contents := []byte(random.String(100))
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name           string
+		remote         string
+		partialUploads bool
+		moveErr        error
+		wantTemp       bool
+	}{
+		{name: "Renamed", remote: "file.txt", partialUploads: true, wantTemp: true},
+		{name: "NoPartialUploads", remote: "file.txt", partialUploads: false},
+		{name: "AlreadyPartial", remote: "file.txt.abcdefgh.partial", partialUploads: true},
+		{name: "MoveFails", remote: "file.txt", partialUploads: true, moveErr: errors.New("potato"), wantTemp: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			src := mockobject.New(test.remote).WithContent(contents, mockobject.SeekModeNone)
+			src.SetFs(srcFs)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			features := f.Features()
+			features.PartialUploads = test.partialUploads
+			var opened, movedFrom string
+			features.OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				opened = remote
+				return &mockfsWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+			}
+			features.Move = func(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+				movedFrom = src.Remote()
+				if test.moveErr != nil {
+					return nil, test.moveErr
+				}
+				o := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+				f.(*mockfs.Fs).AddObject(o)
+				return o, nil
+			}
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, test.remote, src, 4, tr)
+			if !test.wantTemp {
+				require.NoError(t, err)
+				assert.Equal(t, test.remote, opened)
+				assert.Equal(t, "", movedFrom)
+				assert.Equal(t, test.remote, dst.Remote())
+				return
+			}
+			assert.NotEqual(t, test.remote, opened)
+			assert.True(t, strings.HasPrefix(opened, test.remote+"."), opened)
+			assert.True(t, strings.HasSuffix(opened, ci.PartialSuffix), opened)
+			assert.Equal(t, opened, movedFrom)
+			if test.moveErr != nil {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, test.moveErr)
+				assert.Contains(t, err.Error(), "failed to rename temporary file")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.remote, dst.Remote())
+		})
+	}
+}
+
+// noModTimeObject is an object whose SetModTime returns err
+type noModTimeObject struct {
+	*mockobject.ContentMockObject
+	err error
+}
+
+func (o *noModTimeObject) SetModTime(ctx context.Context, t time.Time) error {
+	return o.err
 }
 
-func TestMultithreadCalculateNumChunks(t *testing.T) {
+// noModTimeWriterAt adds a noModTimeObject when closed
+type noModTimeWriterAt struct {
+	mockfsWriterAt
+	err error
+}
+
+func (w *noModTimeWriterAt) Close() error {
+	w.f.AddObject(&noModTimeObject{
+		ContentMockObject: mockobject.New(w.remote).WithContent(w.buf, mockobject.SeekModeNone),
+		err:               w.err,
+	})
+	return nil
+}
+
+func TestMultithreadCopySetModTimeError(t *testing.T) {
 	for _, test := range []struct {
-		size          int64
-		chunkSize     int64
-		wantNumChunks int
+		name    string
+		err     error
+		wantErr bool
 	}{
-		{size: 1, chunkSize: multithreadChunkSize, wantNumChunks: 1},
-		{size: 1 << 20, chunkSize: 1, wantNumChunks: 1 << 20},
-		{size: 1 << 20, chunkSize: 2, wantNumChunks: 1 << 19},
-		{size: (1 << 20) + 1, chunkSize: 2, wantNumChunks: (1 << 19) + 1},
-		{size: (1 << 20) - 1, chunkSize: 2, wantNumChunks: 1 << 19},
+		{name: "OK", err: nil},
+		{name: "CantSetModTime", err: fs.ErrorCantSetModTime},
+		{name: "Wrapped", err: fmt.Errorf("crypt: %w", fs.ErrorCantSetModTimeWithoutDelete)},
+		{name: "Other", err: errors.New("potato"), wantErr: true},
 	} {
-		t.Run(fmt.Sprintf("%+v", test), func(t *testing.T) {
-			mc := &multiThreadCopyState{
-				size: test.size,
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return &noModTimeWriterAt{mockfsWriterAt: mockfsWriterAt{f: f.(*mockfs.Fs), remote: remote}, err: test.err}, nil
 			}
-			mc.numChunks = calculateNumChunks(test.size, test.chunkSize)
-			assert.Equal(t, test.wantNumChunks, mc.numChunks)
-		})
-	}
-}
-
-// Skip if not multithread, returning the chunkSize otherwise
-func skipIfNotMultithread(ctx context.Context, t *testing.T, r *fstest.Run) int {
-	features := r.Fremote.Features()
-	if features.OpenChunkWriter == nil && features.OpenWriterAt == nil {
-		t.Skip("multithread writing not supported")
-	}
 
-	// Only support one hash for the local backend otherwise we end up spending a huge amount of CPU on hashing!
-	if r.Fremote.Features().IsLocal {
-		oldHashes := hash.SupportOnly([]hash.Type{r.Fremote.Hashes().GetOne()})
-		t.Cleanup(func() {
-			_ = hash.SupportOnly(oldHashes)
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "failed to set modification time: potato")
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, dst)
 		})
 	}
+}
 
-	ci := fs.GetConfig(ctx)
-	chunkSize := int(ci.MultiThreadChunkSize)
-	if features.OpenChunkWriter != nil {
-		//OpenChunkWriter func(ctx context.Context, remote string, src ObjectInfo, options ...OpenOption) (info ChunkWriterInfo, writer ChunkWriter, err error)
-		const fileName = "chunksize-probe"
-		src := object.NewStaticObjectInfo(fileName, time.Now(), int64(100*fs.Mebi), true, nil, nil)
-		info, writer, err := features.OpenChunkWriter(ctx, fileName, src)
-		require.NoError(t, err)
-		chunkSize = int(info.ChunkSize)
-		err = writer.Abort(ctx)
-		require.NoError(t, err)
-	}
-	return chunkSize
+// metadataObject is an object with metadata which records the
+// metadata set on it
+type metadataObject struct {
+	*mockobject.ContentMockObject
+	mu   sync.Mutex
+	meta fs.Metadata
 }
 
-func TestMultithreadCopy(t *testing.T) {
-	r := fstest.NewRun(t)
-	ctx := context.Background()
-	chunkSize := skipIfNotMultithread(ctx, t, r)
-	// Check every other transfer for metadata
-	checkMetadata := false
-	ctx, ci := fs.AddConfig(ctx)
+func (o *metadataObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.meta, nil
+}
 
-	for _, upload := range []bool{false, true} {
-		for _, test := range []struct {
-			size    int
-			streams int
-		}{
-			{size: chunkSize*2 - 1, streams: 2},
-			{size: chunkSize * 2, streams: 2},
-			{size: chunkSize*2 + 1, streams: 2},
-		} {
-			checkMetadata = !checkMetadata
-			ci.Metadata = checkMetadata
-			fileName := fmt.Sprintf("test-multithread-copy-%v-%d-%d", upload, test.size, test.streams)
-			t.Run(fmt.Sprintf("upload=%v,size=%v,streams=%v", upload, test.size, test.streams), func(t *testing.T) {
-				if *fstest.SizeLimit > 0 && int64(test.size) > *fstest.SizeLimit {
-					t.Skipf("exceeded file size limit %d > %d", test.size, *fstest.SizeLimit)
-				}
-				var (
-					contents     = random.String(test.size)
-					t1           = fstest.Time("2001-02-03T04:05:06.499999999Z")
-					file1        fstest.Item
-					src, dst     fs.Object
-					err          error
-					testMetadata = fs.Metadata{
-						// System metadata supported by all backends
-						"mtime": t1.Format(time.RFC3339Nano),
-						// User metadata
-						"potato": "jersey",
-					}
-				)
+func (o *metadataObject) SetMetadata(ctx context.Context, meta fs.Metadata) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.meta = meta
+	return nil
+}
 
-				var fSrc, fDst fs.Fs
-				if upload {
-					file1 = r.WriteFile(fileName, contents, t1)
-					r.CheckRemoteItems(t)
-					r.CheckLocalItems(t, file1)
-					fDst, fSrc = r.Fremote, r.Flocal
-				} else {
-					file1 = r.WriteObject(ctx, fileName, contents, t1)
-					r.CheckRemoteItems(t, file1)
-					r.CheckLocalItems(t)
-					fDst, fSrc = r.Flocal, r.Fremote
-				}
-				src, err = fSrc.NewObject(ctx, fileName)
-				require.NoError(t, err)
+// metadataChunkWriter is an orderChunkWriter which adds dst on Close
+type metadataChunkWriter struct {
+	orderChunkWriter
+	dst *metadataObject
+}
 
-				do, canSetMetadata := src.(fs.SetMetadataer)
-				if checkMetadata && canSetMetadata {
-					// Set metadata on the source if required
-					err := do.SetMetadata(ctx, testMetadata)
-					if err == fs.ErrorNotImplemented {
-						canSetMetadata = false
-					} else {
-						require.NoError(t, err)
-						fstest.CheckEntryMetadata(ctx, t, r.Flocal, src, testMetadata)
-					}
-				}
+func (w *metadataChunkWriter) Close(ctx context.Context) error {
+	w.f.AddObject(w.dst)
+	return nil
+}
 
-				accounting.GlobalStats().ResetCounters()
-				tr := accounting.GlobalStats().NewTransfer(src, nil)
+func TestMultithreadCopyMetadataAfterClose(t *testing.T) {
+	for _, metadataAfterClose := range []bool{false, true} {
+		t.Run(fmt.Sprint(metadataAfterClose), func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.Metadata = true
+			const remote = "file.txt"
+			t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+			contents := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, contents)
+			require.NoError(t, contents.SetModTime(ctx, t1))
+			src := &metadataObject{ContentMockObject: contents, meta: fs.Metadata{"potato": "jersey"}}
+			dst := &metadataObject{ContentMockObject: mockobject.New(remote).WithContent(make([]byte, 100), mockobject.SeekModeNone)}
+			w := &metadataChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, dst: dst}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:          50,
+				Concurrency:        4,
+				MetadataAfterClose: metadataAfterClose,
+			}, w)
 
-				defer func() {
-					tr.Done(ctx, err)
-				}()
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			newDst, err := multiThreadCopy(ctx, f, remote, src, 4, tr, fs.MetadataOption{"sausage": "cumberland"})
+			require.NoError(t, err)
+			require.NotNil(t, newDst)
+			if metadataAfterClose {
+				assert.Equal(t, fs.Metadata{
+					"potato":  "jersey",
+					"sausage": "cumberland",
+					"mtime":   t1.Format(time.RFC3339Nano),
+				}, dst.meta)
+			} else {
+				// The chunk writer sets the metadata itself
+				assert.Nil(t, dst.meta)
+			}
+		})
+	}
+}
 
-				dst, err = multiThreadCopy(ctx, fDst, fileName, src, test.streams, tr)
-				require.NoError(t, err)
+// cancelChunkWriter is a fs.ChunkWriter which cancels the transfer
+// when it gets to chunk cancelAt
+type cancelChunkWriter struct {
+	cancel   context.CancelFunc
+	cancelAt int
+}
 
-				assert.Equal(t, src.Size(), dst.Size())
-				assert.Equal(t, fileName, dst.Remote())
-				fstest.CheckListingWithPrecision(t, fSrc, []fstest.Item{file1}, nil, fs.GetModifyWindow(ctx, fDst, fSrc))
-				fstest.CheckListingWithPrecision(t, fDst, []fstest.Item{file1}, nil, fs.GetModifyWindow(ctx, fDst, fSrc))
+func (w *cancelChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	if chunkNumber == w.cancelAt {
+		w.cancel()
+		return -1, ctx.Err()
+	}
+	return io.Copy(io.Discard, reader)
+}
 
-				if checkMetadata && canSetMetadata && fDst.Features().ReadMetadata {
-					fstest.CheckEntryMetadata(ctx, t, fDst, dst, testMetadata)
-				}
+func (w *cancelChunkWriter) Close(ctx context.Context) error {
+	return nil
+}
 
-				require.NoError(t, dst.Remove(ctx))
-				require.NoError(t, src.Remove(ctx))
+func (w *cancelChunkWriter) Abort(ctx context.Context) error {
+	return nil
+}
 
-			})
-		}
+func TestMultithreadCopyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return fs.ChunkWriterInfo{
+			ChunkSize:   25,
+			Concurrency: 1,
+		}, &cancelChunkWriter{cancel: cancel, cancelAt: 2}, nil
 	}
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 1, tr)
+	require.Error(t, err)
+	assert.Nil(t, dst)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Contains(t, err.Error(), "cancelled after 2/4 chunks completed")
 }
 
 type errorObject struct {
@@ -333,3 +2961,382 @@ func TestMultithreadCopyAbort(t *testing.T) {
 		require.NoError(t, o.Remove(ctx))
 	}
 }
+
+func TestMultithreadCopyMaxChunks(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		minChunkSize  int64
+		wantChunks    int
+		wantChunkSize int64
+	}{
+		{name: "MaxChunks", wantChunks: 10000, wantChunkSize: 50},
+		{name: "MinChunkSize", minChunkSize: 100, wantChunks: 5000, wantChunkSize: 100},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			const remote = "file.bin"
+			srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+			require.NoError(t, err)
+			// 50,000 chunks worth of data
+			src := &patternObject{Object: mockobject.New(remote), f: srcFs, size: 500000}
+			w := &benchChunkWriter{remote: remote}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:    10,
+				Concurrency:  4,
+				MinChunkSize: test.minChunkSize,
+				MaxChunks:    10000,
+			}, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, test.wantChunks, result.Chunks)
+			assert.Equal(t, test.wantChunkSize, result.ChunkSize)
+			assert.Equal(t, src.size, result.Bytes)
+		})
+	}
+}
+
+func TestMultithreadCopyOnOpen(t *testing.T) {
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(context.Background(), t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(context.Background(), t, fs.ChunkWriterInfo{
+		ChunkSize:   25,
+		Concurrency: 8,
+	}, w)
+
+	var calls []fs.ChunkWriterInfo
+	ctx := WithMultiThreadOnOpen(context.Background(), func(info fs.ChunkWriterInfo) {
+		w.mu.Lock()
+		assert.Len(t, w.order, 0, "chunks written before OnOpen")
+		w.mu.Unlock()
+		calls = append(calls, info)
+	})
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	require.Len(t, calls, 1)
+	assert.Equal(t, int64(25), calls[0].ChunkSize)
+	// The concurrency is limited to the number of chunks
+	assert.Equal(t, 4, calls[0].Concurrency)
+}
+
+// egressAccount is an example AccountDecorator result which counts
+// the bytes copied for each region as well as accounting them
+// normally
+type egressAccount struct {
+	MultiThreadAccount
+	region string
+	egress map[string]*atomic.Int64
+}
+
+func (acc *egressAccount) AccountRead(n int) error {
+	acc.egress[acc.region].Add(int64(n))
+	return acc.MultiThreadAccount.AccountRead(n)
+}
+
+func TestMultithreadCopyAccountDecorator(t *testing.T) {
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(context.Background(), t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(context.Background(), t, testChunkWriterInfo, w)
+
+	egress := map[string]*atomic.Int64{"eu-west": {}}
+	var decorated []string
+	ctx := WithMultiThreadAccountDecorator(context.Background(), func(src fs.ObjectInfo, acc MultiThreadAccount) MultiThreadAccount {
+		decorated = append(decorated, src.Remote())
+		return &egressAccount{MultiThreadAccount: acc, region: "eu-west", egress: egress}
+	})
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Equal(t, []string{remote}, decorated)
+	assert.Equal(t, int64(100), egress["eu-west"].Load())
+	// The transfer is still accounted as normal
+	assert.Equal(t, int64(100), tr.Snapshot().Bytes)
+}
+
+func TestMultithreadCopyLabel(t *testing.T) {
+	ctx := WithMultiThreadLabel(context.Background(), "multithread-tenant")
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	stats := accounting.Stats(ctx)
+	before := stats.GetLabelBytes()["multithread-tenant"]
+	tr := stats.NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Equal(t, int64(100), stats.GetLabelBytes()["multithread-tenant"]-before)
+
+	// Only the chunk reads are labelled
+	_, ok := accounting.LabelFromContext(ctx)
+	assert.False(t, ok)
+}
+
+// removableObject is a patternObject which records being removed
+type removableObject struct {
+	*patternObject
+	removed *atomic.Bool
+}
+
+func (o *removableObject) Remove(ctx context.Context) error {
+	o.removed.Store(true)
+	return nil
+}
+
+// wrongSizeChunkWriter is an orderChunkWriter which finalizes the
+// object with delta bytes more than were written
+type wrongSizeChunkWriter struct {
+	orderChunkWriter
+	delta   int64
+	removed atomic.Bool
+}
+
+func (w *wrongSizeChunkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.f.AddObject(&removableObject{
+		patternObject: &patternObject{Object: mockobject.New(w.remote), f: w.f, size: w.written + w.delta},
+		removed:       &w.removed,
+	})
+	return nil
+}
+
+func TestMultithreadCopyFinalizedSize(t *testing.T) {
+	for _, delta := range []int64{0, -1, 1} {
+		t.Run(fmt.Sprint(delta), func(t *testing.T) {
+			ctx := context.Background()
+			const remote = "file.txt"
+			src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &wrongSizeChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, delta: delta}
+			f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if delta == 0 {
+				require.NoError(t, err)
+				require.NotNil(t, dst)
+				assert.False(t, w.removed.Load())
+				return
+			}
+			require.Error(t, err)
+			assert.Nil(t, dst)
+			assert.Contains(t, err.Error(), fmt.Sprintf("destination is %d bytes after finalizing but source is 100 bytes", 100+delta))
+			assert.True(t, w.removed.Load())
+		})
+	}
+}
+
+func TestMultithreadAlignChunkSize(t *testing.T) {
+	for _, test := range []struct {
+		chunkSize int64
+		align     int64
+		want      int64
+	}{
+		{chunkSize: 25, align: 0, want: 25},
+		{chunkSize: 25, align: -1, want: 25},
+		{chunkSize: 25, align: 16, want: 32},
+		{chunkSize: 32, align: 16, want: 32},
+		{chunkSize: 33, align: 16, want: 48},
+		{chunkSize: 1, align: 4 << 20, want: 4 << 20},
+	} {
+		assert.Equal(t, test.want, alignChunkSize(test.chunkSize, test.align), "chunkSize=%d align=%d", test.chunkSize, test.align)
+	}
+}
+
+// rangeObject is an object which records the ranges it is opened with
+type rangeObject struct {
+	*mockobject.ContentMockObject
+	mu     sync.Mutex
+	ranges []fs.RangeOption
+}
+
+func (o *rangeObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	for _, option := range options {
+		if x, ok := option.(*fs.RangeOption); ok {
+			o.mu.Lock()
+			o.ranges = append(o.ranges, *x)
+			o.mu.Unlock()
+		}
+	}
+	return o.ContentMockObject.Open(ctx, options...)
+}
+
+func TestMultithreadCopyRangeAlign(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadRangeAlign = 16
+	const remote = "file.txt"
+	contents := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, contents)
+	src := &rangeObject{ContentMockObject: contents}
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Equal(t, int64(32), result.ChunkSize)
+	assert.Equal(t, 4, result.Chunks)
+	sort.Slice(src.ranges, func(i, j int) bool { return src.ranges[i].Start < src.ranges[j].Start })
+	assert.Equal(t, []fs.RangeOption{
+		{Start: 0, End: 31},
+		{Start: 32, End: 63},
+		{Start: 64, End: 95},
+		{Start: 96, End: 99},
+	}, src.ranges)
+}
+
+// patternObject is a synthetic object of size bytes made by a
+// readers.PatternReader so it doesn't need any memory
+type patternObject struct {
+	mockobject.Object
+	f    fs.Info
+	size int64
+}
+
+func (o *patternObject) Fs() fs.Info { return o.f }
+
+func (o *patternObject) Size() int64 { return o.size }
+
+func (o *patternObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		}
+	}
+	r := readers.NewPatternReader(o.size)
+	_, err := r.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return io.NopCloser(r), nil
+	}
+	return io.NopCloser(io.LimitReader(r, limit)), nil
+}
+
+// benchChunkWriter is a fs.ChunkWriter which discards the chunks,
+// simulating a backend which takes latency to start each chunk then
+// writes it at bandwidth bytes/s.
+type benchChunkWriter struct {
+	f         *mockfs.Fs
+	remote    string
+	latency   time.Duration
+	bandwidth int64 // bytes/s for each stream, 0 for unlimited
+	written   atomic.Int64
+}
+
+func (w *benchChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	start := time.Now()
+	n, err := io.Copy(io.Discard, reader)
+	w.written.Add(n)
+	if err != nil {
+		return n, err
+	}
+	wait := w.latency
+	if w.bandwidth > 0 {
+		wait += time.Duration(n) * time.Second / time.Duration(w.bandwidth)
+	}
+	select {
+	case <-time.After(wait - time.Since(start)):
+	case <-ctx.Done():
+		return n, ctx.Err()
+	}
+	return n, nil
+}
+
+func (w *benchChunkWriter) Close(ctx context.Context) error {
+	w.f.AddObject(&patternObject{Object: mockobject.New(w.remote), f: w.f, size: w.written.Swap(0)})
+	return nil
+}
+
+func (w *benchChunkWriter) Abort(ctx context.Context) error {
+	return nil
+}
+
+func (w *benchChunkWriter) setFs(f *mockfs.Fs) {
+	w.f = f
+}
+
+// benchmarkMultithreadCopy copies a synthetic object of size bytes
+// b.N times with streams streams of chunkSize chunks to an in memory
+// backend which takes latency to start each chunk and writes each
+// stream at bandwidth bytes/s (0 for unlimited).
+func benchmarkMultithreadCopy(b *testing.B, size int64, streams int, chunkSize int64, latency time.Duration, bandwidth int64) {
+	ctx := context.Background()
+	const remote = "file.bin"
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(b, err)
+	src := &patternObject{Object: mockobject.New(remote), f: srcFs, size: size}
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(b, err)
+	w := &benchChunkWriter{f: f.(*mockfs.Fs), remote: remote, latency: latency, bandwidth: bandwidth}
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return fs.ChunkWriterInfo{
+			ChunkSize:   chunkSize,
+			Concurrency: streams,
+		}, w, nil
+	}
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		_, err := multiThreadCopy(ctx, f, remote, src, streams, tr)
+		tr.Done(ctx, err)
+		require.NoError(b, err)
+	}
+}
+
+// BenchmarkMultithreadCopy reports the throughput of multi-thread
+// copies of a 1 GiB object with different numbers of streams and
+// chunk sizes to a backend with and without per chunk latency and
+// limited bandwidth.
+//
+// Run it with go test -run XXX -bench MultithreadCopy and use
+// benchmarkMultithreadCopy to try other parameters.
+func BenchmarkMultithreadCopy(b *testing.B) {
+	const size = 1 << 30
+	for _, backend := range []struct {
+		name      string
+		latency   time.Duration
+		bandwidth int64
+	}{
+		{name: "Fast", latency: 0, bandwidth: 0},
+		{name: "Latency", latency: 50 * time.Millisecond, bandwidth: 0},
+		{name: "Bandwidth", latency: 0, bandwidth: 100 << 20},
+		{name: "Both", latency: 50 * time.Millisecond, bandwidth: 100 << 20},
+	} {
+		for _, streams := range []int{1, 4, 8} {
+			for _, chunkSize := range []int64{8 << 20, 32 << 20} {
+				name := fmt.Sprintf("%s/streams=%d/chunk=%v", backend.name, streams, fs.SizeSuffix(chunkSize))
+				b.Run(name, func(b *testing.B) {
+					benchmarkMultithreadCopy(b, size, streams, chunkSize, backend.latency, backend.bandwidth)
+				})
+			}
+		}
+	}
+}
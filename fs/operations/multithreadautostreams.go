@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// bdpStreams returns the number of streams needed to keep bandwidth
+// bytes/s flowing over a link with round trip time rtt when each
+// stream keeps window bytes in flight. This is the bandwidth-delay
+// product divided by the window, rounded up and limited to 1..max.
+func bdpStreams(bandwidth float64, rtt time.Duration, window int64, max int) int {
+	if bandwidth <= 0 || rtt <= 0 || window <= 0 {
+		return max
+	}
+	streams := math.Ceil(bandwidth * rtt.Seconds() / float64(window))
+	if streams > float64(max) {
+		return max
+	}
+	if streams < 1 {
+		return 1
+	}
+	return int(streams)
+}
+
+// bwLimitBandwidth returns the bandwidth in bytes/s set with --bwlimit
+// at now or 0 if it isn't set. If both the upload and download
+// limits are set the smaller is returned.
+func bwLimitBandwidth(ci *fs.ConfigInfo, now time.Time) float64 {
+	bandwidth := ci.BwLimit.LimitAt(now).Bandwidth
+	var limit fs.SizeSuffix
+	for _, l := range []fs.SizeSuffix{bandwidth.Tx, bandwidth.Rx} {
+		if l > 0 && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return float64(limit)
+}
+
+// autoStreams copies the first chunk on its own to measure the round
+// trip time from how long the source took to open and the bytes a
+// single stream keeps in flight from its speed, then returns the
+// number of streams up to max to use for the rest of the chunks with
+// --multi-thread-streams-auto.
+//
+// bandwidth is the target in bytes/s, 0 if unknown. window overrides
+// the measured bytes in flight per stream if set.
+func (mc *multiThreadCopyState) autoStreams(ctx context.Context, writer fs.ChunkWriter, max int, bandwidth float64, window int64) (int, error) {
+	start := mc.now()
+	err := mc.copyChunk(ctx, 0, writer)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := mc.since(start)
+	rtt := time.Duration(mc.openLatency.Load())
+	if rtt <= 0 || elapsed <= rtt {
+		fs.Debugf(mc.src, "multi-thread copy: couldn't measure round trip time so using %v streams", fs.LogValue("streams", max))
+		return max, nil
+	}
+	_, end := chunkRange(0, mc.size, mc.firstPartSize, mc.partSize)
+	speed := float64(end) / (elapsed - rtt).Seconds()
+	if window <= 0 {
+		window = int64(speed * rtt.Seconds())
+	}
+	if bandwidth <= 0 {
+		fs.Debugf(mc.src, "multi-thread copy: no --bwlimit to aim for so using %v streams", fs.LogValue("streams", max))
+		return max, nil
+	}
+	streams := bdpStreams(bandwidth, rtt, window, max)
+	fs.Debugf(mc.src, "multi-thread copy: round trip time %v, window %v, target %v/s so using %v streams", rtt.Round(time.Millisecond), fs.SizeSuffix(window), fs.SizeSuffix(bandwidth), fs.LogValue("streams", streams))
+	return streams, nil
+}
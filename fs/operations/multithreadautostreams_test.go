@@ -0,0 +1,109 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBdpStreams(t *testing.T) {
+	for _, test := range []struct {
+		bandwidth fs.SizeSuffix
+		rtt       time.Duration
+		window    fs.SizeSuffix
+		max       int
+		want      int
+	}{
+		// 100 MiB/s * 0.1s = 10 MiB in flight / 1 MiB windows
+		{bandwidth: 100 * fs.Mebi, rtt: 100 * time.Millisecond, window: fs.Mebi, max: 64, want: 10},
+		{bandwidth: 100 * fs.Mebi, rtt: 100 * time.Millisecond, window: fs.Mebi, max: 4, want: 4},
+		{bandwidth: 100 * fs.Mebi, rtt: 105 * time.Millisecond, window: fs.Mebi, max: 64, want: 11},
+		{bandwidth: fs.Mebi, rtt: 10 * time.Millisecond, window: fs.Mebi, max: 64, want: 1},
+		{bandwidth: 0, rtt: 100 * time.Millisecond, window: fs.Mebi, max: 8, want: 8},
+		{bandwidth: fs.Mebi, rtt: 0, window: fs.Mebi, max: 8, want: 8},
+		{bandwidth: fs.Mebi, rtt: time.Second, window: 0, max: 8, want: 8},
+	} {
+		got := bdpStreams(float64(test.bandwidth), test.rtt, int64(test.window), test.max)
+		assert.Equal(t, test.want, got, "%+v", test)
+	}
+}
+
+func TestBwLimitBandwidth(t *testing.T) {
+	_, ci := fs.AddConfig(context.Background())
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, float64(0), bwLimitBandwidth(ci, now))
+	ci.BwLimit = fs.BwTimetable{{Bandwidth: fs.BwPair{Tx: 2 * fs.Mebi, Rx: fs.Mebi}}}
+	assert.Equal(t, float64(fs.Mebi), bwLimitBandwidth(ci, now))
+	ci.BwLimit = fs.BwTimetable{{Bandwidth: fs.BwPair{Tx: 2 * fs.Mebi, Rx: -1}}}
+	assert.Equal(t, float64(2*fs.Mebi), bwLimitBandwidth(ci, now))
+
+	// The limit in the timetable at now is used
+	ci.BwLimit = fs.BwTimetable{
+		{DayOfTheWeek: 3, HHMM: 800, Bandwidth: fs.BwPair{Tx: fs.Mebi, Rx: fs.Mebi}},
+		{DayOfTheWeek: 3, HHMM: 1800, Bandwidth: fs.BwPair{Tx: 2 * fs.Mebi, Rx: 2 * fs.Mebi}},
+	}
+	assert.Equal(t, float64(fs.Mebi), bwLimitBandwidth(ci, now))
+	assert.Equal(t, float64(2*fs.Mebi), bwLimitBandwidth(ci, now.Add(7*time.Hour)))
+}
+
+// slowOpenObject takes delay to open
+type slowOpenObject struct {
+	*mockobject.ContentMockObject
+	delay time.Duration
+}
+
+func (o *slowOpenObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	time.Sleep(o.delay)
+	return o.ContentMockObject.Open(ctx, options...)
+}
+
+func TestMultithreadCopyStreamsAuto(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadStreamsAuto = true
+	ci.MultiThreadStreamWindow = 10
+	const remote = "file.txt"
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		bwLimit  fs.SizeSuffix
+		wantFrom int
+	}{
+		// 100 bytes/s * 50ms / 10 bytes rounds up to 1 stream
+		{name: "OneStream", bwLimit: 100, wantFrom: 1},
+		// 1000 bytes/s * 50ms / 10 bytes is more than the 4 allowed
+		{name: "Max", bwLimit: 1000, wantFrom: 4},
+		{name: "NoLimit", wantFrom: 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ci.BwLimit = nil
+			if test.bwLimit > 0 {
+				ci.BwLimit = fs.BwTimetable{{Bandwidth: fs.BwPair{Tx: test.bwLimit, Rx: test.bwLimit}}}
+			}
+			content := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			content.SetFs(srcFs)
+			src := &slowOpenObject{ContentMockObject: content, delay: 50 * time.Millisecond}
+			w := &orderChunkWriter{remote: remote, last: -1}
+			f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			assert.Equal(t, int64(100), dst.Size())
+			assert.Equal(t, test.wantFrom, result.Concurrency)
+			require.Len(t, w.order, 4)
+			assert.Equal(t, 0, w.order[0])
+		})
+	}
+}
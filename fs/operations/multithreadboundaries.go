@@ -0,0 +1,76 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+// boundaryCheckBytes is the number of bytes read from the start of
+// each chunk by --multi-thread-check-boundaries
+const boundaryCheckBytes = 16
+
+// readRangeBytes reads n bytes at offset from o
+func readRangeBytes(ctx context.Context, o fs.Object, offset, n int64, options ...fs.OpenOption) (buf []byte, err error) {
+	options = append(options[:len(options):len(options)], &fs.RangeOption{Start: offset, End: offset + n - 1})
+	in, err := Open(ctx, o, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	buf = make([]byte, n)
+	_, err = io.ReadFull(in, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// checkBoundaries reads the first few bytes of each chunk back from
+// dst and checks they match the source, to catch backends which put
+// chunks in the order they finish rather than at the offset of their
+// chunk number.
+//
+// This costs two small ranged reads per chunk.
+func (mc *multiThreadCopyState) checkBoundaries(ctx context.Context, dst fs.Object, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for chunk := 0; chunk < mc.numChunks; chunk++ {
+		chunk := chunk
+		start, end := chunkRange(chunk, mc.size, mc.firstPartSize, mc.partSize)
+		n := end - start
+		if n > boundaryCheckBytes {
+			n = boundaryCheckBytes
+		}
+		if n <= 0 {
+			continue
+		}
+		g.Go(func() error {
+			want, err := readRangeBytes(gCtx, mc.src, start, n, mc.openOptions...)
+			if err != nil {
+				return fmt.Errorf("multi-thread copy: failed to read source to check chunk %d/%d: %w", chunk+1, mc.numChunks, err)
+			}
+			got, err := readRangeBytes(gCtx, dst, start, n)
+			if err != nil {
+				return fmt.Errorf("multi-thread copy: failed to read destination to check chunk %d/%d: %w", chunk+1, mc.numChunks, err)
+			}
+			if !bytes.Equal(want, got) {
+				return fmt.Errorf("multi-thread copy: chunk %d/%d isn't at offset %d on the destination - the backend may be ignoring the chunk number", chunk+1, mc.numChunks, start)
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return err
+	}
+	fs.Debugf(mc.src, "multi-thread copy: checked the boundaries of %d chunks", mc.numChunks)
+	return nil
+}
@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placeChunkWriter writes chunks of chunkSize into memory, either at
+// the offset of their chunk number or, if appendOnly is set, in the
+// order they finish like a buggy backend
+type placeChunkWriter struct {
+	f          *mockfs.Fs
+	remote     string
+	chunkSize  int64
+	appendOnly bool
+	mu         sync.Mutex
+	buf        []byte
+}
+
+func (w *placeChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	// Make the first chunk finish last
+	if chunkNumber == 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.appendOnly {
+		w.buf = append(w.buf, data...)
+		return int64(len(data)), nil
+	}
+	off := int64(chunkNumber) * w.chunkSize
+	if end := off + int64(len(data)); end > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, end-int64(len(w.buf)))...)
+	}
+	return int64(len(append(w.buf[off:off], data...))), nil
+}
+
+func (w *placeChunkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.f.AddObject(mockobject.New(w.remote).WithContent(w.buf, mockobject.SeekModeNone))
+	return nil
+}
+
+func (w *placeChunkWriter) Abort(ctx context.Context) error {
+	return nil
+}
+
+func (w *placeChunkWriter) setFs(f *mockfs.Fs) {
+	w.f = f
+}
+
+func TestMultithreadCopyCheckBoundaries(t *testing.T) {
+	const remote = "file.txt"
+	content := []byte(random.String(100))
+	for _, test := range []struct {
+		name       string
+		appendOnly bool
+		check      bool
+		wantErr    string
+	}{
+		{name: "Placed", check: true},
+		{name: "AppendedNotChecked", appendOnly: true},
+		{name: "Appended", appendOnly: true, check: true, wantErr: "the backend may be ignoring the chunk number"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, ci := fs.AddConfig(context.Background())
+			ci.MultiThreadCheckBoundaries = test.check
+			src := mockobject.New(remote).WithContent(content, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			w := &placeChunkWriter{remote: remote, chunkSize: 25, appendOnly: test.appendOnly}
+			f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				assert.Nil(t, dst)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, !test.appendOnly, bytes.Equal(content, w.buf))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// multiThreadBreaker stops multi-thread copies to destinations which
+// keep failing them for --multi-thread-breaker-failures
+var multiThreadBreaker = newBreaker()
+
+// breaker counts the multi-thread copies to each destination which
+// failed in a row and trips when there are too many so the
+// destination is copied to with a single stream from then on.
+//
+// If a reset time is set, the next copy to a tripped destination after
+// that long is allowed to use multi-thread again. If it fails the
+// breaker trips again straight away, otherwise it is closed.
+type breaker struct {
+	mu       sync.Mutex
+	clock    clock                // source of time, the real time if nil
+	failures map[string]int       // failures in a row for each destination
+	tripped  map[string]time.Time // when each tripped destination was tripped
+}
+
+// newBreaker makes a new breaker with all the destinations closed
+func newBreaker() *breaker {
+	return &breaker{
+		failures: map[string]int{},
+		tripped:  map[string]time.Time{},
+	}
+}
+
+// now returns the current time from the clock of the breaker
+func (b *breaker) now() time.Time {
+	if b.clock == nil {
+		return time.Now()
+	}
+	return b.clock.Now()
+}
+
+// allow returns false if multi-thread copies to f have been stopped by
+// the breaker.
+func (b *breaker) allow(ctx context.Context, f fs.Info) bool {
+	ci := fs.GetConfig(ctx)
+	if ci.MultiThreadBreakerFailures <= 0 {
+		return true
+	}
+	key := fs.ConfigString(f)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	trippedAt, found := b.tripped[key]
+	if !found {
+		return true
+	}
+	if ci.MultiThreadBreakerReset <= 0 || b.now().Sub(trippedAt) < ci.MultiThreadBreakerReset {
+		return false
+	}
+	// Let one copy try again - one more failure trips it again
+	fs.Infof(f, "multi-thread copy: trying multi-thread copies again after --multi-thread-breaker-reset %v", ci.MultiThreadBreakerReset)
+	delete(b.tripped, key)
+	b.failures[key] = ci.MultiThreadBreakerFailures - 1
+	return true
+}
+
+// record records the result of a multi-thread copy to f, tripping the
+// breaker if it has failed too many times in a row.
+//
+// Errors from cancelling the copy, fatal errors and copies which
+// couldn't use multi-thread aren't counted as failures.
+func (b *breaker) record(ctx context.Context, f fs.Info, err error) {
+	ci := fs.GetConfig(ctx)
+	if ci.MultiThreadBreakerFailures <= 0 {
+		return
+	}
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, fs.ErrorCantMultiThread) || fserrors.IsFatalError(err)) {
+		return
+	}
+	key := fs.ConfigString(f)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.failures, key)
+		return
+	}
+	b.failures[key]++
+	if b.failures[key] < ci.MultiThreadBreakerFailures {
+		return
+	}
+	delete(b.failures, key)
+	b.tripped[key] = b.now()
+	if ci.MultiThreadBreakerReset > 0 {
+		fs.Logf(f, "multi-thread copy: using single stream copies for %v after %d multi-thread copies failed in a row (--multi-thread-breaker-failures)", ci.MultiThreadBreakerReset, ci.MultiThreadBreakerFailures)
+	} else {
+		fs.Logf(f, "multi-thread copy: using single stream copies for the rest of the run after %d multi-thread copies failed in a row (--multi-thread-breaker-failures)", ci.MultiThreadBreakerFailures)
+	}
+}
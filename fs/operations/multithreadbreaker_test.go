@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadBreaker(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	other, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	errFailed := errors.New("failed")
+	clock := newFakeClock()
+	b := newBreaker()
+	b.clock = clock
+
+	// Off by default
+	for i := 0; i < 5; i++ {
+		b.record(ctx, f, errFailed)
+	}
+	assert.True(t, b.allow(ctx, f))
+
+	ci.MultiThreadBreakerFailures = 3
+
+	// A success resets the count
+	b.record(ctx, f, errFailed)
+	b.record(ctx, f, errFailed)
+	b.record(ctx, f, nil)
+	b.record(ctx, f, errFailed)
+	b.record(ctx, f, errFailed)
+	assert.True(t, b.allow(ctx, f))
+
+	// Cancelled copies and fatal errors don't count
+	b.record(ctx, f, context.Canceled)
+	b.record(ctx, f, fserrors.FatalError(errFailed))
+	b.record(ctx, f, fs.ErrorCantMultiThread)
+	assert.True(t, b.allow(ctx, f))
+
+	// The third failure in a row trips it for that destination only
+	b.record(ctx, f, errFailed)
+	assert.False(t, b.allow(ctx, f))
+	assert.True(t, b.allow(ctx, other))
+
+	// Without a reset it stays tripped
+	clock.advance(time.Hour)
+	assert.False(t, b.allow(ctx, f))
+
+	// With a reset it lets a copy try again after that long
+	ci.MultiThreadBreakerReset = 2 * time.Hour
+	assert.False(t, b.allow(ctx, f))
+	clock.advance(time.Hour)
+	assert.True(t, b.allow(ctx, f))
+
+	// and one more failure trips it again
+	b.record(ctx, f, errFailed)
+	assert.False(t, b.allow(ctx, f))
+
+	// while a success closes it
+	clock.advance(2 * time.Hour)
+	assert.True(t, b.allow(ctx, f))
+	b.record(ctx, f, nil)
+	b.record(ctx, f, errFailed)
+	assert.True(t, b.allow(ctx, f))
+}
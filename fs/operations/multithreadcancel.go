@@ -0,0 +1,157 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// errChunkCancelled is the cause of the context of a chunk cancelled
+// with job/cancelchunk
+var errChunkCancelled = errors.New("multi-thread copy: chunk cancelled with job/cancelchunk")
+
+type chunkWriteStartKeyType struct{}
+
+// Context key for the function stopping a chunk being cancellable
+var chunkWriteStartKey = chunkWriteStartKeyType{}
+
+// copyChunkCancellable copies chunk with copyChunk allowing it to be
+// cancelled with job/cancelchunk until it starts being written.
+//
+// A cancelled chunk is queued with requeue to be copied again and nil
+// is returned.
+func (mc *multiThreadCopyState) copyChunkCancellable(ctx context.Context, chunk int, writer fs.ChunkWriter) error {
+	if mc.job == nil {
+		return mc.copyChunk(ctx, chunk, writer)
+	}
+	chunkCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	remove := mc.job.AddChunkCanceller(mc.src.Remote(), chunk, func() {
+		cancel(errChunkCancelled)
+	})
+	defer remove()
+	chunkCtx = context.WithValue(chunkCtx, chunkWriteStartKey, remove)
+	err := mc.copyChunk(chunkCtx, chunk, writer)
+	if err != nil && chunkCancelled(chunkCtx) {
+		fs.Infof(mc.src, "multi-thread copy: chunk %v/%v cancelled with job/cancelchunk - will copy it again", fs.LogValue("chunk", chunk+1), fs.LogValue("total", mc.numChunks))
+		mc.requeue(chunk)
+		return nil
+	}
+	return err
+}
+
+// chunkCancelled returns true if the chunk being copied with ctx was
+// cancelled with job/cancelchunk
+func chunkCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errChunkCancelled)
+}
+
+// startChunkWrite must be called before a chunk starts being written.
+// After it returns the chunk can no longer be cancelled with
+// job/cancelchunk, so if it returns nil then the chunk won't be
+// cancelled.
+func startChunkWrite(ctx context.Context) error {
+	remove, ok := ctx.Value(chunkWriteStartKey).(func())
+	if !ok {
+		return nil
+	}
+	remove()
+	if chunkCancelled(ctx) {
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
+// requeue queues chunk to be copied again after being cancelled
+// before anything was written.
+func (mc *multiThreadCopyState) requeue(chunk int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.dispatched, chunk)
+	mc.requeued = append(mc.requeued, chunk)
+}
+
+// takeRequeued returns the chunks queued with requeue, emptying the
+// queue.
+func (mc *multiThreadCopyState) takeRequeued() (chunks []int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	chunks, mc.requeued = mc.requeued, nil
+	return chunks
+}
+
+// pendingChunks returns the chunk numbers which aren't in
+// completedChunks in the order set by --multi-thread-sort.
+//
+// finalChunk, if set, is always returned last.
+func (mc *multiThreadCopyState) pendingChunks(completedChunks map[int]bool, finalChunk int) (chunks []int) {
+	for chunk := 0; chunk < mc.numChunks; chunk++ {
+		if !completedChunks[chunk] {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sortChunks(chunks, mc.sortOrder, mc.numChunks, finalChunk)
+	return chunks
+}
+
+// sortChunks sorts the ascending chunk numbers in chunks into order
+// for a file of numChunks chunks, leaving finalChunk (if >= 0) last.
+func sortChunks(chunks []int, order fs.MultiThreadSort, numChunks int, finalChunk int) {
+	var less func(a, b int) bool
+	switch order {
+	case fs.MultiThreadSortDescending:
+		less = func(a, b int) bool { return a > b }
+	case fs.MultiThreadSortCenterOut:
+		// Distance from the middle counted in half chunks so
+		// an even number of chunks has two middle chunks
+		middle := numChunks - 1
+		distance := func(chunk int) int {
+			d := 2*chunk - middle
+			if d < 0 {
+				d = -d
+			}
+			return d
+		}
+		less = func(a, b int) bool {
+			da, db := distance(a), distance(b)
+			if da != db {
+				return da < db
+			}
+			return a < b
+		}
+	default:
+		return
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		a, b := chunks[i], chunks[j]
+		if a == finalChunk || b == finalChunk {
+			return b == finalChunk && a != finalChunk
+		}
+		return less(a, b)
+	})
+}
+
+// holdFinalChunk is called before the final chunk at queue[i] is
+// dispatched once the preceding chunks have been written. If chunks
+// have been cancelled meanwhile it returns a new queue with them
+// ahead of the final chunk and true.
+func (mc *multiThreadCopyState) holdFinalChunk(queue []int, i int) ([]int, bool) {
+	requeued := mc.takeRequeued()
+	if len(requeued) == 0 {
+		return queue, false
+	}
+	fs.Debugf(mc.src, "multi-thread copy: copying %d cancelled chunks before the final chunk", len(requeued))
+	return append(requeued, queue[i:]...), true
+}
+
+// errChunksNotCopied is returned if the copy stopped with chunks
+// still to be copied
+func (mc *multiThreadCopyState) errChunksNotCopied(ctx context.Context, queue []int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("multi-thread copy: %d chunks not copied", len(queue))
+}
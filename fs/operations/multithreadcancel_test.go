@@ -0,0 +1,146 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingOpenObject blocks the first open of the range starting at
+// blockStart until its context is cancelled
+type blockingOpenObject struct {
+	*mockobject.ContentMockObject
+	blockStart int64
+	opened     chan struct{}
+	blocked    atomic.Bool
+}
+
+func (o *blockingOpenObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	for _, option := range options {
+		if r, ok := option.(*fs.RangeOption); ok && r.Start == o.blockStart && !o.blocked.Swap(true) {
+			close(o.opened)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	return o.ContentMockObject.Open(ctx, options...)
+}
+
+func TestMultithreadCopyCancelChunk(t *testing.T) {
+	ctx := context.Background()
+	const remote = "file.txt"
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name           string
+		concurrency    int
+		finalChunkLast bool
+		want           []int
+	}{
+		{name: "Serial", concurrency: 1, want: []int{0, 2, 3, 1}},
+		{name: "Serial/FinalChunkLast", concurrency: 1, finalChunkLast: true, want: []int{0, 2, 1, 3}},
+		{name: "Parallel", concurrency: 4},
+		{name: "Parallel/FinalChunkLast", concurrency: 4, finalChunkLast: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			content := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			content.SetFs(srcFs)
+			src := &blockingOpenObject{ContentMockObject: content, blockStart: 25, opened: make(chan struct{})}
+			w := &orderChunkWriter{remote: remote, last: -1}
+			f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+				ChunkSize:      25,
+				Concurrency:    test.concurrency,
+				FinalChunkLast: test.finalChunkLast,
+			}, w)
+
+			_, _, err = jobs.NewJob(ctx, func(ctx context.Context, in rc.Params) (rc.Params, error) {
+				job, ok := jobs.GetJob(ctx)
+				require.True(t, ok)
+
+				// Chunks which aren't in progress can't be cancelled
+				assert.Error(t, job.CancelChunk(remote, 1))
+
+				cancelled := make(chan error, 1)
+				go func() {
+					<-src.opened
+					cancelled <- job.CancelChunk(remote, 1)
+				}()
+				tr := accounting.GlobalStats().NewTransfer(src, nil)
+				defer tr.Done(ctx, nil)
+				dst, err := multiThreadCopy(ctx, f, remote, src, test.concurrency, tr)
+				require.NoError(t, err)
+				require.NoError(t, <-cancelled)
+				assert.Equal(t, int64(100), dst.Size())
+				return nil, nil
+			}, rc.Params{})
+			require.NoError(t, err)
+
+			require.Len(t, w.order, 4)
+			if test.want != nil {
+				assert.Equal(t, test.want, w.order)
+			} else if test.finalChunkLast {
+				assert.Equal(t, []int{1, 3}, w.order[2:])
+			} else {
+				assert.Equal(t, 1, w.order[3])
+			}
+		})
+	}
+}
+
+func TestMultithreadSortChunks(t *testing.T) {
+	for _, test := range []struct {
+		order      fs.MultiThreadSort
+		chunks     []int
+		numChunks  int
+		finalChunk int
+		want       []int
+	}{
+		{fs.MultiThreadSortAscending, []int{0, 1, 2, 3}, 4, -1, []int{0, 1, 2, 3}},
+		{fs.MultiThreadSortDescending, []int{0, 1, 2, 3}, 4, -1, []int{3, 2, 1, 0}},
+		{fs.MultiThreadSortDescending, []int{0, 1, 2, 3}, 4, 3, []int{2, 1, 0, 3}},
+		{fs.MultiThreadSortDescending, []int{0, 2}, 4, 3, []int{2, 0}},
+		{fs.MultiThreadSortCenterOut, []int{0, 1, 2, 3, 4}, 5, -1, []int{2, 1, 3, 0, 4}},
+		{fs.MultiThreadSortCenterOut, []int{0, 1, 2, 3}, 4, -1, []int{1, 2, 0, 3}},
+		{fs.MultiThreadSortCenterOut, []int{0, 1, 2, 3, 4}, 5, 4, []int{2, 1, 3, 0, 4}},
+		{fs.MultiThreadSortCenterOut, []int{0, 1, 3, 4, 5}, 6, 5, []int{3, 1, 4, 0, 5}},
+		{fs.MultiThreadSortCenterOut, []int{0}, 1, -1, []int{0}},
+		{fs.MultiThreadSortDescending, nil, 0, -1, nil},
+	} {
+		chunks := append([]int(nil), test.chunks...)
+		sortChunks(chunks, test.order, test.numChunks, test.finalChunk)
+		assert.Equal(t, test.want, chunks, "%v %v final=%d", test.order, test.chunks, test.finalChunk)
+	}
+}
+
+func TestMultithreadCopySort(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadSort = fs.MultiThreadSortDescending
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+		ChunkSize:   25,
+		Concurrency: 1,
+	}, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, err := multiThreadCopy(ctx, f, remote, src, 1, tr)
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	assert.Equal(t, []int{3, 2, 1, 0}, w.order)
+}
@@ -0,0 +1,145 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/readers"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// average size of the chunks made by --multi-thread-cdc
+	cdcAverageSize = 1 << 20
+)
+
+// gearTable is the table of random numbers used by the gear rolling
+// hash. It is made from a fixed seed so chunk boundaries are the same
+// on every run.
+var gearTable = func() (table [256]uint64) {
+	// splitmix64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// cdcChunker finds content defined chunk boundaries with a gear
+// rolling hash.
+//
+// As the boundaries depend only on the data near them, inserting or
+// removing data only moves the boundaries near the change.
+type cdcChunker struct {
+	min  int    // smallest chunk
+	max  int    // largest chunk
+	mask uint64 // a boundary is where the hash has these bits clear
+}
+
+// newCDCChunker makes a cdcChunker which makes chunks of average
+// size on random data, between average/4 and average*4 long.
+func newCDCChunker(average int) *cdcChunker {
+	mask := uint64(1)
+	for mask < uint64(average) {
+		mask <<= 1
+	}
+	return &cdcChunker{
+		min:  average / 4,
+		max:  average * 4,
+		mask: mask - 1,
+	}
+}
+
+// cut returns the length of the next chunk at the start of buf.
+//
+// buf should be at least c.max long unless it holds the end of the
+// data.
+func (c *cdcChunker) cut(buf []byte) int {
+	n := len(buf)
+	if n > c.max {
+		n = c.max
+	}
+	if n <= c.min {
+		return n
+	}
+	var h uint64
+	for i := 0; i < n; i++ {
+		h = h<<1 + gearTable[buf[i]]
+		if i >= c.min && h&c.mask == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// copyCDC copies the source to w in variable sized chunks with
+// boundaries found by chunker.
+//
+// The source is read in order with a single stream and each chunk is
+// written at its offset by the goroutines of g. The number of chunks
+// waiting to be written is limited by the limit set on g.
+func (mc *multiThreadCopyState) copyCDC(ctx context.Context, g *errgroup.Group, w *writerAtChunkWriter, chunker *cdcChunker) (err error) {
+	rc, err := Open(ctx, mc.src, mc.openOptions...)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to open source: %w", err)
+	}
+	defer fs.CheckClose(rc, &err)
+	rc.SetAccounting(mc.acc.AccountRead)
+	in := readers.NewContextReader(ctx, rc)
+
+	buf := make([]byte, 0, chunker.max)
+	var offset int64
+	eof := false
+	for chunk := 0; ; chunk++ {
+		// Fill the buffer so there is a whole chunk to look at
+		if !eof {
+			var n int
+			n, err = io.ReadFull(in, buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				eof = true
+			} else if err != nil {
+				return fmt.Errorf("multi-thread copy: failed to read source: %w", err)
+			}
+		}
+		if len(buf) == 0 {
+			break
+		}
+		size := chunker.cut(buf)
+		data := append([]byte(nil), buf[:size]...)
+		buf = append(buf[:0], buf[size:]...)
+		start := offset
+		offset += int64(size)
+		if offset > mc.size {
+			return fmt.Errorf("multi-thread copy: source is longer than the expected %d bytes", mc.size)
+		}
+		// Wait for a goroutine under --multi-thread-max-goroutines
+		releaseGoroutine, err := multiThreadGoroutines.acquire(ctx, fs.GetConfig(ctx).MultiThreadMaxGoroutines)
+		if err != nil {
+			return err
+		}
+		chunk := chunk
+		g.Go(func() error {
+			defer releaseGoroutine()
+			fs.Debugf(mc.src, "multi-thread copy: chunk %v (%v-%v) size %v starting", fs.LogValue("chunk", chunk+1), fs.LogValue("start", start), fs.LogValue("end", start+int64(size)), fs.LogValue("size", fs.SizeSuffix(size)))
+			n, err := w.writerAt.WriteAt(data, start)
+			if err != nil {
+				return writeAtError(chunk, 0, start+int64(n), err)
+			}
+			mc.written.Add(int64(n))
+			mc.completed.Add(1)
+			return nil
+		})
+	}
+	if offset != mc.size {
+		return fmt.Errorf("multi-thread copy: source is %d bytes which is shorter than the expected %d bytes", offset, mc.size)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package operations
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// chunkSizes splits data with c returning the size of each chunk
+func chunkSizes(c *cdcChunker, data []byte) (sizes []int) {
+	for len(data) > 0 {
+		size := c.cut(data)
+		sizes = append(sizes, size)
+		data = data[size:]
+	}
+	return sizes
+}
+
+func TestMultithreadCDCChunker(t *testing.T) {
+	c := newCDCChunker(1024)
+	assert.Equal(t, 256, c.min)
+	assert.Equal(t, 4096, c.max)
+	assert.Equal(t, uint64(1023), c.mask)
+
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	sizes := chunkSizes(c, data)
+	total := 0
+	for i, size := range sizes {
+		total += size
+		assert.LessOrEqual(t, size, c.max)
+		if i < len(sizes)-1 {
+			assert.Greater(t, size, c.min)
+		}
+	}
+	assert.Equal(t, len(data), total)
+	// The average should be about the size asked for
+	average := len(data) / len(sizes)
+	assert.Greater(t, average, 512)
+	assert.Less(t, average, 2048)
+
+	// Chunking is repeatable
+	assert.Equal(t, sizes, chunkSizes(c, data))
+
+	// Inserting data at the start only changes the first few chunks
+	shifted := append([]byte("potato"), data...)
+	shiftedSizes := chunkSizes(c, shifted)
+	assert.Equal(t, sizes[len(sizes)-10:], shiftedSizes[len(shiftedSizes)-10:])
+}
+
+func TestMultithreadCopyCDC(t *testing.T) {
+	ctx := context.Background()
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(2)).Read(data)
+	src := mockobject.New("file.txt").WithContent(data, mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+
+	for _, test := range []struct {
+		name    string
+		size    int64
+		wantErr string
+	}{
+		{name: "OK", size: int64(len(data))},
+		{name: "Shorter", size: int64(len(data)) + 1, wantErr: "shorter than the expected"},
+		{name: "Longer", size: int64(len(data)) - 1, wantErr: "longer than the expected"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			writerAt := &memWriterAt{}
+			w := &writerAtChunkWriter{
+				remote:   "file.txt",
+				size:     test.size,
+				writerAt: writerAt,
+			}
+			mc := &multiThreadCopyState{
+				size: test.size,
+				src:  src,
+				acc:  tr.Account(ctx, nil),
+			}
+			g, gCtx := errgroup.WithContext(ctx)
+			g.SetLimit(4)
+			err := mc.copyCDC(gCtx, g, w, newCDCChunker(1024))
+			require.NoError(t, g.Wait())
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, data, writerAt.buf)
+			assert.Equal(t, int64(len(data)), mc.written.Load())
+			assert.Greater(t, mc.completed.Load(), int64(10))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package operations
+
+import (
+	"context"
+	"time"
+)
+
+// clock is the source of time for the timing based parts of a
+// multi-thread copy, such as --multi-thread-adaptive-chunk,
+// --multi-thread-streams-auto, --multi-thread-min-speed,
+// --multi-thread-dispatch-jitter and the chunk ETA, so tests can stub it to make them deterministic.
+type clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a ticker which ticks every d
+	NewTicker(d time.Duration) ticker
+	// NewTimer returns a timer which fires once after d
+	NewTimer(d time.Duration) timer
+}
+
+// ticker is the part of a time.Ticker a clock returns
+type ticker interface {
+	// C returns the channel the ticks are delivered on
+	C() <-chan time.Time
+	// Stop stops the ticker
+	Stop()
+}
+
+// timer is the part of a time.Timer a clock returns
+type timer interface {
+	// C returns the channel the time is delivered on
+	C() <-chan time.Time
+	// Stop stops the timer
+	Stop() bool
+}
+
+// realClock is a clock which uses the real time
+type realClock struct{}
+
+// Now returns the current time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker returns a time.Ticker which ticks every d
+func (realClock) NewTicker(d time.Duration) ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// NewTimer returns a time.Timer which fires once after d
+func (realClock) NewTimer(d time.Duration) timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// realTicker is a ticker using a time.Ticker
+type realTicker struct {
+	*time.Ticker
+}
+
+// C returns the channel the ticks are delivered on
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// realTimer is a timer using a time.Timer
+type realTimer struct {
+	*time.Timer
+}
+
+// C returns the channel the time is delivered on
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// Check interfaces
+var (
+	_ clock  = realClock{}
+	_ ticker = realTicker{}
+	_ timer  = realTimer{}
+)
+
+type multiThreadClockKeyType struct{}
+
+// Context key for the clock of the multi-thread copies
+var multiThreadClockKey = multiThreadClockKeyType{}
+
+// withMultiThreadClock returns a context which makes the multi-thread
+// copies made with it use c as their source of time.
+func withMultiThreadClock(ctx context.Context, c clock) context.Context {
+	return context.WithValue(ctx, multiThreadClockKey, c)
+}
+
+// getMultiThreadClock returns the clock set with withMultiThreadClock
+// or the real time if it isn't set
+func getMultiThreadClock(ctx context.Context) clock {
+	c, ok := ctx.Value(multiThreadClockKey).(clock)
+	if !ok || c == nil {
+		return realClock{}
+	}
+	return c
+}
+
+// now returns the current time from the clock of the copy
+func (mc *multiThreadCopyState) now() time.Time {
+	return mc.getClock().Now()
+}
+
+// since returns the time elapsed since t by the clock of the copy
+func (mc *multiThreadCopyState) since(t time.Time) time.Duration {
+	return mc.now().Sub(t)
+}
+
+// getClock returns the clock of the copy, which is the real time if
+// it isn't set
+func (mc *multiThreadCopyState) getClock() clock {
+	if mc.clock == nil {
+		return realClock{}
+	}
+	return mc.clock
+}
@@ -0,0 +1,192 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock which only moves when the test moves it
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	ticks chan time.Time // ticks for all the tickers
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		ticks: make(chan time.Time),
+	}
+}
+
+// Now returns the fake time
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker which ticks when the test calls tick
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	return fakeTicker{c: c.ticks}
+}
+
+// NewTimer returns a timer which fires when the test calls tick
+func (c *fakeClock) NewTimer(d time.Duration) timer {
+	return fakeTimer{c: c.ticks}
+}
+
+// advance moves the fake time on by d
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// tick delivers a tick to a ticker, waiting for it to be received
+func (c *fakeClock) tick() {
+	c.ticks <- c.Now()
+}
+
+// fakeTicker ticks when the fakeClock is told to
+type fakeTicker struct {
+	c chan time.Time
+}
+
+func (t fakeTicker) C() <-chan time.Time { return t.c }
+func (t fakeTicker) Stop()               {}
+
+// fakeTimer fires when the fakeClock is told to tick
+type fakeTimer struct {
+	c chan time.Time
+}
+
+func (t fakeTimer) C() <-chan time.Time { return t.c }
+func (t fakeTimer) Stop() bool          { return true }
+
+// clockAccount moves the clock on the first time it is called as if
+// reading the chunk took that long
+type clockAccount struct {
+	MultiThreadAccount
+	clock *fakeClock
+	took  time.Duration
+	once  sync.Once
+}
+
+func (a *clockAccount) AccountRead(n int) error {
+	a.once.Do(func() { a.clock.advance(a.took) })
+	return a.MultiThreadAccount.AccountRead(n)
+}
+
+func TestMultithreadAdaptChunkSizeClock(t *testing.T) {
+	ctx := context.Background()
+	const size = 3 << 20
+	contents := []byte(random.String(size))
+	src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	writerAt := &memWriterAt{}
+	w := newTestWriterAtChunkWriter(size, 512<<10, writerAt)
+	clock := newFakeClock()
+	mc := &multiThreadCopyState{
+		size:        size,
+		partSize:    512 << 10,
+		numChunks:   6,
+		src:         src,
+		noBuffering: true,
+		clock:       clock,
+	}
+	mc.acc = &clockAccount{MultiThreadAccount: tr.Account(ctx, nil), clock: clock, took: adaptiveChunkDuration / 2}
+
+	// The first chunk takes half adaptiveChunkDuration so the rest
+	// should be twice the size
+	require.NoError(t, mc.adaptChunkSize(ctx, w))
+	assert.Equal(t, int64(512<<10), mc.firstPartSize)
+	assert.Equal(t, int64(1<<20), mc.partSize)
+	assert.Equal(t, 4, mc.numChunks)
+	assert.Equal(t, mc.numChunks, w.chunks)
+}
+
+func TestMultithreadChunkWatchdogClock(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	mc := &multiThreadCopyState{src: mockobject.New("file.txt"), numChunks: 1, minSpeed: 1000, clock: clock}
+
+	chunkCtx, wd, stop := mc.startWatchdog(ctx, 0)
+
+	// Enough progress in each window keeps the chunk going
+	for i := 0; i < 2; i++ {
+		wd.bytes.Add(wd.minBytes)
+		clock.tick()
+	}
+	assert.NoError(t, chunkCtx.Err())
+
+	// A window with too little progress stops it
+	wd.bytes.Add(wd.minBytes - 1)
+	clock.tick()
+	<-chunkCtx.Done()
+	err := chunkCtx.Err()
+	stop(&err)
+	assert.True(t, errors.Is(err, errChunkTooSlow))
+}
+
+func TestMultithreadDispatchJitterClock(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	mc := &multiThreadCopyState{clock: clock}
+
+	// Waits for the clock whatever the jitter
+	done := make(chan struct{})
+	go func() {
+		mc.dispatchJitter(ctx, time.Hour)
+		close(done)
+	}()
+	clock.tick()
+	<-done
+
+	// No jitter doesn't wait
+	mc.dispatchJitter(ctx, 0)
+}
+
+func TestMultithreadCopyResultDurationClock(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 25
+	oldRegistry := fs.Registry
+	mockfs.Register()
+	defer func() {
+		fs.Registry = oldRegistry
+	}()
+	const remote = "file.txt"
+	contents := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+	}
+
+	// The copy takes as long as the clock says it does
+	clock := newFakeClock()
+	ctx = withMultiThreadClock(ctx, clock)
+	ctx = WithMultiThreadOnOpen(ctx, func(info fs.ChunkWriterInfo) {
+		clock.advance(time.Hour)
+	})
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 2, tr)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, result.Duration)
+}
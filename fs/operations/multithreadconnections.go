@@ -0,0 +1,51 @@
+package operations
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// multiThreadFreeConnections returns the smallest number of free
+// connections of fss for --multi-thread-check-connections.
+//
+// It returns -1 if the flag isn't set or none of fss limit their
+// connections.
+func multiThreadFreeConnections(ctx context.Context, fss ...fs.Info) int {
+	ci := fs.GetConfig(ctx)
+	if !ci.MultiThreadCheckConns {
+		return -1
+	}
+	free := -1
+	for _, f := range fss {
+		connectionLimit := f.Features().ConnectionLimit
+		if connectionLimit == nil {
+			continue
+		}
+		n := connectionLimit(ctx)
+		if n < 0 {
+			continue
+		}
+		if free < 0 || n < free {
+			free = n
+		}
+	}
+	return free
+}
+
+// limitStreamsToConnections returns streams reduced to the number of
+// free connections of f if --multi-thread-check-connections is set.
+//
+// It never returns less than 1 stream so the copy can go on waiting
+// for a connection.
+func limitStreamsToConnections(ctx context.Context, f fs.Info, what string, streams int) int {
+	free := multiThreadFreeConnections(ctx, f)
+	if free < 0 || streams <= free {
+		return streams
+	}
+	if free < 1 {
+		free = 1
+	}
+	fs.Debugf(f, "multi-thread copy: reducing %s streams from %d to %d as only %d connections are free", what, streams, free, free)
+	return free
+}
@@ -0,0 +1,54 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadCheckConnections(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadStreams, ci.MultiThreadCutoff = 4, 50
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(200)), mockobject.SeekModeNone)
+	srcFs := setTestSrcFs(ctx, t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+	free := 1
+	f.Features().ConnectionLimit = func(ctx context.Context) int {
+		return free
+	}
+
+	// Ignored unless the flag is set
+	assert.Equal(t, -1, multiThreadFreeConnections(ctx, srcFs, f))
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	ci.MultiThreadCheckConns = true
+	assert.Equal(t, 1, multiThreadFreeConnections(ctx, srcFs, f))
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+
+	// Backends which don't limit their connections are ignored
+	free = -1
+	assert.Equal(t, -1, multiThreadFreeConnections(ctx, srcFs, f))
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+
+	// The read and write streams are limited to the free
+	// connections of the source and destination
+	free = 2
+	srcFs.Features().ConnectionLimit = func(ctx context.Context) int {
+		return 3
+	}
+	assert.Equal(t, 2, multiThreadFreeConnections(ctx, srcFs, f))
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Concurrency)
+}
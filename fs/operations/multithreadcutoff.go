@@ -0,0 +1,111 @@
+package operations
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	// number of single stream transfers to measure before estimating
+	// the multi-thread cutoff
+	autoCutoffSamples = 8
+	// multi-thread is used once the time to transfer a file with a
+	// single stream is this many times the extra latency of opening
+	// the streams
+	autoCutoffFactor = 4
+)
+
+// cutoffSample is the size and duration of a single stream transfer
+type cutoffSample struct {
+	size     int64
+	duration time.Duration
+}
+
+// cutoffEstimator measures single stream transfers to estimate a
+// --multi-thread-cutoff for --multi-thread-cutoff-auto
+type cutoffEstimator struct {
+	mu      sync.Mutex
+	samples []cutoffSample
+}
+
+// globalCutoffEstimator is used to estimate the cutoff for all transfers
+var globalCutoffEstimator = &cutoffEstimator{}
+
+// add records a single stream transfer of size bytes which took
+// duration. Only the first autoCutoffSamples transfers are kept.
+func (e *cutoffEstimator) add(size int64, duration time.Duration) {
+	if size <= 0 || duration <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.samples) >= autoCutoffSamples {
+		return
+	}
+	e.samples = append(e.samples, cutoffSample{size: size, duration: duration})
+}
+
+// estimate fits duration = latency + size/bandwidth to the samples
+// with a least squares fit.
+//
+// It returns ok as false if there aren't enough samples or they
+// don't give a sensible fit.
+func (e *cutoffEstimator) estimate() (latency time.Duration, bandwidth float64, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := float64(len(e.samples))
+	if len(e.samples) < autoCutoffSamples {
+		return 0, 0, false
+	}
+	var meanSize, meanDuration float64
+	for _, sample := range e.samples {
+		meanSize += float64(sample.size)
+		meanDuration += sample.duration.Seconds()
+	}
+	meanSize /= n
+	meanDuration /= n
+	var covariance, variance float64
+	for _, sample := range e.samples {
+		dSize := float64(sample.size) - meanSize
+		covariance += dSize * (sample.duration.Seconds() - meanDuration)
+		variance += dSize * dSize
+	}
+	if variance == 0 || covariance <= 0 {
+		return 0, 0, false
+	}
+	secondsPerByte := covariance / variance
+	intercept := meanDuration - secondsPerByte*meanSize
+	if intercept < 0 {
+		intercept = 0
+	}
+	return time.Duration(intercept * float64(time.Second)), 1 / secondsPerByte, true
+}
+
+// cutoff returns the estimated multi-thread cutoff for streams
+// streams or fallback if there isn't an estimate yet.
+//
+// The cutoff is the size which takes autoCutoffFactor times the
+// latency of opening all the streams to transfer at the measured
+// single stream bandwidth. It is never less than minCutoff.
+func (e *cutoffEstimator) cutoff(streams int, minCutoff, fallback int64) int64 {
+	latency, bandwidth, ok := e.estimate()
+	if !ok {
+		return fallback
+	}
+	cutoff := int64(bandwidth * latency.Seconds() * float64(streams*autoCutoffFactor))
+	if cutoff < minCutoff {
+		cutoff = minCutoff
+	}
+	return cutoff
+}
+
+// multiThreadCutoff returns the size above which multi-thread
+// copies should be used.
+func multiThreadCutoff(ci *fs.ConfigInfo) int64 {
+	if !ci.MultiThreadCutoffAuto {
+		return int64(ci.MultiThreadCutoff)
+	}
+	return globalCutoffEstimator.cutoff(ci.MultiThreadStreams, int64(ci.MultiThreadChunkSize), int64(ci.MultiThreadCutoff))
+}
@@ -0,0 +1,60 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutoffEstimator(t *testing.T) {
+	const (
+		latency   = 100 * time.Millisecond
+		bandwidth = 10 * float64(fs.Mebi) // bytes per second
+		fallback  = int64(256 * fs.Mebi)
+		minCutoff = int64(1 * fs.Mebi)
+	)
+	e := &cutoffEstimator{}
+
+	// Not enough samples
+	assert.Equal(t, int64(fallback), e.cutoff(4, minCutoff, fallback))
+	e.add(0, time.Second)
+	e.add(100, 0)
+	assert.Len(t, e.samples, 0)
+
+	for i := 1; i <= autoCutoffSamples+2; i++ {
+		size := int64(i) * 100 * int64(fs.Kibi)
+		e.add(size, latency+time.Duration(float64(size)/bandwidth*float64(time.Second)))
+	}
+	assert.Len(t, e.samples, autoCutoffSamples)
+
+	gotLatency, gotBandwidth, ok := e.estimate()
+	assert.True(t, ok)
+	assert.InDelta(t, latency.Seconds(), gotLatency.Seconds(), 0.001)
+	assert.InDelta(t, bandwidth, gotBandwidth, 1000)
+
+	// 10 MiB/s * 0.1s * 4 streams * autoCutoffFactor
+	assert.InDelta(t, float64(4*fs.Mebi*autoCutoffFactor), float64(e.cutoff(4, minCutoff, fallback)), 1000)
+	assert.Equal(t, int64(64*fs.Mebi), e.cutoff(4, int64(64*fs.Mebi), fallback))
+
+	// All the same size gives no estimate
+	e = &cutoffEstimator{}
+	for i := 0; i < autoCutoffSamples; i++ {
+		e.add(int64(fs.Mebi), time.Second)
+	}
+	assert.Equal(t, int64(fallback), e.cutoff(4, minCutoff, fallback))
+}
+
+func TestMultiThreadCutoff(t *testing.T) {
+	ci := fs.NewConfig()
+	ci.MultiThreadCutoff = 123
+	assert.Equal(t, int64(123), multiThreadCutoff(ci))
+	ci.MultiThreadCutoffAuto = true
+	oldEstimator := globalCutoffEstimator
+	defer func() {
+		globalCutoffEstimator = oldEstimator
+	}()
+	globalCutoffEstimator = &cutoffEstimator{}
+	assert.Equal(t, int64(123), multiThreadCutoff(ci))
+}
@@ -0,0 +1,118 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"golang.org/x/sync/errgroup"
+)
+
+// nopWriterAtCloser turns an io.WriterAt into an fs.WriterAtCloser
+// whose Close does nothing as the caller owns it
+type nopWriterAtCloser struct {
+	io.WriterAt
+}
+
+// Close does nothing
+func (nopWriterAtCloser) Close() error {
+	return nil
+}
+
+// MultiThreadDownloadTo downloads src into w using concurrency
+// streams, each reading a chunk of --multi-thread-chunk-size from src
+// and writing it to w at its offset.
+//
+// This is the download half of a multi-thread copy for when the
+// destination isn't an fs.Fs, eg when streaming an object into a
+// processing pipeline. w must accept writes at any offset from
+// several goroutines at once. It isn't closed.
+func MultiThreadDownloadTo(ctx context.Context, src fs.Object, w io.WriterAt, concurrency int) (err error) {
+	ci := fs.GetConfig(ctx)
+	size := src.Size()
+	if size < 0 {
+		return errors.New("multi-thread download: can't download an object of unknown size")
+	}
+	chunkSize := int64(ci.MultiThreadChunkSize)
+	if chunkSize <= 0 {
+		return fmt.Errorf("multi-thread download: invalid chunk size %v", ci.MultiThreadChunkSize)
+	}
+	if size == 0 {
+		return nil
+	}
+	numChunks := calculateNumChunks(size, chunkSize)
+	if concurrency > numChunks {
+		concurrency = numChunks
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	tr := accounting.Stats(ctx).NewTransfer(src, nil)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	tr.SetStreams(concurrency)
+
+	chunkWriter := &writerAtChunkWriter{
+		remote:          src.Remote(),
+		size:            size,
+		chunkSize:       chunkSize,
+		chunks:          numChunks,
+		writerAt:        nopWriterAtCloser{w},
+		writeBufferSize: int64(ci.MultiThreadWriteBufferSize),
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	mc := &multiThreadCopyState{
+		ctx:        gCtx,
+		size:       size,
+		src:        src,
+		partSize:   chunkSize,
+		numChunks:  numChunks,
+		checkRange: ci.MultiThreadCheckRange,
+		clock:      getMultiThreadClock(ctx),
+	}
+	mc.eta = newChunkETA(numChunks, mc.now())
+	for _, option := range ci.DownloadHeaders {
+		mc.openOptions = append(mc.openOptions, option)
+	}
+	mc.buffers = getMultiThreadBuffers(ctx)
+	mc.shouldRetry = getMultiThreadRetryClassifier(ctx)
+	mc.inspector = getMultiThreadChunkInspector(ctx)
+	mc.acc = multiThreadAccount(gCtx, src, tr)
+
+	fs.Debugf(src, "Starting multi-thread download with %v chunks of size %v with %v parallel streams", fs.LogValue("total", numChunks), fs.LogValue("chunkSize", fs.SizeSuffix(chunkSize)), fs.LogValue("streams", concurrency))
+	for chunk := 0; chunk < numChunks; chunk++ {
+		// Fail fast, in case an errgroup managed function returns an error
+		if gCtx.Err() != nil {
+			break
+		}
+		// Wait for a goroutine under --multi-thread-max-goroutines
+		releaseGoroutine, err := multiThreadGoroutines.acquire(gCtx, ci.MultiThreadMaxGoroutines)
+		if err != nil {
+			break
+		}
+		chunk := chunk
+		g.Go(func() error {
+			defer releaseGoroutine()
+			return mc.copyChunk(gCtx, chunk, chunkWriter)
+		})
+	}
+	err = g.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	if written := mc.written.Load(); written != size {
+		return fmt.Errorf("multi-thread download: wrote %d bytes but source is %d bytes", written, size)
+	}
+	fs.Debugf(src, "Finished multi-thread download with %v chunks of size %v", fs.LogValue("total", numChunks), fs.LogValue("chunkSize", fs.SizeSuffix(chunkSize)))
+	return nil
+}
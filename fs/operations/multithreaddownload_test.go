@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiThreadDownloadTo(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadChunkSize = 25
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name        string
+		size        int
+		concurrency int
+	}{
+		{name: "Parallel", size: 110, concurrency: 4},
+		{name: "Serial", size: 110, concurrency: 1},
+		{name: "OneChunk", size: 10, concurrency: 4},
+		{name: "Empty", size: 0, concurrency: 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			contents := []byte(random.String(test.size))
+			src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+			src.SetFs(srcFs)
+			w := &memWriterAt{}
+			require.NoError(t, MultiThreadDownloadTo(ctx, src, w, test.concurrency))
+			assert.Equal(t, len(contents), len(w.buf))
+			if test.size > 0 {
+				assert.Equal(t, contents, w.buf)
+			}
+		})
+	}
+
+	t.Run("UnknownSize", func(t *testing.T) {
+		src := mockobject.New("file.txt").WithContent([]byte("potato"), mockobject.SeekModeNone)
+		src.SetFs(srcFs)
+		src.SetUnknownSize(true)
+		err := MultiThreadDownloadTo(ctx, src, &memWriterAt{}, 4)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown size")
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		src := mockobject.New("file.txt").WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+		src.SetFs(srcFs)
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := MultiThreadDownloadTo(cancelCtx, src, &memWriterAt{}, 4)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
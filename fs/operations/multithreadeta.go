@@ -0,0 +1,51 @@
+package operations
+
+import (
+	"sync"
+	"time"
+)
+
+// number of chunk completions to average the ETA over
+const chunkETAPeriod = 8
+
+// chunkETA estimates the time remaining for a multi-thread copy from
+// a moving average of the time between chunk completions.
+//
+// This is more accurate for a single large file than the ETA from
+// the transfer speed as it measures the chunks actually committed.
+type chunkETA struct {
+	mu       sync.Mutex
+	left     int       // number of chunks left to complete
+	last     time.Time // time of the last chunk completion or start
+	interval float64   // moving average of the seconds between completions
+	period   float64   // number of intervals in the moving average
+}
+
+// newChunkETA makes a chunkETA for chunks chunks starting at now
+func newChunkETA(chunks int, now time.Time) *chunkETA {
+	return &chunkETA{
+		left: chunks,
+		last: now,
+	}
+}
+
+// done records that a chunk completed at now and returns the
+// estimated time until the remaining chunks complete.
+func (e *chunkETA) done(now time.Time) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.left > 0 {
+		e.left--
+	}
+	elapsed := now.Sub(e.last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	e.last = now
+	// Soft start the moving average
+	if e.period < chunkETAPeriod {
+		e.period++
+	}
+	e.interval = (elapsed + (e.period-1)*e.interval) / e.period
+	return time.Duration(float64(e.left) * e.interval * float64(time.Second)).Round(time.Second)
+}
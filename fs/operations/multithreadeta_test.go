@@ -0,0 +1,36 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkETA(t *testing.T) {
+	now := time.Now()
+	e := newChunkETA(10, now)
+
+	// One chunk every 2 seconds
+	for i := 1; i <= 4; i++ {
+		now = now.Add(2 * time.Second)
+		assert.Equal(t, time.Duration(10-i)*2*time.Second, e.done(now), "chunk %d", i)
+	}
+
+	// Chunks speed up to 1 per second - the ETA follows
+	// gradually
+	now = now.Add(time.Second)
+	eta := e.done(now)
+	assert.Less(t, eta, 5*2*time.Second)
+	assert.Greater(t, eta, 5*time.Second)
+
+	// Finish the rest
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		eta = e.done(now)
+	}
+	assert.Equal(t, time.Duration(0), eta)
+
+	// Extra completions don't go negative
+	assert.Equal(t, time.Duration(0), e.done(now.Add(time.Second)))
+}
@@ -0,0 +1,43 @@
+package operations
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// multiThreadGoroutines limits the chunk copying goroutines started by
+// all the multi-thread copies for --multi-thread-max-goroutines
+var multiThreadGoroutines goroutineLimit
+
+// goroutineLimit is a process wide limit on goroutines which is
+// remade if the limit changes
+type goroutineLimit struct {
+	mu  sync.Mutex
+	max int
+	sem *semaphore.Weighted
+}
+
+// acquire waits until another goroutine is allowed by max, returning
+// a function to call when the goroutine has finished. A max <= 0
+// means there is no limit.
+func (l *goroutineLimit) acquire(ctx context.Context, max int) (release func(), err error) {
+	if max <= 0 {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	if l.sem == nil || l.max != max {
+		l.sem = semaphore.NewWeighted(int64(max))
+		l.max = max
+	}
+	sem := l.sem
+	l.mu.Unlock()
+	err = sem.Acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		sem.Release(1)
+	}, nil
+}
@@ -0,0 +1,69 @@
+package operations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoroutineLimit(t *testing.T) {
+	ctx := context.Background()
+	var l goroutineLimit
+
+	// Unlimited
+	release, err := l.acquire(ctx, 0)
+	require.NoError(t, err)
+	release()
+	assert.Nil(t, l.sem)
+
+	release1, err := l.acquire(ctx, 2)
+	require.NoError(t, err)
+	release2, err := l.acquire(ctx, 2)
+	require.NoError(t, err)
+
+	// Full until one is released
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(timeoutCtx, 2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release1()
+	release3, err := l.acquire(ctx, 2)
+	require.NoError(t, err)
+	release2()
+	release3()
+
+	// Changing the limit makes a new semaphore
+	old := l.sem
+	release, err = l.acquire(ctx, 3)
+	require.NoError(t, err)
+	release()
+	assert.NotEqual(t, old, l.sem)
+	assert.Equal(t, 3, l.max)
+}
+
+func TestMultithreadCopyMaxGoroutines(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadMaxGoroutines = 2
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(200)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &inflightChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), dst.Size())
+	// The copy is set up with 4 streams but only 2 run at once
+	assert.Equal(t, 4, result.Concurrency)
+	assert.Equal(t, int32(2), w.maxInflight.Load())
+}
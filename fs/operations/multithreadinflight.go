@@ -0,0 +1,52 @@
+package operations
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// inflightBytes limits the total size of the chunks of a multi-thread
+// copy being copied at once for --multi-thread-max-inflight-bytes
+type inflightBytes struct {
+	max int64
+	sem *semaphore.Weighted
+}
+
+// newInflightBytes makes an inflightBytes allowing max bytes in
+// flight or returns nil if max <= 0 meaning there is no limit.
+func newInflightBytes(max int64) *inflightBytes {
+	if max <= 0 {
+		return nil
+	}
+	return &inflightBytes{
+		max: max,
+		sem: semaphore.NewWeighted(max),
+	}
+}
+
+// acquire waits until size bytes can be put in flight, returning how
+// many were acquired. A chunk bigger than the limit acquires all of
+// it so it is copied on its own rather than never.
+//
+// Return the bytes with release when the chunk is done.
+func (b *inflightBytes) acquire(ctx context.Context, size int64) (int64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	if size > b.max {
+		size = b.max
+	}
+	if err := b.sem.Acquire(ctx, size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// release returns bytes acquired with acquire
+func (b *inflightBytes) release(acquired int64) {
+	if b == nil || acquired == 0 {
+		return
+	}
+	b.sem.Release(acquired)
+}
@@ -0,0 +1,105 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInflightBytes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("NoLimit", func(t *testing.T) {
+		b := newInflightBytes(0)
+		assert.Nil(t, b)
+		acquired, err := b.acquire(ctx, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), acquired)
+		b.release(acquired)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		b := newInflightBytes(100)
+		first, err := b.acquire(ctx, 60)
+		require.NoError(t, err)
+		assert.Equal(t, int64(60), first)
+
+		// Doesn't fit until the first is released
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = b.acquire(timeoutCtx, 60)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		b.release(first)
+		second, err := b.acquire(ctx, 60)
+		require.NoError(t, err)
+		b.release(second)
+	})
+
+	t.Run("BiggerThanLimit", func(t *testing.T) {
+		b := newInflightBytes(100)
+		acquired, err := b.acquire(ctx, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), acquired)
+		b.release(acquired)
+	})
+}
+
+// inflightChunkWriter records the most chunks written at once
+type inflightChunkWriter struct {
+	orderChunkWriter
+	inflight    atomic.Int32
+	maxInflight atomic.Int32
+}
+
+func (w *inflightChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	n := w.inflight.Add(1)
+	defer w.inflight.Add(-1)
+	for {
+		max := w.maxInflight.Load()
+		if n <= max || w.maxInflight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	return w.orderChunkWriter.WriteChunk(ctx, chunkNumber, reader)
+}
+
+func TestMultithreadCopyMaxInflightBytes(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(200)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	for _, test := range []struct {
+		name        string
+		maxInflight fs.SizeSuffix
+		want        int32
+	}{
+		{name: "NoLimit", maxInflight: 0, want: 4},
+		{name: "TwoChunks", maxInflight: 50, want: 2},
+		{name: "SmallerThanChunk", maxInflight: 10, want: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ci.MultiThreadMaxInflightBytes = test.maxInflight
+			w := &inflightChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}}
+			f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+			require.NoError(t, err)
+			assert.Equal(t, int64(200), dst.Size())
+			assert.Len(t, w.order, 8)
+			assert.Equal(t, test.want, w.maxInflight.Load())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadCopyChunkInspector(t *testing.T) {
+	const remote = "file.txt"
+	errVirus := errors.New("virus found")
+	for _, test := range []struct {
+		name   string
+		reject int // chunk to reject, -1 for none
+	}{
+		{name: "Accept", reject: -1},
+		{name: "Reject", reject: 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadChunkSize = 25
+			ci.MultiThreadStreams = 1
+			data := []byte(random.String(100))
+			src := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, src)
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			w := &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return w, nil
+			}
+
+			var (
+				mu        sync.Mutex
+				inspected = map[int]string{}
+			)
+			ctx = WithMultiThreadChunkInspector(ctx, func(ctx context.Context, chunk int, data io.Reader) error {
+				// Read all the chunk so it has to be rewound
+				buf, err := io.ReadAll(data)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				inspected[chunk] = string(buf)
+				mu.Unlock()
+				if chunk == test.reject {
+					return errVirus
+				}
+				return nil
+			})
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 1, tr)
+			if test.reject < 0 {
+				require.NoError(t, err)
+				require.NotNil(t, dst)
+				// The chunks were inspected with the right data and
+				// written in full after
+				require.Len(t, inspected, 4)
+				for chunk := 0; chunk < 4; chunk++ {
+					assert.Equal(t, string(data[chunk*25:(chunk+1)*25]), inspected[chunk])
+				}
+				assert.Equal(t, string(data), string(w.buf))
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errVirus), "wrong error: %v", err)
+			assert.True(t, fserrors.IsNoRetryError(err))
+			assert.Contains(t, err.Error(), "chunk 3 rejected by inspector")
+			// The rejected chunk wasn't written
+			assert.Equal(t, []int{0, 1}, result.CompletedChunks)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+)
+
+// multiThreadManifestSuffix is added to the name of the destination to
+// make the name of the manifest written by --multi-thread-manifest
+const multiThreadManifestSuffix = ".rclone-manifest.json"
+
+// MultiThreadManifest lists the chunks a multi-thread copy wrote with
+// their checksums so parts of the destination can be checked later
+// without reading the whole object.
+type MultiThreadManifest struct {
+	Remote   string                     `json:"remote"`   // name of the destination
+	Size     int64                      `json:"size"`     // size of the destination
+	HashType string                     `json:"hashType"` // type of the chunk checksums
+	Chunks   []MultiThreadManifestChunk `json:"chunks"`   // the chunks in order
+}
+
+// MultiThreadManifestChunk describes one chunk in a MultiThreadManifest
+type MultiThreadManifestChunk struct {
+	Offset int64  `json:"offset"` // offset of the chunk in the destination
+	Length int64  `json:"length"` // length of the chunk
+	Hash   string `json:"hash"`   // checksum of the chunk, empty if it wasn't copied this time
+}
+
+// manifestBuilder collects the chunk checksums for --multi-thread-manifest
+type manifestBuilder struct {
+	hashType hash.Type
+	mu       sync.Mutex
+	sums     map[int]string // checksum of each chunk written
+}
+
+// newManifestBuilder makes a manifestBuilder using hashType for the
+// chunk checksums
+func newManifestBuilder(hashType hash.Type) *manifestBuilder {
+	return &manifestBuilder{
+		hashType: hashType,
+		sums:     map[int]string{},
+	}
+}
+
+// add records the checksum of chunk from hasher
+func (m *manifestBuilder) add(chunk int, hasher *hash.MultiHasher) error {
+	sum, err := hasher.SumString(m.hashType, false)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to calculate %v hash of chunk %d for manifest: %w", m.hashType, chunk+1, err)
+	}
+	m.mu.Lock()
+	m.sums[chunk] = sum
+	m.mu.Unlock()
+	return nil
+}
+
+// build makes the manifest for the chunks of mc copied to remote
+func (m *manifestBuilder) build(mc *multiThreadCopyState, remote string) *MultiThreadManifest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	manifest := &MultiThreadManifest{
+		Remote:   remote,
+		Size:     mc.size,
+		HashType: m.hashType.String(),
+		Chunks:   make([]MultiThreadManifestChunk, 0, mc.numChunks),
+	}
+	for chunk := 0; chunk < mc.numChunks; chunk++ {
+		start, end := chunkRange(chunk, mc.size, mc.firstPartSize, mc.partSize)
+		manifest.Chunks = append(manifest.Chunks, MultiThreadManifestChunk{
+			Offset: start,
+			Length: end - start,
+			Hash:   m.sums[chunk],
+		})
+	}
+	return manifest
+}
+
+// writeMultiThreadManifest stores manifest next to the destination in f
+func writeMultiThreadManifest(ctx context.Context, f fs.Fs, manifest *MultiThreadManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to encode manifest: %w", err)
+	}
+	remote := manifest.Remote + multiThreadManifestSuffix
+	info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(data)), true, nil, f)
+	_, err = f.Put(ctx, bytes.NewReader(data), info)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to write manifest %q: %w", remote, err)
+	}
+	fs.Debugf(manifest.Remote, "multi-thread copy: wrote manifest of %d chunks to %q", len(manifest.Chunks), remote)
+	return nil
+}
+
+// readMultiThreadManifest reads the manifest written next to remote on
+// f by --multi-thread-manifest, returning fs.ErrorObjectNotFound if
+// there isn't one
+func readMultiThreadManifest(ctx context.Context, f fs.Fs, remote string) (manifest *MultiThreadManifest, err error) {
+	o, err := f.NewObject(ctx, remote+multiThreadManifestSuffix)
+	if err != nil {
+		return nil, err
+	}
+	in, err := Open(ctx, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+	manifest = new(MultiThreadManifest)
+	err = json.NewDecoder(in).Decode(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// chunkLayout returns the sizes of the chunks in the manifest as
+// passed to chunkRange. firstPartSize is 0 unless chunk 0 is a
+// different size to the ones which follow it.
+func (m *MultiThreadManifest) chunkLayout() (firstPartSize, partSize int64) {
+	if len(m.Chunks) == 0 {
+		return 0, 0
+	}
+	partSize = m.Chunks[0].Length
+	if len(m.Chunks) < 2 {
+		return 0, partSize
+	}
+	// Only the last chunk may be shorter than the ones before it
+	if second := m.Chunks[1].Length; second > partSize || (len(m.Chunks) > 2 && second != partSize) {
+		return partSize, second
+	}
+	return 0, partSize
+}
+
+// finishManifest builds the manifest of the copy of src to (f,
+// remote), returns it in result and collector if set and writes it next
+// to the destination if --multi-thread-manifest is set.
+//
+// Failing to write the manifest doesn't fail the copy but is counted
+// as an error.
+func (mc *multiThreadCopyState) finishManifest(ctx context.Context, f fs.Fs, remote string, result *MultiThreadCopyResult, collector *manifestCollector) {
+	result.Manifest = mc.manifest.build(mc, remote)
+	if collector != nil {
+		collector.set(result.Manifest)
+	}
+	if !fs.GetConfig(ctx).MultiThreadManifest {
+		return
+	}
+	err := writeMultiThreadManifest(ctx, f, result.Manifest)
+	if err != nil {
+		fs.Errorf(mc.src, "%v", err)
+		_ = accounting.Stats(ctx).Error(err)
+	}
+}
+
+type multiThreadManifestKeyType struct{}
+
+// Context key for the manifest collector
+var multiThreadManifestKey = multiThreadManifestKeyType{}
+
+// manifestCollector receives the manifest of a multi-thread copy for
+// WithMultiThreadManifest
+type manifestCollector struct {
+	mu       sync.Mutex
+	manifest *MultiThreadManifest
+}
+
+// set records manifest as the latest one
+func (c *manifestCollector) set(manifest *MultiThreadManifest) {
+	c.mu.Lock()
+	c.manifest = manifest
+	c.mu.Unlock()
+}
+
+// get returns the latest manifest or nil if there isn't one
+func (c *manifestCollector) get() *MultiThreadManifest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.manifest
+}
+
+// WithMultiThreadManifest returns a context which makes multi-thread
+// copies checksum their chunks as --multi-thread-manifest does, without
+// writing the manifest to the destination unless that is set too.
+//
+// The function returned gives the manifest of the last multi-thread
+// copy made with the context to finish, or nil if there wasn't one, for
+// example because the file was copied with a single stream.
+func WithMultiThreadManifest(ctx context.Context) (context.Context, func() *MultiThreadManifest) {
+	collector := &manifestCollector{}
+	return context.WithValue(ctx, multiThreadManifestKey, collector), collector.get
+}
+
+// getMultiThreadManifestCollector returns the collector from
+// WithMultiThreadManifest or nil
+func getMultiThreadManifestCollector(ctx context.Context) *manifestCollector {
+	collector, _ := ctx.Value(multiThreadManifestKey).(*manifestCollector)
+	return collector
+}
@@ -0,0 +1,114 @@
+package operations
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putFs is a mockfs.Fs which stores the objects Put to it
+type putFs struct {
+	*mockfs.Fs
+}
+
+func (f putFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	o := mockobject.New(src.Remote()).WithContent(data, mockobject.SeekModeNone)
+	f.AddObject(o)
+	return o, nil
+}
+
+func TestMultithreadCopyManifest(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadManifest = true
+	const remote = "file.txt"
+	contents := []byte(random.String(90))
+	src := mockobject.New(remote).WithContent(contents, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	var want []MultiThreadManifestChunk
+	for offset := 0; offset < len(contents); offset += 25 {
+		end := offset + 25
+		if end > len(contents) {
+			end = len(contents)
+		}
+		sum := md5.Sum(contents[offset:end])
+		want = append(want, MultiThreadManifestChunk{
+			Offset: int64(offset),
+			Length: int64(end - offset),
+			Hash:   hex.EncodeToString(sum[:]),
+		})
+	}
+
+	newFs := func(t *testing.T) (*mockfs.Fs, *orderChunkWriter) {
+		w := &orderChunkWriter{remote: remote, last: -1}
+		f := newTestChunkWriterFs(ctx, t, fs.ChunkWriterInfo{
+			ChunkSize:    25,
+			Concurrency:  4,
+			NoSeekNeeded: true,
+		}, w)
+		return f.(*mockfs.Fs), w
+	}
+
+	t.Run("Stored", func(t *testing.T) {
+		f, _ := newFs(t)
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		defer tr.Done(ctx, nil)
+		_, result, err := MultiThreadCopyWithResult(ctx, putFs{f}, remote, src, 4, tr)
+		require.NoError(t, err)
+		require.NotNil(t, result.Manifest)
+		assert.Equal(t, remote, result.Manifest.Remote)
+		assert.Equal(t, int64(len(contents)), result.Manifest.Size)
+		assert.Equal(t, "md5", result.Manifest.HashType)
+		assert.Equal(t, want, result.Manifest.Chunks)
+
+		o, err := f.NewObject(ctx, remote+multiThreadManifestSuffix)
+		require.NoError(t, err)
+		in, err := o.Open(ctx)
+		require.NoError(t, err)
+		var stored MultiThreadManifest
+		require.NoError(t, json.NewDecoder(in).Decode(&stored))
+		require.NoError(t, in.Close())
+		assert.Equal(t, result.Manifest, &stored)
+	})
+
+	t.Run("PutFails", func(t *testing.T) {
+		ctx := accounting.WithStatsGroup(ctx, "TestMultithreadCopyManifest")
+		f, _ := newFs(t)
+		tr := accounting.Stats(ctx).NewTransfer(src, nil)
+		defer tr.Done(ctx, nil)
+		dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+		require.NoError(t, err)
+		require.NotNil(t, dst)
+		require.NotNil(t, result.Manifest)
+		assert.Equal(t, want, result.Manifest.Chunks)
+		assert.Equal(t, int64(1), accounting.Stats(ctx).GetErrors())
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		ci.MultiThreadManifest = false
+		defer func() { ci.MultiThreadManifest = true }()
+		f, _ := newFs(t)
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		defer tr.Done(ctx, nil)
+		_, result, err := MultiThreadCopyWithResult(ctx, putFs{f}, remote, src, 4, tr)
+		require.NoError(t, err)
+		assert.Nil(t, result.Manifest)
+		_, err = f.NewObject(ctx, remote+multiThreadManifestSuffix)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// errChunkTooSlow is the cause of the context of a chunk stopped by
+// --multi-thread-min-speed
+var errChunkTooSlow = errors.New("chunk slower than --multi-thread-min-speed")
+
+// minSpeedWindow is how long a chunk may run below
+// --multi-thread-min-speed before it is stopped
+var minSpeedWindow = 30 * time.Second
+
+// chunkWatchdog stops a chunk which moves less than minBytes in any
+// minSpeedWindow, catching connections which are alive but so slow
+// they are useless.
+//
+// Progress is counted from the bytes the chunk reads from the source
+// and the bytes it accounts as they are written. Time spent waiting
+// for a write stream or a buffer isn't counted.
+type chunkWatchdog struct {
+	mc       *multiThreadCopyState
+	chunk    int
+	window   time.Duration // how often progress is checked
+	minBytes int64         // least bytes to move in each window
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	bytes    atomic.Int64 // bytes moved so far
+	paused   atomic.Int32 // set while the chunk is waiting
+}
+
+// startWatchdog starts a watchdog for chunk if --multi-thread-min-speed
+// is set, returning the context to copy the chunk with.
+//
+// The function returned stops the watchdog and must be called with a
+// pointer to the error the chunk returned. If the watchdog stopped
+// the chunk then the error is replaced with one which can be retried.
+//
+// The watchdog returned is nil if there is no minimum speed, which its
+// methods allow.
+func (mc *multiThreadCopyState) startWatchdog(ctx context.Context, chunk int) (context.Context, *chunkWatchdog, func(*error)) {
+	if mc.minSpeed <= 0 {
+		return ctx, nil, func(*error) {}
+	}
+	chunkCtx, cancel := context.WithCancelCause(ctx)
+	wd := &chunkWatchdog{
+		mc:       mc,
+		chunk:    chunk,
+		window:   minSpeedWindow,
+		minBytes: int64(float64(mc.minSpeed) * minSpeedWindow.Seconds()),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go wd.run()
+	return chunkCtx, wd, func(perr *error) {
+		close(wd.done)
+		if *perr != nil && errors.Is(context.Cause(chunkCtx), errChunkTooSlow) {
+			*perr = fserrors.RetryError(fmt.Errorf("multi-thread copy: chunk %d/%d: %w", chunk+1, mc.numChunks, errChunkTooSlow))
+		}
+		cancel(nil)
+	}
+}
+
+// run checks the progress of the chunk every window until it is done
+func (wd *chunkWatchdog) run() {
+	ticker := wd.mc.getClock().NewTicker(wd.window)
+	defer ticker.Stop()
+	last := int64(0)
+	for {
+		select {
+		case <-wd.done:
+			return
+		case <-ticker.C():
+		}
+		now := wd.bytes.Load()
+		if moved := now - last; moved < wd.minBytes && wd.paused.Load() == 0 {
+			fs.Logf(wd.mc.src, "multi-thread copy: chunk %v/%v moved %v in %v which is below --multi-thread-min-speed %v/s - stopping it", fs.LogValue("chunk", wd.chunk+1), fs.LogValue("total", wd.mc.numChunks), fs.LogValue("moved", fs.SizeSuffix(moved)), wd.window, fs.SizeSuffix(wd.mc.minSpeed))
+			wd.cancel(errChunkTooSlow)
+			return
+		}
+		last = now
+	}
+}
+
+// account returns account wrapped to count the bytes as progress
+func (wd *chunkWatchdog) account(account func(n int) error) func(n int) error {
+	if wd == nil {
+		return account
+	}
+	return func(n int) error {
+		wd.bytes.Add(int64(n))
+		return account(n)
+	}
+}
+
+// reader returns in wrapped to count the bytes read as progress
+func (wd *chunkWatchdog) reader(in io.Reader) io.Reader {
+	if wd == nil {
+		return in
+	}
+	return &watchdogReader{Reader: in, wd: wd}
+}
+
+// wait calls fn which waits for something other than the transfer
+// without the time it takes counting against the chunk
+func (wd *chunkWatchdog) wait(fn func() error) error {
+	if wd == nil {
+		return fn()
+	}
+	wd.paused.Add(1)
+	defer wd.paused.Add(-1)
+	return fn()
+}
+
+// watchdogReader counts the bytes read through it as progress
+type watchdogReader struct {
+	io.Reader
+	wd *chunkWatchdog
+}
+
+// Read reads from the underlying reader counting the bytes read
+func (r *watchdogReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.wd.bytes.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadCopyMinSpeed(t *testing.T) {
+	oldWindow := minSpeedWindow
+	minSpeedWindow = 50 * time.Millisecond
+	defer func() {
+		minSpeedWindow = oldWindow
+	}()
+	const remote = "file.txt"
+
+	for _, test := range []struct {
+		name  string
+		stuck bool
+	}{
+		{name: "Fast"},
+		{name: "Stuck", stuck: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadChunkSize = 25
+			ci.MultiThreadStreams = 1
+			ci.MultiThreadMinSpeed = 1000
+			content := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, content)
+			var src fs.Object = content
+			if test.stuck {
+				// Opening chunk 2 never returns any data
+				src = &blockingOpenObject{ContentMockObject: content, blockStart: 50, opened: make(chan struct{})}
+			}
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}, nil
+			}
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 1, tr)
+			if !test.stuck {
+				require.NoError(t, err)
+				require.NotNil(t, dst)
+				assert.Equal(t, int64(100), result.Bytes)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errChunkTooSlow), "wrong error: %v", err)
+			assert.False(t, errors.Is(err, context.Canceled))
+			assert.True(t, fserrors.IsRetryError(err))
+			assert.Contains(t, err.Error(), "chunk 3/4")
+			assert.Equal(t, []int{0, 1}, result.CompletedChunks)
+		})
+	}
+}
+
+func TestMultithreadChunkWatchdogWait(t *testing.T) {
+	oldWindow := minSpeedWindow
+	minSpeedWindow = 10 * time.Millisecond
+	defer func() {
+		minSpeedWindow = oldWindow
+	}()
+	ctx := context.Background()
+	mc := &multiThreadCopyState{src: mockobject.New("file.txt"), numChunks: 1, minSpeed: 1000}
+
+	// Time spent waiting doesn't stop the chunk
+	chunkCtx, wd, stop := mc.startWatchdog(ctx, 0)
+	err := wd.wait(func() error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NoError(t, chunkCtx.Err())
+	stop(&err)
+	assert.NoError(t, err)
+
+	// Time spent not moving data does
+	chunkCtx, _, stop = mc.startWatchdog(ctx, 0)
+	<-chunkCtx.Done()
+	err = chunkCtx.Err()
+	stop(&err)
+	assert.True(t, errors.Is(err, errChunkTooSlow))
+
+	// No watchdog if there is no minimum speed
+	mc.minSpeed = 0
+	chunkCtx, wd, _ = mc.startWatchdog(ctx, 0)
+	assert.Equal(t, ctx, chunkCtx)
+	assert.Nil(t, wd)
+}
@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// matchMultiThreadPattern returns the first of patterns which matches
+// src for --multi-thread-include and --multi-thread-exclude.
+//
+// Patterns containing a "/" are matched against the MIME type of src,
+// eg "video/*", the others against the leaf name, eg "*.mp4". Both are
+// case insensitive.
+func matchMultiThreadPattern(ctx context.Context, src fs.Object, patterns []string) (pattern string, found bool) {
+	if len(patterns) == 0 {
+		return "", false
+	}
+	name := strings.ToLower(path.Base(src.Remote()))
+	mimeType := ""
+	for _, pattern := range patterns {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			if mimeType == "" {
+				mimeType, _, _ = strings.Cut(fs.MimeType(ctx, src), ";")
+				mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+			}
+			subject = mimeType
+		}
+		match, err := path.Match(strings.ToLower(pattern), subject)
+		if err != nil {
+			fs.Errorf(src, "multi-thread copy: ignoring bad pattern %q: %v", pattern, err)
+			continue
+		}
+		if match {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// multiThreadDisabledByMetadata returns true if src has the metadata
+// key set by --multi-thread-disable-key, whatever its value.
+//
+// The metadata is only read if --metadata is in use as it may need
+// an extra API call.
+func multiThreadDisabledByMetadata(ctx context.Context, src fs.Object, key string) bool {
+	ci := fs.GetConfig(ctx)
+	if key == "" || !ci.Metadata {
+		return false
+	}
+	metadata, err := fs.GetMetadata(ctx, src)
+	if err != nil {
+		fs.Debugf(src, "multi-thread copy: failed to read metadata to check for %q: %v", key, err)
+		return false
+	}
+	_, found := metadata[strings.ToLower(key)]
+	return found
+}
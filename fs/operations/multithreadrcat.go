@@ -0,0 +1,67 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/multipart"
+)
+
+// doMultiThreadRcat returns whether a stream of size bytes uploaded to
+// f should be uploaded in parallel chunks
+func doMultiThreadRcat(ctx context.Context, f fs.Fs, size int64) bool {
+	ci := fs.GetConfig(ctx)
+	return size >= 0 &&
+		size >= multiThreadCutoff(ci) &&
+		ci.MultiThreadStreams > 1 &&
+		f.Features().OpenChunkWriter != nil
+}
+
+// rcatChunkWriterOpener opens the chunk writer for multiThreadRcat,
+// choosing the number of streams in the same way as multi-thread
+// copies
+type rcatChunkWriterOpener struct {
+	f fs.Fs
+}
+
+// OpenChunkWriter opens the chunk writer on the destination
+func (o rcatChunkWriterOpener) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	ci := fs.GetConfig(ctx)
+	info, writer, err = o.f.Features().OpenChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return info, writer, err
+	}
+	info.Concurrency, err = chooseConcurrency(ci, src, info.Concurrency, ci.MultiThreadStreams)
+	if err != nil {
+		_ = writer.Abort(ctx)
+		return info, nil, err
+	}
+	fs.Debugf(src, "multi-thread rcat: uploading %v in chunks of %v with %d streams", fs.SizeSuffix(src.Size()), fs.SizeSuffix(info.ChunkSize), info.Concurrency)
+	return info, writer, nil
+}
+
+// multiThreadRcat uploads src.Size() bytes read sequentially from in
+// to f. The chunks are buffered as they are read and written in
+// parallel, so at most one chunk per stream is held in memory.
+//
+// If the backend can't do a multi-thread upload of src then it
+// returns an error wrapping fs.ErrorCantMultiThread before reading
+// anything from in.
+func multiThreadRcat(ctx context.Context, f fs.Fs, in io.Reader, src fs.ObjectInfo) (fs.Object, error) {
+	_, err := multipart.UploadMultipart(ctx, src, in, multipart.UploadMultipartOptions{
+		Open: rcatChunkWriterOpener{f: f},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread rcat: %w", err)
+	}
+	dst, err := f.NewObject(ctx, src.Remote())
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread rcat: failed to find object after upload: %w", err)
+	}
+	if dst.Size() != src.Size() {
+		return nil, fmt.Errorf("multi-thread rcat: uploaded %d bytes but --size was %d", dst.Size(), src.Size())
+	}
+	return dst, nil
+}
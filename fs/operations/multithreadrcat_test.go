@@ -0,0 +1,72 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoMultiThreadRcat(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadCutoff = 50
+	ci.MultiThreadStreams = 4
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+
+	assert.False(t, doMultiThreadRcat(ctx, f, 100), "no OpenChunkWriter")
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return fs.ChunkWriterInfo{}, nil, fs.ErrorCantMultiThread
+	}
+	assert.True(t, doMultiThreadRcat(ctx, f, 100))
+	assert.False(t, doMultiThreadRcat(ctx, f, 49), "below cutoff")
+	assert.False(t, doMultiThreadRcat(ctx, f, -1), "unknown size")
+	ci.MultiThreadStreams = 1
+	assert.False(t, doMultiThreadRcat(ctx, f, 100), "one stream")
+}
+
+func TestMultithreadRcatSize(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadCutoff = 1
+	ci.MultiThreadStreams = 4
+	const remote = "file.txt"
+	data := []byte(random.String(100))
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	w := &orderChunkWriter{f: f.(*mockfs.Fs), remote: remote, last: 3}
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		assert.Equal(t, int64(len(data)), src.Size())
+		return fs.ChunkWriterInfo{
+			ChunkSize:   25,
+			Concurrency: 4,
+		}, w, nil
+	}
+
+	dst, err := RcatSize(ctx, f, remote, io.NopCloser(bytes.NewReader(data)), int64(len(data)), time.Now(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), dst.Size())
+	assert.Equal(t, int64(len(data)), w.written)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, w.order)
+}
+
+func TestMultithreadRcatSizeCantMultiThread(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadCutoff = 1
+	ci.MultiThreadStreams = 4
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		return fs.ChunkWriterInfo{}, nil, fs.ErrorCantMultiThread
+	}
+
+	// Falls back to Put which mockfs doesn't implement
+	_, err = RcatSize(ctx, f, "file.txt", io.NopCloser(bytes.NewReader([]byte("hello"))), 5, time.Now(), nil)
+	assert.ErrorIs(t, err, mockfs.ErrNotImplemented)
+}
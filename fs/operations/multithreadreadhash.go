@@ -0,0 +1,157 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// readHasher checks the data read from the source of a multi-thread
+// copy against the checksums of the source for
+// --multi-thread-checksum-on-read.
+type readHasher struct {
+	src         fs.Object
+	hashType    hash.Type
+	rangeHasher fs.RangeHasher // set if the source can checksum ranges
+	wholeHash   string         // checksum of the whole source if known
+
+	mu    sync.Mutex
+	whole *hash.MultiHasher     // checksum of the chunks read so far, nil if not checking
+	next  int                   // next chunk to add to whole
+	turns map[int]chan struct{} // closed when it is the chunk's turn to be added to whole
+}
+
+// newReadHasher makes a readHasher for src or returns nil if src has
+// no checksums to check against.
+//
+// If whole is set then the chunks will be checked against the
+// checksum of the whole source, so all the chunks must be passed to
+// check.
+func newReadHasher(ctx context.Context, src fs.Object, whole bool) (h *readHasher, err error) {
+	hashType := src.Fs().Hashes().GetOne()
+	if hashType == hash.None {
+		fs.Debugf(src, "multi-thread copy: can't check source reads as the source has no checksums")
+		return nil, nil
+	}
+	h = &readHasher{
+		src:      src,
+		hashType: hashType,
+		turns:    map[int]chan struct{}{},
+	}
+	h.rangeHasher, _ = src.(fs.RangeHasher)
+	if whole {
+		h.wholeHash, err = src.Hash(ctx, hashType)
+		if err != nil {
+			return nil, fmt.Errorf("multi-thread copy: failed to read source %v hash: %w", hashType, err)
+		}
+	}
+	if h.wholeHash != "" {
+		h.whole, err = hash.NewMultiHasherTypes(hash.NewHashSet(hashType))
+		if err != nil {
+			return nil, fmt.Errorf("multi-thread copy: failed to make source hasher: %w", err)
+		}
+	}
+	if h.rangeHasher == nil && h.whole == nil {
+		fs.Debugf(src, "multi-thread copy: can't check source reads as the source has no %v checksum", hashType)
+		return nil, nil
+	}
+	return h, nil
+}
+
+// sum returns the hashType checksum of in, rewinding it afterwards
+func (h *readHasher) sum(in io.ReadSeeker) (string, error) {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(h.hashType))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(hasher, in)
+	if err != nil {
+		return "", err
+	}
+	_, err = in.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+	return hasher.SumString(h.hashType, false)
+}
+
+// check the data in of chunk which runs from start to end read from
+// the source, rewinding it afterwards.
+//
+// If the whole source checksum is being checked then this waits for
+// the preceding chunks to be checked first.
+func (h *readHasher) check(ctx context.Context, chunk int, start, end int64, in io.ReadSeeker) error {
+	if h.rangeHasher != nil {
+		want, err := h.rangeHasher.RangeHash(ctx, h.hashType, start, end-1)
+		if err != nil {
+			return fmt.Errorf("multi-thread copy: failed to read source %v hash of chunk %d: %w", h.hashType, chunk+1, err)
+		}
+		if want != "" {
+			got, err := h.sum(in)
+			if err != nil {
+				return fmt.Errorf("multi-thread copy: failed to calculate %v hash of chunk %d: %w", h.hashType, chunk+1, err)
+			}
+			if !hash.Equals(want, got) {
+				return fmt.Errorf("multi-thread copy: corrupted read of chunk %d (%d-%d): %v hash differ src(%q) read(%q)", chunk+1, start, end, h.hashType, want, got)
+			}
+		}
+	}
+	if h.whole == nil {
+		return nil
+	}
+
+	// Wait for our turn to add to the whole file checksum
+	h.mu.Lock()
+	for chunk != h.next {
+		turn, ok := h.turns[chunk]
+		if !ok {
+			turn = make(chan struct{})
+			h.turns[chunk] = turn
+		}
+		h.mu.Unlock()
+		select {
+		case <-turn:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		h.mu.Lock()
+	}
+	defer h.mu.Unlock()
+	_, err := io.Copy(h.whole, in)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to calculate %v hash of chunk %d: %w", h.hashType, chunk+1, err)
+	}
+	_, err = in.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to rewind chunk %d: %w", chunk+1, err)
+	}
+	h.next++
+	if turn, ok := h.turns[h.next]; ok {
+		close(turn)
+		delete(h.turns, h.next)
+	}
+	return nil
+}
+
+// finish checks the whole source checksum if it is being checked,
+// once all the chunks have been passed to check.
+func (h *readHasher) finish() error {
+	if h.whole == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	got, err := h.whole.SumString(h.hashType, false)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to calculate %v hash of source: %w", h.hashType, err)
+	}
+	if !hash.Equals(h.wholeHash, got) {
+		return fmt.Errorf("multi-thread copy: corrupted read of source: %v hash differ src(%q) read(%q)", h.hashType, h.wholeHash, got)
+	}
+	fs.Debugf(h.src, "multi-thread copy: %v hash of the data read matches the source", h.hashType)
+	return nil
+}
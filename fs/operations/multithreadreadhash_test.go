@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// rangeHashObject is an object which can checksum ranges of itself
+type rangeHashObject struct {
+	*mockobject.ContentMockObject
+	contents []byte
+}
+
+// RangeHash returns the checksum of the range start-end inclusive
+func (o *rangeHashObject) RangeHash(ctx context.Context, ty hash.Type, start, end int64) (string, error) {
+	sums, err := hash.StreamTypes(bytes.NewReader(o.contents[start:end+1]), hash.NewHashSet(ty))
+	if err != nil {
+		return "", err
+	}
+	return sums[ty], nil
+}
+
+var _ fs.RangeHasher = (*rangeHashObject)(nil)
+
+func TestMultithreadReadHasher(t *testing.T) {
+	ctx := context.Background()
+	contents := []byte(random.String(1000))
+	const chunkSize = 100
+	srcFs, err := mockfs.NewFs(ctx, "sausage", "", nil)
+	require.NoError(t, err)
+	srcFs.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.MD5))
+	src := mockobject.New("file.txt").WithContent(contents, mockobject.SeekModeNone)
+	src.SetFs(srcFs)
+
+	// check all the chunks of data in reverse order concurrently
+	checkAll := func(h *readHasher, data []byte) error {
+		g, gCtx := errgroup.WithContext(ctx)
+		for chunk := len(data)/chunkSize - 1; chunk >= 0; chunk-- {
+			chunk := chunk
+			g.Go(func() error {
+				start := int64(chunk * chunkSize)
+				end := start + chunkSize
+				return h.check(gCtx, chunk, start, end, bytes.NewReader(data[start:end]))
+			})
+		}
+		return g.Wait()
+	}
+	corrupt := append([]byte(nil), contents...)
+	corrupt[550] ^= 1
+
+	t.Run("Whole", func(t *testing.T) {
+		h, err := newReadHasher(ctx, src, true)
+		require.NoError(t, err)
+		require.NotNil(t, h)
+		assert.Nil(t, h.rangeHasher)
+		require.NoError(t, checkAll(h, contents))
+		require.NoError(t, h.finish())
+
+		h, err = newReadHasher(ctx, src, true)
+		require.NoError(t, err)
+		require.NoError(t, checkAll(h, corrupt))
+		err = h.finish()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "corrupted read of source")
+	})
+
+	t.Run("NotWhole", func(t *testing.T) {
+		h, err := newReadHasher(ctx, src, false)
+		require.NoError(t, err)
+		assert.Nil(t, h)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		rangeSrc := &rangeHashObject{ContentMockObject: src, contents: contents}
+		h, err := newReadHasher(ctx, rangeSrc, false)
+		require.NoError(t, err)
+		require.NotNil(t, h)
+		assert.Nil(t, h.whole)
+		require.NoError(t, checkAll(h, contents))
+		require.NoError(t, h.finish())
+
+		err = checkAll(h, corrupt)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "corrupted read of chunk 6 (500-600)")
+	})
+
+	t.Run("NoHashes", func(t *testing.T) {
+		srcFs.(*mockfs.Fs).SetHashes(hash.Set(hash.None))
+		defer srcFs.(*mockfs.Fs).SetHashes(hash.NewHashSet(hash.MD5))
+		h, err := newReadHasher(ctx, src, true)
+		require.NoError(t, err)
+		assert.Nil(t, h)
+	})
+}
@@ -0,0 +1,173 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"golang.org/x/sync/errgroup"
+)
+
+// RepairChunks copies only the chunks listed in indices of src over
+// the existing object remote on f, leaving the rest of it untouched,
+// for example to fix chunks a manifest shows are corrupt.
+//
+// Chunks are numbered from 0 and laid out as in the manifest written
+// by --multi-thread-manifest if there is one next to remote, otherwise
+// as a multi-thread copy to f would lay them out with
+// --multi-thread-chunk-size.
+//
+// The destination must be the same size as src and f must support
+// random writes with OpenWriterAt and its objects ReopenWriterAt. The
+// modification time of the destination is kept.
+func RepairChunks(ctx context.Context, f fs.Fs, remote string, src fs.Object, indices []int) (err error) {
+	ci := fs.GetConfig(ctx)
+	if f.Features().OpenWriterAt == nil {
+		return fmt.Errorf("multi-thread repair: %v doesn't support random writes", f)
+	}
+	dst, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return fmt.Errorf("multi-thread repair: failed to find destination: %w", err)
+	}
+	do, ok := dst.(fs.ReopenWriterAter)
+	if !ok {
+		return fmt.Errorf("multi-thread repair: %v can't rewrite objects in place", f)
+	}
+	size := src.Size()
+	if size < 0 || dst.Size() != size {
+		return fmt.Errorf("multi-thread repair: destination is %d bytes but source is %d bytes", dst.Size(), size)
+	}
+
+	// Find the chunk layout
+	var firstPartSize, partSize int64
+	manifest, err := readMultiThreadManifest(ctx, f, remote)
+	switch {
+	case err == nil && manifest.Size == size:
+		firstPartSize, partSize = manifest.chunkLayout()
+		fs.Debugf(dst, "multi-thread repair: using the chunks from the manifest")
+	case err == nil:
+		fs.Logf(dst, "multi-thread repair: ignoring manifest for a %d byte object", manifest.Size)
+	case !errors.Is(err, fs.ErrorObjectNotFound):
+		fs.Logf(dst, "multi-thread repair: ignoring manifest: %v", err)
+	}
+	if partSize <= 0 {
+		partSize = int64(ci.MultiThreadChunkSize)
+		if chunkSize, ok := fs.MultiThreadChunkSize(f); ok {
+			partSize = int64(chunkSize)
+		}
+		partSize = alignChunkSize(partSize, int64(ci.MultiThreadRangeAlign))
+	}
+	if partSize <= 0 {
+		return fmt.Errorf("multi-thread repair: invalid chunk size %v", fs.SizeSuffix(partSize))
+	}
+	numChunks := calculateNumChunks(size, partSize)
+	if firstPartSize > 0 && size > firstPartSize {
+		numChunks = 1 + calculateNumChunks(size-firstPartSize, partSize)
+	}
+
+	// Check and sort the chunks to repair
+	chunks := append([]int(nil), indices...)
+	sort.Ints(chunks)
+	for i, chunk := range chunks {
+		if chunk < 0 || chunk >= numChunks {
+			return fmt.Errorf("multi-thread repair: chunk %d out of range 0-%d", chunk, numChunks-1)
+		}
+		if i > 0 && chunk == chunks[i-1] {
+			return fmt.Errorf("multi-thread repair: chunk %d listed twice", chunk)
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	modTime := dst.ModTime(ctx)
+	writerAt, err := do.ReopenWriterAt(ctx)
+	if err != nil {
+		return fmt.Errorf("multi-thread repair: failed to open destination: %w", err)
+	}
+	chunkWriter := &writerAtChunkWriter{
+		remote:          remote,
+		size:            size,
+		chunkSize:       partSize,
+		firstChunkSize:  firstPartSize,
+		chunks:          numChunks,
+		writerAt:        writerAt,
+		writeBufferSize: int64(ci.MultiThreadWriteBufferSize),
+		f:               f,
+	}
+	// Never Abort the chunk writer as that would remove the destination
+	defer func() {
+		closeErr := chunkWriter.Close(ctx)
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("multi-thread repair: failed to close destination: %w", closeErr)
+		}
+	}()
+
+	tr := accounting.Stats(ctx).NewTransfer(src, f)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	concurrency := ci.MultiThreadStreams
+	if concurrency > len(chunks) {
+		concurrency = len(chunks)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	tr.SetStreams(concurrency)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	mc := &multiThreadCopyState{
+		ctx:           gCtx,
+		size:          size,
+		src:           src,
+		partSize:      partSize,
+		firstPartSize: firstPartSize,
+		numChunks:     numChunks,
+		checkRange:    ci.MultiThreadCheckRange,
+		clock:         getMultiThreadClock(ctx),
+	}
+	for _, option := range ci.DownloadHeaders {
+		mc.openOptions = append(mc.openOptions, option)
+	}
+	mc.buffers = getMultiThreadBuffers(ctx)
+	mc.shouldRetry = getMultiThreadRetryClassifier(ctx)
+	mc.acc = multiThreadAccount(gCtx, src, tr)
+
+	fs.Debugf(src, "Starting multi-thread repair of %d/%d chunks with %v parallel streams", len(chunks), numChunks, concurrency)
+	for _, chunk := range chunks {
+		if gCtx.Err() != nil {
+			break
+		}
+		chunk := chunk
+		g.Go(func() error {
+			return mc.copyChunk(gCtx, chunk, chunkWriter)
+		})
+	}
+	err = g.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	err = chunkWriter.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("multi-thread repair: failed to close destination: %w", err)
+	}
+
+	// Put the modification time back after writing
+	err = dst.SetModTime(ctx, modTime)
+	if errors.Is(err, fs.ErrorCantSetModTime) || errors.Is(err, fs.ErrorCantSetModTimeWithoutDelete) {
+		fs.Debugf(dst, "multi-thread repair: can't set modification time: %v", err)
+		err = nil
+	} else if err != nil {
+		return fmt.Errorf("multi-thread repair: failed to set modification time: %w", err)
+	}
+	fs.Infof(dst, "multi-thread repair: rewrote %d of %d chunks", len(chunks), numChunks)
+	return nil
+}
@@ -0,0 +1,95 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiThreadManifestChunkLayout(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		lengths   []int64
+		wantFirst int64
+		wantPart  int64
+	}{
+		{name: "Empty"},
+		{name: "One", lengths: []int64{10}, wantPart: 10},
+		{name: "Even", lengths: []int64{10, 10, 10}, wantPart: 10},
+		{name: "Tail", lengths: []int64{10, 10, 5}, wantPart: 10},
+		{name: "TwoTail", lengths: []int64{10, 5}, wantPart: 10},
+		{name: "First", lengths: []int64{5, 10, 10, 3}, wantFirst: 5, wantPart: 10},
+		{name: "FirstTwo", lengths: []int64{5, 10}, wantFirst: 5, wantPart: 10},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := &MultiThreadManifest{}
+			for _, length := range test.lengths {
+				m.Chunks = append(m.Chunks, MultiThreadManifestChunk{Length: length})
+			}
+			first, part := m.chunkLayout()
+			assert.Equal(t, test.wantFirst, first)
+			assert.Equal(t, test.wantPart, part)
+		})
+	}
+}
+
+func TestRepairChunks(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadChunkSize = 10
+	ci.MultiThreadStreams = 2
+
+	const remote = "repair.txt"
+	good := []byte(random.String(45))
+	bad := append([]byte(nil), good...)
+	for _, i := range []int{12, 33, 40} {
+		bad[i] ^= 0xFF
+	}
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	r.WriteFile(remote, string(bad), t1)
+	src := mockobject.New(remote).WithContent(good, mockobject.SeekModeNone)
+
+	read := func() []byte {
+		dst, err := r.Flocal.NewObject(ctx, remote)
+		require.NoError(t, err)
+		in, err := dst.Open(ctx)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, in.Close())
+		}()
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		return data
+	}
+
+	// Bad chunk numbers
+	err := RepairChunks(ctx, r.Flocal, remote, src, []int{5})
+	assert.ErrorContains(t, err, "out of range")
+	err = RepairChunks(ctx, r.Flocal, remote, src, []int{1, 1})
+	assert.ErrorContains(t, err, "listed twice")
+
+	// Repair only chunk 1 - the rest is untouched
+	require.NoError(t, RepairChunks(ctx, r.Flocal, remote, src, []int{1}))
+	got := read()
+	assert.Equal(t, good[:30], got[:30])
+	assert.Equal(t, bad[30:], got[30:])
+	assert.False(t, bytes.Equal(good, got))
+
+	// Repair the rest
+	require.NoError(t, RepairChunks(ctx, r.Flocal, remote, src, []int{3, 4}))
+	assert.Equal(t, good, read())
+
+	// The size and modification time are kept
+	dst, err := r.Flocal.NewObject(ctx, remote)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(good)), dst.Size())
+	fstest.AssertTimeEqualWithPrecision(t, remote, t1, dst.ModTime(ctx), fs.GetModifyWindow(ctx, r.Flocal))
+}
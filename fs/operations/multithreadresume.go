@@ -0,0 +1,285 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+)
+
+// name of the directory in the cache dir to store resume state
+const multiThreadResumeDir = "multi-thread-resume"
+
+// multiThreadResumeState is persisted so an interrupted multi-thread
+// upload can be resumed with --multi-thread-resume
+type multiThreadResumeState struct {
+	Remote   string `json:"remote"`   // destination of the upload for humans
+	UploadID string `json:"uploadId"` // ID of the upload from ChunkWriterInfo
+}
+
+// multiThreadResumeKey returns a key identifying the upload of src to
+// (f, remote).
+//
+// The size and modification time of src are included so a changed
+// source won't resume an upload of the old contents.
+func multiThreadResumeKey(ctx context.Context, f fs.Info, remote string, src fs.ObjectInfo) string {
+	h := sha1.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d", fs.ConfigString(f), remote, src.Size(), src.ModTime(ctx).UnixNano())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path to the file storing the resume state for key
+func multiThreadResumePath(key string) string {
+	return filepath.Join(config.GetCacheDir(), multiThreadResumeDir, key+".json")
+}
+
+// loadMultiThreadResumeState reads the resume state for key returning
+// nil if there isn't one
+func loadMultiThreadResumeState(key string) *multiThreadResumeState {
+	data, err := os.ReadFile(multiThreadResumePath(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fs.Debugf(nil, "multi-thread copy: failed to read resume state: %v", err)
+		}
+		return nil
+	}
+	var state multiThreadResumeState
+	err = json.Unmarshal(data, &state)
+	if err != nil || state.UploadID == "" {
+		fs.Debugf(nil, "multi-thread copy: ignoring corrupted resume state: %v", err)
+		return nil
+	}
+	return &state
+}
+
+// saveMultiThreadResumeState writes the resume state for key
+func saveMultiThreadResumeState(key string, state *multiThreadResumeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(multiThreadResumePath(key), data)
+}
+
+// writeFileAtomic writes data to path via a temporary file renamed
+// into place, so an interrupted write leaves the old file intact
+func writeFileAtomic(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// removeMultiThreadResumeState removes the resume state for key if it exists
+func removeMultiThreadResumeState(key string) {
+	err := os.Remove(multiThreadResumePath(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fs.Debugf(nil, "multi-thread copy: failed to remove resume state: %v", err)
+	}
+}
+
+// multiThreadDirResumeState is kept for each destination so a
+// directory copy interrupted with --multi-thread-resume can be resumed
+// as a whole. It records the multi-thread copies started so files
+// which were completed can be skipped and those which weren't resumed.
+//
+// It is persisted as a journal of multiThreadDirResumeRecord, one per
+// line, which is appended to as copies start and finish. It is read
+// once and then kept in memory, so each copy only costs one small
+// append, and a write cut short by a crash only loses its own record.
+type multiThreadDirResumeState struct {
+	mu    sync.Mutex
+	path  string                              // path of the journal
+	files map[string]multiThreadDirResumeFile // the copies by remote
+}
+
+// multiThreadDirResumeFile is a copy in a multiThreadDirResumeState
+type multiThreadDirResumeFile struct {
+	Key  string `json:"key"`  // resume key of the copy from multiThreadResumeKey
+	Done bool   `json:"done"` // set once the copy has completed
+}
+
+// multiThreadDirResumeRecord is a line of the directory resume journal
+type multiThreadDirResumeRecord struct {
+	Remote string `json:"remote"` // remote of the copy in the destination
+	multiThreadDirResumeFile
+}
+
+var (
+	multiThreadDirResumeMu     sync.Mutex                                // protects multiThreadDirResumeStates
+	multiThreadDirResumeStates = map[string]*multiThreadDirResumeState{} // loaded states by path
+)
+
+// path to the file storing the directory resume state for f
+func multiThreadDirResumePath(f fs.Info) string {
+	h := sha1.Sum([]byte(fs.ConfigString(f)))
+	return filepath.Join(config.GetCacheDir(), multiThreadResumeDir, "dir-"+hex.EncodeToString(h[:])+".jsonl")
+}
+
+// getMultiThreadDirResumeState returns the directory resume state for
+// f, reading it the first time it is used. If reload is set it is
+// read again.
+func getMultiThreadDirResumeState(f fs.Info, reload bool) *multiThreadDirResumeState {
+	path := multiThreadDirResumePath(f)
+	multiThreadDirResumeMu.Lock()
+	defer multiThreadDirResumeMu.Unlock()
+	state := multiThreadDirResumeStates[path]
+	if state == nil || reload {
+		state = loadMultiThreadDirResumeState(f, path)
+		multiThreadDirResumeStates[path] = state
+	}
+	return state
+}
+
+// loadMultiThreadDirResumeState reads the directory resume journal
+// for f at path, skipping any records which can't be read
+func loadMultiThreadDirResumeState(f fs.Info, path string) *multiThreadDirResumeState {
+	state := &multiThreadDirResumeState{
+		path:  path,
+		files: map[string]multiThreadDirResumeFile{},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fs.Debugf(f, "multi-thread copy: failed to read directory resume state: %v", err)
+		}
+		return state
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record multiThreadDirResumeRecord
+		err = json.Unmarshal(line, &record)
+		if err != nil || record.Remote == "" {
+			fs.Debugf(f, "multi-thread copy: ignoring corrupted directory resume record: %v", err)
+			continue
+		}
+		state.files[record.Remote] = record.multiThreadDirResumeFile
+	}
+	return state
+}
+
+// lookup returns the copy to remote and whether it was found
+func (state *multiThreadDirResumeState) lookup(remote string) (file multiThreadDirResumeFile, found bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	file, found = state.files[remote]
+	return file, found
+}
+
+// record appends the copy to remote to the journal
+func (state *multiThreadDirResumeState) record(remote string, file multiThreadDirResumeFile) error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if old, found := state.files[remote]; found && old == file {
+		return nil
+	}
+	data, err := json.Marshal(multiThreadDirResumeRecord{Remote: remote, multiThreadDirResumeFile: file})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(state.path), 0700)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(state.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	state.files[remote] = file
+	return nil
+}
+
+// updateMultiThreadDirResumeState records the copy to remote in f with
+// resume key in the directory resume state for f
+func updateMultiThreadDirResumeState(f fs.Info, remote, key string, done bool) {
+	state := getMultiThreadDirResumeState(f, false)
+	err := state.record(remote, multiThreadDirResumeFile{Key: key, Done: done})
+	if err != nil {
+		fs.Errorf(f, "multi-thread copy: failed to save directory resume state: %v", err)
+	}
+}
+
+// multiThreadResumeCompleted returns true if dst was completely
+// copied from src by a multi-thread copy recorded in the directory
+// resume state of its Fs.
+//
+// This is only used to say why a file is skipped once the usual checks
+// have found it unchanged, as dst may have been changed since.
+func multiThreadResumeCompleted(ctx context.Context, dst fs.Object, src fs.ObjectInfo) bool {
+	if dst.Size() != src.Size() {
+		return false
+	}
+	file, found := getMultiThreadDirResumeState(dst.Fs(), false).lookup(dst.Remote())
+	return found && file.Done && file.Key == multiThreadResumeKey(ctx, dst.Fs(), dst.Remote(), src)
+}
+
+// MultiThreadResumeSummary returns the number of multi-thread copies to
+// f recorded as completed and as interrupted by a previous directory
+// copy with --multi-thread-resume.
+func MultiThreadResumeSummary(f fs.Info) (completed, interrupted int) {
+	// This is called as a directory copy starts so read the state
+	// afresh in case it was changed by another rclone
+	state := getMultiThreadDirResumeState(f, true)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for _, file := range state.files {
+		if file.Done {
+			completed++
+		} else {
+			interrupted++
+		}
+	}
+	return completed, interrupted
+}
+
+// RemoveMultiThreadResumeDir removes the directory resume state for f
+// once a directory copy to it has finished without errors.
+func RemoveMultiThreadResumeDir(f fs.Info) {
+	path := multiThreadDirResumePath(f)
+	multiThreadDirResumeMu.Lock()
+	defer multiThreadDirResumeMu.Unlock()
+	delete(multiThreadDirResumeStates, path)
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fs.Debugf(f, "multi-thread copy: failed to remove directory resume state: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiThreadResumeKey(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	t1 := time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", t1, 100, true, nil, nil)
+
+	key := multiThreadResumeKey(ctx, f, "file.txt", src)
+	assert.Len(t, key, 40)
+	assert.Equal(t, key, multiThreadResumeKey(ctx, f, "file.txt", src))
+	assert.NotEqual(t, key, multiThreadResumeKey(ctx, f, "file2.txt", src))
+	changedSize := object.NewStaticObjectInfo("file.txt", t1, 101, true, nil, nil)
+	assert.NotEqual(t, key, multiThreadResumeKey(ctx, f, "file.txt", changedSize))
+	changedTime := object.NewStaticObjectInfo("file.txt", t1.Add(time.Second), 100, true, nil, nil)
+	assert.NotEqual(t, key, multiThreadResumeKey(ctx, f, "file.txt", changedTime))
+}
+
+func TestMultiThreadResumeState(t *testing.T) {
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		require.NoError(t, config.SetCacheDir(oldCacheDir))
+	}()
+
+	const key = "0123456789abcdef"
+	assert.Nil(t, loadMultiThreadResumeState(key))
+	removeMultiThreadResumeState(key)
+
+	state := &multiThreadResumeState{Remote: "file.txt", UploadID: "upload-id"}
+	require.NoError(t, saveMultiThreadResumeState(key, state))
+	assert.Equal(t, state, loadMultiThreadResumeState(key))
+
+	// Corrupted state is ignored
+	require.NoError(t, os.WriteFile(multiThreadResumePath(key), []byte("{"), 0600))
+	assert.Nil(t, loadMultiThreadResumeState(key))
+
+	removeMultiThreadResumeState(key)
+	assert.Nil(t, loadMultiThreadResumeState(key))
+}
+
+func TestMultiThreadDirResumeState(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		require.NoError(t, config.SetCacheDir(oldCacheDir))
+	}()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	t1 := time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", t1, 6, true, nil, nil)
+	dst := mockobject.New("file.txt").WithContent([]byte("potato"), mockobject.SeekModeNone)
+	dst.SetFs(f)
+	key := multiThreadResumeKey(ctx, f, "file.txt", src)
+
+	completed, interrupted := MultiThreadResumeSummary(f)
+	assert.Equal(t, 0, completed+interrupted)
+	assert.False(t, multiThreadResumeCompleted(ctx, dst, src))
+
+	updateMultiThreadDirResumeState(f, "file.txt", key, false)
+	updateMultiThreadDirResumeState(f, "file2.txt", "other", false)
+	completed, interrupted = MultiThreadResumeSummary(f)
+	assert.Equal(t, 0, completed)
+	assert.Equal(t, 2, interrupted)
+	assert.False(t, multiThreadResumeCompleted(ctx, dst, src))
+
+	updateMultiThreadDirResumeState(f, "file.txt", key, true)
+	completed, interrupted = MultiThreadResumeSummary(f)
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 1, interrupted)
+	assert.True(t, multiThreadResumeCompleted(ctx, dst, src))
+
+	// A changed source isn't completed
+	changed := object.NewStaticObjectInfo("file.txt", t1.Add(time.Second), 6, true, nil, nil)
+	assert.False(t, multiThreadResumeCompleted(ctx, dst, changed))
+
+	// NeedTransfer still compares completed files with
+	// --multi-thread-resume so a changed destination of the same
+	// size is transferred
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadResume = true
+	srcObj := mockobject.New("file.txt").WithContent([]byte("potato"), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, srcObj)
+	require.NoError(t, srcObj.SetModTime(ctx, t1))
+	updateMultiThreadDirResumeState(f, "file.txt", multiThreadResumeKey(ctx, f, "file.txt", srcObj), true)
+	require.NoError(t, dst.SetModTime(ctx, t1.Add(time.Hour)))
+	assert.True(t, NeedTransfer(ctx, dst, srcObj))
+
+	// An unchanged one is skipped as usual
+	require.NoError(t, dst.SetModTime(ctx, t1))
+	assert.False(t, NeedTransfer(ctx, dst, srcObj))
+
+	RemoveMultiThreadResumeDir(f)
+	completed, interrupted = MultiThreadResumeSummary(f)
+	assert.Equal(t, 0, completed+interrupted)
+	assert.False(t, NeedTransfer(ctx, dst, srcObj))
+}
+
+func TestMultiThreadDirResumeJournal(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		require.NoError(t, config.SetCacheDir(oldCacheDir))
+	}()
+	f, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	path := multiThreadDirResumePath(f)
+
+	// Each change appends one record and repeats append nothing
+	updateMultiThreadDirResumeState(f, "file.txt", "key", false)
+	updateMultiThreadDirResumeState(f, "file.txt", "key", false)
+	updateMultiThreadDirResumeState(f, "file2.txt", "key2", false)
+	updateMultiThreadDirResumeState(f, "file.txt", "key", true)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(data, []byte("\n")))
+
+	// A record cut short by a crash loses only that record
+	require.NoError(t, os.WriteFile(path, append(data, []byte(`{"remote":"file2.txt","ke`)...), 0600))
+	completed, interrupted := MultiThreadResumeSummary(f)
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 1, interrupted)
+
+	RemoveMultiThreadResumeDir(f)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,145 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// sourceSlots holds the readers which chunks of a multi-thread copy
+// read the source with for --multi-thread-reuse-reader.
+//
+// Each chunk takes a slot while it runs and gives it back when it is
+// done so a slot is used by one chunk at once. There are never more
+// slots than chunks running at once, so each is in effect tied to one
+// of the streams of the copy rather than to a chunk, and the chunks
+// which run on that stream one after another share its reader.
+type sourceSlots struct {
+	ctx    context.Context
+	src    fs.Object
+	mu     sync.Mutex
+	free   []*sourceSlot
+	closed bool
+}
+
+// newSourceSlots returns the slots for reading src, or nil if src
+// can't be read with ReadAt so each chunk should open it as usual.
+func newSourceSlots(ctx context.Context, src fs.Object) *sourceSlots {
+	if _, ok := src.(fs.OpenReaderAter); !ok {
+		fs.Debugf(src, "multi-thread copy: not reusing source readers as the source doesn't support ReadAt")
+		return nil
+	}
+	fs.Debugf(src, "multi-thread copy: reusing a source reader for each stream")
+	return &sourceSlots{
+		ctx: ctx,
+		src: src,
+	}
+}
+
+// acquire returns the object for a chunk to open the source with and
+// a function to give it back when the chunk is done.
+//
+// This is mc.src if slots is nil.
+func (slots *sourceSlots) acquire(src fs.Object) (fs.Object, func()) {
+	if slots == nil {
+		return src, func() {}
+	}
+	slots.mu.Lock()
+	var slot *sourceSlot
+	if n := len(slots.free); n > 0 {
+		slot = slots.free[n-1]
+		slots.free = slots.free[:n-1]
+	} else {
+		slot = &sourceSlot{Object: slots.src, slots: slots}
+	}
+	slots.mu.Unlock()
+	return slot, func() {
+		slots.mu.Lock()
+		defer slots.mu.Unlock()
+		if slots.closed {
+			slot.close()
+			return
+		}
+		slots.free = append(slots.free, slot)
+	}
+}
+
+// close closes the readers of the free slots. Slots given back after
+// this are closed as they are given back.
+func (slots *sourceSlots) close() {
+	if slots == nil {
+		return
+	}
+	slots.mu.Lock()
+	defer slots.mu.Unlock()
+	slots.closed = true
+	for _, slot := range slots.free {
+		slot.close()
+	}
+	slots.free = nil
+}
+
+// sourceSlot is the source as seen by the chunks using one slot.
+//
+// It opens the source with ReadAt the first time it is opened and
+// serves ranges from that reader after, so opening it again for the
+// next chunk or after a read error doesn't open a new connection.
+type sourceSlot struct {
+	fs.Object
+	slots    *sourceSlots
+	readerAt fs.ReaderAtCloser // nil until first opened
+	fallback bool              // set if ReadAt couldn't be used
+}
+
+// Open returns a reader for the range of the source in options
+//
+// If the options can't be served from the reader it opens the source
+// as usual.
+func (slot *sourceSlot) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(slot.Size())
+		default:
+			// Options like --header-download need a fresh request
+			return slot.Object.Open(ctx, options...)
+		}
+	}
+	if slot.fallback {
+		return slot.Object.Open(ctx, options...)
+	}
+	if slot.readerAt == nil {
+		readerAt, err := slot.Object.(fs.OpenReaderAter).OpenReaderAt(slot.slots.ctx)
+		if errors.Is(err, fs.ErrorNotImplemented) {
+			fs.Debugf(slot.Object, "multi-thread copy: not reusing source reader: %v", err)
+			slot.fallback = true
+			return slot.Object.Open(ctx, options...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		slot.readerAt = readerAt
+	}
+	if limit < 0 {
+		limit = slot.Size() - offset
+	}
+	return io.NopCloser(io.NewSectionReader(slot.readerAt, offset, limit)), nil
+}
+
+// close closes the reader of the slot if it was opened
+func (slot *sourceSlot) close() {
+	if slot.readerAt == nil {
+		return
+	}
+	err := slot.readerAt.Close()
+	if err != nil {
+		fs.Debugf(slot.Object, "multi-thread copy: failed to close source reader: %v", err)
+	}
+	slot.readerAt = nil
+}
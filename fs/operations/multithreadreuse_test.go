@@ -0,0 +1,102 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readerAtObject is a source which can be read with ReadAt counting
+// how it is opened
+type readerAtObject struct {
+	*mockobject.ContentMockObject
+	content      []byte
+	opens        atomic.Int32
+	openReaderAt atomic.Int32
+	closes       atomic.Int32
+}
+
+// Open counts the opens of the object
+func (o *readerAtObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.opens.Add(1)
+	return o.ContentMockObject.Open(ctx, options...)
+}
+
+// OpenReaderAt counts the opens of the object with ReadAt
+func (o *readerAtObject) OpenReaderAt(ctx context.Context) (fs.ReaderAtCloser, error) {
+	o.openReaderAt.Add(1)
+	return &countCloseReaderAt{ReaderAt: bytes.NewReader(o.content), closes: &o.closes}, nil
+}
+
+// countCloseReaderAt counts the times it is closed
+type countCloseReaderAt struct {
+	io.ReaderAt
+	closes *atomic.Int32
+}
+
+// Close counts the close
+func (r *countCloseReaderAt) Close() error {
+	r.closes.Add(1)
+	return nil
+}
+
+func TestMultithreadCopyReuseReader(t *testing.T) {
+	const remote = "file.txt"
+	for _, test := range []struct {
+		name             string
+		reuse            bool
+		streams          int
+		wantOpens        int32
+		wantOpenReaderAt int32
+	}{
+		{name: "Off", streams: 1, wantOpens: 4},
+		{name: "Serial", reuse: true, streams: 1, wantOpenReaderAt: 1},
+		{name: "Parallel", reuse: true, streams: 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			ci.MultiThreadChunkSize = 25
+			ci.MultiThreadStreams = test.streams
+			ci.MultiThreadReuseReader = test.reuse
+			data := []byte(random.String(100))
+			content := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+			setTestSrcFs(ctx, t, content)
+			src := &readerAtObject{ContentMockObject: content, content: data}
+			f, err := mockfs.NewFs(ctx, "potato", "", nil)
+			require.NoError(t, err)
+			w := &fileWriterAt{f: f.(*mockfs.Fs), remote: remote}
+			f.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+				return w, nil
+			}
+
+			tr := accounting.GlobalStats().NewTransfer(src, nil)
+			defer tr.Done(ctx, nil)
+			dst, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, test.streams, tr)
+			require.NoError(t, err)
+			require.NotNil(t, dst)
+			assert.Equal(t, int64(100), result.Bytes)
+			assert.Equal(t, string(data), string(w.buf))
+			assert.Equal(t, test.wantOpens, src.opens.Load())
+			if test.streams == 1 {
+				assert.Equal(t, test.wantOpenReaderAt, src.openReaderAt.Load())
+			} else {
+				// Never more readers than streams
+				opened := src.openReaderAt.Load()
+				assert.True(t, opened >= 1 && opened <= int32(test.streams), "opened %d readers", opened)
+			}
+			// All the readers are closed when the copy is done
+			assert.Equal(t, src.openReaderAt.Load(), src.closes.Load())
+		})
+	}
+}
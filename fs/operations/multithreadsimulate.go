@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// errSimulatedFailure is returned for chunks failed by
+// --multi-thread-simulate-failure
+var errSimulatedFailure = errors.New("simulated failure from --multi-thread-simulate-failure")
+
+// simulatedFailures says which chunks copyChunk should fail for the
+// hidden --multi-thread-simulate-failure debugging flag which is used
+// to exercise the retry, fallback and abort paths.
+type simulatedFailures struct {
+	chunks      map[int]bool // chunk numbers which always fail
+	probability float64      // probability any chunk fails
+}
+
+// parseSimulatedFailures parses the --multi-thread-simulate-failure
+// spec which is a comma separated list of chunk numbers starting from
+// 0 to fail and optionally p=X to fail any chunk with probability X.
+//
+// An empty spec returns nil which fails no chunks.
+func parseSimulatedFailures(spec string) (*simulatedFailures, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	s := &simulatedFailures{chunks: map[int]bool{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if p, ok := strings.CutPrefix(item, "p="); ok {
+			probability, err := strconv.ParseFloat(p, 64)
+			if err != nil || probability < 0 || probability > 1 {
+				return nil, fmt.Errorf("multi-thread copy: invalid probability %q in --multi-thread-simulate-failure", p)
+			}
+			s.probability = probability
+			continue
+		}
+		chunk, err := strconv.Atoi(item)
+		if err != nil || chunk < 0 {
+			return nil, fmt.Errorf("multi-thread copy: invalid chunk number %q in --multi-thread-simulate-failure", item)
+		}
+		s.chunks[chunk] = true
+	}
+	return s, nil
+}
+
+// fail returns an error if chunk should be failed
+func (s *simulatedFailures) fail(chunk int) error {
+	if s == nil {
+		return nil
+	}
+	if s.chunks[chunk] || (s.probability > 0 && rand.Float64() < s.probability) {
+		return fmt.Errorf("multi-thread copy: chunk %d: %w", chunk+1, errSimulatedFailure)
+	}
+	return nil
+}
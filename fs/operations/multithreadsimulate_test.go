@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadParseSimulatedFailures(t *testing.T) {
+	for _, test := range []struct {
+		spec    string
+		want    *simulatedFailures
+		wantErr string
+	}{
+		{spec: "", want: nil},
+		{spec: "3", want: &simulatedFailures{chunks: map[int]bool{3: true}}},
+		{spec: "0, 2,5", want: &simulatedFailures{chunks: map[int]bool{0: true, 2: true, 5: true}}},
+		{spec: "p=0.25", want: &simulatedFailures{chunks: map[int]bool{}, probability: 0.25}},
+		{spec: "1,p=1", want: &simulatedFailures{chunks: map[int]bool{1: true}, probability: 1}},
+		{spec: "potato", wantErr: "invalid chunk number"},
+		{spec: "-1", wantErr: "invalid chunk number"},
+		{spec: "p=2", wantErr: "invalid probability"},
+		{spec: "p=x", wantErr: "invalid probability"},
+	} {
+		got, err := parseSimulatedFailures(test.spec)
+		if test.wantErr != "" {
+			require.Error(t, err, test.spec)
+			assert.Contains(t, err.Error(), test.wantErr, test.spec)
+			continue
+		}
+		require.NoError(t, err, test.spec)
+		assert.Equal(t, test.want, got, test.spec)
+	}
+
+	var none *simulatedFailures
+	assert.NoError(t, none.fail(0))
+	s := &simulatedFailures{chunks: map[int]bool{1: true}}
+	assert.NoError(t, s.fail(0))
+	assert.ErrorIs(t, s.fail(1), errSimulatedFailure)
+	s.probability = 1
+	assert.ErrorIs(t, s.fail(0), errSimulatedFailure)
+}
+
+func TestMultithreadCopySimulateFailure(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(100)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &failChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote, last: -1}, failAt: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	ci.MultiThreadSimulateFailure = "2"
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errSimulatedFailure), err)
+	assert.True(t, w.aborted.Load())
+	assert.NotContains(t, w.order, 2)
+
+	// An invalid spec isn't retried
+	ci.MultiThreadSimulateFailure = "potato"
+	_, err = multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.Error(t, err)
+	assert.False(t, fserrors.IsRetryError(err))
+	assert.True(t, fserrors.IsNoRetryError(err))
+}
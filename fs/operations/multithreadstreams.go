@@ -0,0 +1,47 @@
+package operations
+
+import "sync"
+
+// multiThreadStreams counts the streams in use by all the multi-thread
+// copies so they can be limited by --multi-thread-total-streams
+var multiThreadStreams streamBudget
+
+// streamBudget shares a total number of streams between multi-thread
+// copies running at the same time
+type streamBudget struct {
+	mu    sync.Mutex
+	inUse int
+}
+
+// reserve reserves up to want streams out of total, returning how
+// many were reserved. This is at least 1 so each copy can make
+// progress. A total <= 0 means there is no limit.
+//
+// Return the streams with release when done.
+func (b *streamBudget) reserve(total, want int) (reserved int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	reserved = want
+	if total > 0 && reserved > total-b.inUse {
+		reserved = total - b.inUse
+	}
+	if reserved < 1 {
+		reserved = 1
+	}
+	b.inUse += reserved
+	return reserved
+}
+
+// release returns streams reserved with reserve
+func (b *streamBudget) release(reserved int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inUse -= reserved
+}
+
+// free returns how many streams out of total aren't in use
+func (b *streamBudget) free(total int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return total - b.inUse
+}
@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadStreamBudget(t *testing.T) {
+	var b streamBudget
+
+	// Unlimited
+	assert.Equal(t, 10, b.reserve(0, 10))
+	b.release(10)
+
+	assert.Equal(t, 4, b.reserve(8, 4))
+	assert.Equal(t, 4, b.free(8))
+	assert.Equal(t, 3, b.reserve(8, 3))
+	assert.Equal(t, 1, b.reserve(8, 4))
+	assert.Equal(t, 0, b.free(8))
+
+	// Always get one stream
+	assert.Equal(t, 1, b.reserve(8, 4))
+	assert.Equal(t, -1, b.free(8))
+
+	b.release(1)
+	b.release(1)
+	b.release(3)
+	assert.Equal(t, 4, b.free(8))
+	b.release(4)
+	assert.Equal(t, 0, b.inUse)
+}
+
+func TestMultithreadCopyTotalStreams(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.MultiThreadTotalStreams = 8
+	const remote = "file.txt"
+	src := mockobject.New(remote).WithContent([]byte(random.String(200)), mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &orderChunkWriter{remote: remote, last: -1}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	// Another transfer is using most of the streams
+	reserved := multiThreadStreams.reserve(ci.MultiThreadTotalStreams, 6)
+	defer multiThreadStreams.release(reserved)
+
+	tr := accounting.GlobalStats().NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, result, err := MultiThreadCopyWithResult(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Concurrency)
+
+	// The streams are returned when the copy finishes
+	assert.Equal(t, 2, multiThreadStreams.free(ci.MultiThreadTotalStreams))
+}
@@ -0,0 +1,364 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"golang.org/x/sync/errgroup"
+)
+
+// teeDest is one of the destinations of MultiThreadTee
+type teeDest struct {
+	f      fs.Fs
+	info   fs.ChunkWriterInfo
+	writer fs.ChunkWriter
+	ratio  int           // number of destination chunks in each chunk read
+	chunks int           // number of destination chunks in the file
+	writes chan struct{} // limits the chunks being written at once to info.Concurrency
+	closed bool          // set once the writer has been closed
+}
+
+// MultiThreadTee copies src to remote on each of dsts in one pass
+// using concurrency streams, reading each chunk of the source once and
+// writing it to all the destinations at once. It returns the new
+// objects in the same order as dsts.
+//
+// This halves the reads of the source, and so its egress, compared to
+// copying it to two destinations one after the other.
+//
+// Each destination must support OpenChunkWriter or OpenWriterAt. The
+// chunks are read at the largest chunk size of the destinations and
+// the others are written in their own chunk size from it, so each
+// chunk size must divide the largest one. A destination whose chunk
+// size doesn't is asked for the largest one instead, and the copy
+// fails if it can't use it.
+//
+// Each chunk read is held in memory until it has been written to all
+// the destinations, so this uses up to concurrency times the largest
+// chunk size of memory. The chunks written to each destination at once
+// are limited to its concurrency and --multi-thread-max-goroutines.
+//
+// If the copy fails the uploads to all the destinations are aborted,
+// except for those which have already been finalized.
+func MultiThreadTee(ctx context.Context, dsts []fs.Fs, remote string, src fs.Object, concurrency int, options ...fs.OpenOption) (newDsts []fs.Object, err error) {
+	ci := fs.GetConfig(ctx)
+	if len(dsts) == 0 {
+		return nil, errors.New("multi-thread tee: no destinations")
+	}
+	size := src.Size()
+	if size < 0 {
+		return nil, errors.New("multi-thread tee: can't copy unknown sized file")
+	}
+	if size == 0 {
+		return nil, errors.New("multi-thread tee: can't copy zero sized file")
+	}
+	for _, f := range dsts {
+		err = checkMultiThreadOverwrite(ctx, f, remote)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tr := accounting.Stats(ctx).NewTransfer(src, nil)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+
+	outs := make([]*teeDest, len(dsts))
+	defer func() {
+		if err == nil {
+			return
+		}
+		// ctx may be cancelled so use a fresh one so the abort
+		// can still reach the backends
+		abortCtx, abortCancel := context.WithTimeout(fs.CopyConfig(context.Background(), ctx), abortTimeout)
+		defer abortCancel()
+		for _, out := range outs {
+			if out != nil && !out.closed {
+				abortTeeDest(abortCtx, src, out)
+			}
+		}
+	}()
+	for i, f := range dsts {
+		outs[i], err = openTeeDest(ctx, f, remote, src, 0, options...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Read at the largest chunk size and make sure the others fit
+	// into it, asking for the largest if they don't
+	var readSize int64
+	for _, out := range outs {
+		if out.info.ChunkSize > readSize {
+			readSize = out.info.ChunkSize
+		}
+	}
+	for i, out := range outs {
+		if readSize%out.info.ChunkSize != 0 {
+			fs.Debugf(src, "multi-thread tee: chunk size %v of %v doesn't divide %v so asking for %v", fs.SizeSuffix(out.info.ChunkSize), out.f, fs.SizeSuffix(readSize), fs.SizeSuffix(readSize))
+			outs[i] = nil
+			abortTeeDest(ctx, src, out)
+			outs[i], err = openTeeDest(ctx, out.f, remote, src, readSize, options...)
+			if err != nil {
+				return nil, err
+			}
+			out = outs[i]
+			if readSize%out.info.ChunkSize != 0 {
+				return nil, fserrors.NoRetryError(fmt.Errorf("multi-thread tee: chunk size %v of %v doesn't divide chunk size %v of the other destinations", fs.SizeSuffix(out.info.ChunkSize), out.f, fs.SizeSuffix(readSize)))
+			}
+		}
+		out.ratio = int(readSize / out.info.ChunkSize)
+		out.chunks = calculateNumChunks(size, out.info.ChunkSize)
+	}
+
+	numChunks := calculateNumChunks(size, readSize)
+	if concurrency > numChunks {
+		concurrency = numChunks
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	// Limit the writes to each destination to its concurrency as
+	// each read is written in several chunks at once
+	for _, out := range outs {
+		writes := out.info.Concurrency
+		if writes <= 0 {
+			writes = concurrency
+		}
+		out.writes = make(chan struct{}, writes)
+	}
+	tr.SetStreams(concurrency)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	mc := &multiThreadCopyState{
+		ctx:       ctx,
+		size:      size,
+		src:       src,
+		partSize:  readSize,
+		numChunks: numChunks,
+		clock:     getMultiThreadClock(ctx),
+	}
+	for _, option := range ci.DownloadHeaders {
+		mc.openOptions = append(mc.openOptions, option)
+	}
+	// gCtx is cancelled once g.Wait returns so account with ctx as
+	// the final chunk may be copied after that
+	mc.acc = multiThreadAccount(ctx, src, tr)
+
+	// If any destination needs the final chunk written last then
+	// hold it back until all the others have been written
+	lastChunk := numChunks
+	for _, out := range outs {
+		if out.info.FinalChunkLast {
+			lastChunk = numChunks - 1
+		}
+	}
+
+	fs.Debugf(src, "Starting multi-thread tee to %d destinations with %v chunks of size %v with %v parallel streams", len(outs), fs.LogValue("total", numChunks), fs.LogValue("chunkSize", fs.SizeSuffix(readSize)), fs.LogValue("streams", concurrency))
+	for chunk := 0; chunk < lastChunk; chunk++ {
+		// Fail fast, in case an errgroup managed function returns an error
+		if gCtx.Err() != nil {
+			break
+		}
+		chunk := chunk
+		g.Go(func() error {
+			return mc.teeChunk(gCtx, chunk, outs)
+		})
+	}
+	err = g.Wait()
+	if err == nil && lastChunk < numChunks {
+		// gCtx is cancelled now so copy the final chunk with ctx
+		err = mc.teeChunk(ctx, lastChunk, outs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newDsts = make([]fs.Object, len(outs))
+	for i, out := range outs {
+		newDsts[i], err = closeTeeDest(ctx, out, remote, src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fs.Debugf(src, "Finished multi-thread tee to %d destinations with %v chunks", len(outs), fs.LogValue("total", numChunks))
+	return newDsts, nil
+}
+
+// openTeeDest opens a chunk writer on f for remote, asking for
+// chunkSize if it is set
+func openTeeDest(ctx context.Context, f fs.Fs, remote string, src fs.Object, chunkSize int64, options ...fs.OpenOption) (*teeDest, error) {
+	ci := fs.GetConfig(ctx)
+	openChunkWriter := f.Features().OpenChunkWriter
+	if openChunkWriter == nil {
+		openWriterAt := f.Features().OpenWriterAt
+		if openWriterAt == nil {
+			return nil, fmt.Errorf("multi-thread tee: neither OpenChunkWriter nor OpenWriterAt supported by %v", f)
+		}
+		if chunkSize <= 0 {
+			chunkSize = int64(ci.MultiThreadChunkSize)
+		}
+		openChunkWriter = openChunkWriterFromOpenWriterAt(openWriterAt, chunkSize, int64(ci.MultiThreadWriteBufferSize), f)
+	} else if chunkSize > 0 {
+		options = append(options[:len(options):len(options)], &fs.ChunkOption{ChunkSize: chunkSize})
+	}
+	info, writer, err := openChunkWriter(ctx, remote, src, options...)
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread tee: failed to open chunk writer on %v: %w", f, err)
+	}
+	out := &teeDest{f: f, info: info, writer: writer}
+	if info.ChunkSize <= 0 {
+		abortTeeDest(ctx, src, out)
+		return nil, fmt.Errorf("multi-thread tee: %v returned invalid chunk size %d", f, info.ChunkSize)
+	}
+	return out, nil
+}
+
+// abortTeeDest aborts the upload to out, logging any error
+func abortTeeDest(ctx context.Context, src fs.Object, out *teeDest) {
+	err := out.writer.Abort(ctx)
+	if err != nil {
+		fs.Debugf(src, "multi-thread tee: abort on %v failed: %v", out.f, err)
+	}
+}
+
+// teeChunk reads chunk from the source once and writes it to each of
+// outs in their own chunk size
+func (mc *multiThreadCopyState) teeChunk(ctx context.Context, chunk int, outs []*teeDest) (err error) {
+	start, end := chunkRange(chunk, mc.size, 0, mc.partSize)
+	openOptions := append(mc.openOptions[:len(mc.openOptions):len(mc.openOptions)], &fs.RangeOption{Start: start, End: end - 1})
+	rc, err := Open(ctx, mc.src, openOptions...)
+	if err != nil {
+		return fmt.Errorf("multi-thread tee: failed to open source: %w", err)
+	}
+	buf := make([]byte, end-start)
+	_, err = io.ReadFull(rc, buf)
+	fs.CheckClose(rc, &err)
+	if err != nil {
+		return fmt.Errorf("multi-thread tee: failed to read chunk %d: %w", chunk+1, err)
+	}
+	err = mc.acc.AccountRead(len(buf))
+	if err != nil {
+		return err
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	written := make([]sync.WaitGroup, len(outs))
+	type finalPart struct {
+		out         int
+		chunkNumber int
+		part        []byte
+	}
+	var finals []finalPart
+dispatch:
+	for o, out := range outs {
+		for i := 0; i < out.ratio; i++ {
+			partStart := int64(i) * out.info.ChunkSize
+			if partStart >= int64(len(buf)) {
+				break
+			}
+			partEnd := partStart + out.info.ChunkSize
+			if partEnd > int64(len(buf)) {
+				partEnd = int64(len(buf))
+			}
+			part := buf[partStart:partEnd]
+			chunkNumber := chunk*out.ratio + i
+			// Hold back the final chunk of a destination which
+			// needs it written last
+			if out.info.FinalChunkLast && chunkNumber == out.chunks-1 {
+				finals = append(finals, finalPart{out: o, chunkNumber: chunkNumber, part: part})
+				continue
+			}
+			err = goTeeWrite(gCtx, g, out, chunkNumber, part, &written[o])
+			if err != nil {
+				break dispatch
+			}
+		}
+	}
+	for _, final := range finals {
+		if err != nil {
+			break
+		}
+		fs.Debugf(mc.src, "multi-thread tee: waiting for preceding chunks to be written to %v before writing the final chunk", outs[final.out].f)
+		written[final.out].Wait()
+		err = goTeeWrite(gCtx, g, outs[final.out], final.chunkNumber, final.part, &written[final.out])
+	}
+	if waitErr := g.Wait(); waitErr != nil {
+		err = waitErr
+	}
+	if err != nil {
+		return err
+	}
+	fs.Debugf(mc.src, "multi-thread tee: chunk %v/%v (%v-%v) size %v finished", fs.LogValue("chunk", chunk+1), fs.LogValue("total", mc.numChunks), start, end, fs.SizeSuffix(end-start))
+	return nil
+}
+
+// goTeeWrite writes part to out as chunkNumber in a goroutine of g
+// once out has a free write stream and --multi-thread-max-goroutines
+// allows it. written is done when the write has finished.
+func goTeeWrite(ctx context.Context, g *errgroup.Group, out *teeDest, chunkNumber int, part []byte, written *sync.WaitGroup) error {
+	err := acquireStream(ctx, out.writes)
+	if err != nil {
+		return err
+	}
+	releaseGoroutine, err := multiThreadGoroutines.acquire(ctx, fs.GetConfig(ctx).MultiThreadMaxGoroutines)
+	if err != nil {
+		releaseStream(out.writes)
+		return err
+	}
+	written.Add(1)
+	g.Go(func() error {
+		defer written.Done()
+		defer releaseGoroutine()
+		defer releaseStream(out.writes)
+		n, err := out.writer.WriteChunk(ctx, chunkNumber, bytes.NewReader(part))
+		if err != nil {
+			return fmt.Errorf("multi-thread tee: failed to write chunk %d to %v: %w", chunkNumber+1, out.f, err)
+		}
+		if n != int64(len(part)) {
+			return fmt.Errorf("multi-thread tee: wrote %d bytes of chunk %d to %v but expected %d", n, chunkNumber+1, out.f, len(part))
+		}
+		return nil
+	})
+	return nil
+}
+
+// closeTeeDest finalizes the upload to out returning the new object
+func closeTeeDest(ctx context.Context, out *teeDest, remote string, src fs.Object) (fs.Object, error) {
+	ci := fs.GetConfig(ctx)
+	err := closeChunkWriter(ctx, out.writer, ci.MultiThreadFinalizeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	out.closed = true
+	obj, err := out.f.NewObject(ctx, remote)
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread tee: failed to find object on %v after copy: %w", out.f, err)
+	}
+	if obj.Size() >= 0 && obj.Size() != src.Size() {
+		if removeErr := obj.Remove(ctx); removeErr != nil {
+			fs.Errorf(obj, "multi-thread tee: failed to remove wrongly sized object: %v", removeErr)
+		}
+		return nil, fmt.Errorf("multi-thread tee: %v is %d bytes after finalizing but source is %d bytes", out.f, obj.Size(), src.Size())
+	}
+	if out.info.MetadataAfterClose {
+		// Wrapping backends may wrap the errors saying the
+		// modification time can't be set
+		err = obj.SetModTime(ctx, src.ModTime(ctx))
+		if errors.Is(err, fs.ErrorCantSetModTime) || errors.Is(err, fs.ErrorCantSetModTimeWithoutDelete) {
+			fs.Debugf(obj, "multi-thread tee: can't set modification time: %v", err)
+		} else if err != nil {
+			return nil, fmt.Errorf("multi-thread tee: failed to set modification time: %w", err)
+		}
+	}
+	return obj, nil
+}
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// teeChunkWriter is a fs.ChunkWriter which keeps the chunks it is
+// given and puts them together into an object on Close
+type teeChunkWriter struct {
+	f           *mockfs.Fs
+	remote      string
+	delay       time.Duration // time each chunk takes to write
+	mu          sync.Mutex
+	chunks      map[int][]byte
+	order       []int // chunk numbers in the order they were written
+	inFlight    int   // chunks being written now
+	maxInFlight int   // most chunks written at once
+	aborted     bool
+}
+
+func (w *teeChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	w.mu.Lock()
+	w.inFlight++
+	if w.inFlight > w.maxInFlight {
+		w.maxInFlight = w.inFlight
+	}
+	w.mu.Unlock()
+	time.Sleep(w.delay)
+	data, err := io.ReadAll(reader)
+	w.mu.Lock()
+	w.inFlight--
+	w.chunks[chunkNumber] = data
+	w.order = append(w.order, chunkNumber)
+	w.mu.Unlock()
+	return int64(len(data)), err
+}
+
+func (w *teeChunkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var numbers []int
+	for chunkNumber := range w.chunks {
+		numbers = append(numbers, chunkNumber)
+	}
+	sort.Ints(numbers)
+	var data []byte
+	for _, chunkNumber := range numbers {
+		data = append(data, w.chunks[chunkNumber]...)
+	}
+	w.f.AddObject(mockobject.New(w.remote).WithContent(data, mockobject.SeekModeNone))
+	return nil
+}
+
+func (w *teeChunkWriter) Abort(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.aborted = true
+	return nil
+}
+
+// newTeeChunkWriterFs makes a destination whose chunk writers use
+// chunkSize, or the chunk size asked for if honour is set
+func newTeeChunkWriterFs(t *testing.T, name string, chunkSize int64, honour bool) (*mockfs.Fs, *[]*teeChunkWriter) {
+	return newTeeChunkWriterFsInfo(t, name, fs.ChunkWriterInfo{ChunkSize: chunkSize, Concurrency: 4}, honour, 0)
+}
+
+// newTeeChunkWriterFsInfo makes a destination whose chunk writers
+// return info and take delay to write each chunk, using the chunk
+// size asked for if honour is set
+func newTeeChunkWriterFsInfo(t *testing.T, name string, info fs.ChunkWriterInfo, honour bool, delay time.Duration) (*mockfs.Fs, *[]*teeChunkWriter) {
+	f, err := mockfs.NewFs(context.Background(), name, "", nil)
+	require.NoError(t, err)
+	var writers []*teeChunkWriter
+	f.Features().OpenChunkWriter = func(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (fs.ChunkWriterInfo, fs.ChunkWriter, error) {
+		info := info
+		for _, option := range options {
+			if x, ok := option.(*fs.ChunkOption); ok && honour {
+				info.ChunkSize = x.ChunkSize
+			}
+		}
+		w := &teeChunkWriter{f: f.(*mockfs.Fs), remote: remote, delay: delay, chunks: map[int][]byte{}}
+		writers = append(writers, w)
+		return info, w, nil
+	}
+	return f.(*mockfs.Fs), &writers
+}
+
+func TestMultithreadTee(t *testing.T) {
+	const remote = "file.txt"
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 25
+	data := []byte(random.String(110))
+	content := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, content)
+	src := &readerAtObject{ContentMockObject: content, content: data}
+
+	// Destination written with OpenWriterAt in chunks of 25
+	writerAtFs, err := mockfs.NewFs(ctx, "potato", "", nil)
+	require.NoError(t, err)
+	writerAt := &fileWriterAt{f: writerAtFs.(*mockfs.Fs), remote: remote}
+	writerAtFs.Features().OpenWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		return writerAt, nil
+	}
+	// Destination written with OpenChunkWriter in chunks of 50
+	chunkFs, chunkWriters := newTeeChunkWriterFs(t, "carrot", 50, false)
+
+	dsts, err := MultiThreadTee(ctx, []fs.Fs{writerAtFs, chunkFs}, remote, src, 4)
+	require.NoError(t, err)
+	require.Len(t, dsts, 2)
+	for _, dst := range dsts {
+		assert.Equal(t, int64(len(data)), dst.Size())
+	}
+	assert.Equal(t, string(data), string(writerAt.buf))
+	require.Len(t, *chunkWriters, 1)
+	assert.Len(t, (*chunkWriters)[0].chunks, 3)
+
+	// The source was read once in chunks of 50
+	assert.Equal(t, int32(3), src.opens.Load())
+}
+
+func TestMultithreadTeeChunkSizes(t *testing.T) {
+	const remote = "file.txt"
+	ctx := context.Background()
+	data := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	t.Run("Reopen", func(t *testing.T) {
+		// 30 doesn't divide 40 so the first is asked for 40
+		aFs, aWriters := newTeeChunkWriterFs(t, "a", 30, true)
+		bFs, _ := newTeeChunkWriterFs(t, "b", 40, false)
+		dsts, err := MultiThreadTee(ctx, []fs.Fs{aFs, bFs}, remote, src, 2)
+		require.NoError(t, err)
+		require.Len(t, dsts, 2)
+		require.Len(t, *aWriters, 2)
+		assert.True(t, (*aWriters)[0].aborted)
+		assert.False(t, (*aWriters)[1].aborted)
+		assert.Len(t, (*aWriters)[1].chunks, 3)
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		aFs, aWriters := newTeeChunkWriterFs(t, "a", 30, false)
+		bFs, bWriters := newTeeChunkWriterFs(t, "b", 40, false)
+		_, err := MultiThreadTee(ctx, []fs.Fs{aFs, bFs}, remote, src, 2)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't divide")
+		for _, w := range append(*aWriters, *bWriters...) {
+			assert.True(t, w.aborted)
+		}
+	})
+}
+
+func TestMultithreadTeeFinalChunkLast(t *testing.T) {
+	const remote = "file.txt"
+	ctx := context.Background()
+	data := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	// b needs its final chunk written after all the others and has
+	// 5 chunks in each chunk read for a
+	aFs, aWriters := newTeeChunkWriterFs(t, "a", 50, false)
+	bFs, bWriters := newTeeChunkWriterFsInfo(t, "b", fs.ChunkWriterInfo{ChunkSize: 10, Concurrency: 8, FinalChunkLast: true}, false, 10*time.Millisecond)
+
+	dsts, err := MultiThreadTee(ctx, []fs.Fs{aFs, bFs}, remote, src, 4)
+	require.NoError(t, err)
+	require.Len(t, dsts, 2)
+	for _, dst := range dsts {
+		assert.Equal(t, int64(len(data)), dst.Size())
+	}
+	require.Len(t, *aWriters, 1)
+	assert.Len(t, (*aWriters)[0].chunks, 2)
+	require.Len(t, *bWriters, 1)
+	order := (*bWriters)[0].order
+	require.Len(t, order, 10)
+	assert.Equal(t, 9, order[len(order)-1])
+}
+
+func TestMultithreadTeeConcurrency(t *testing.T) {
+	const remote = "file.txt"
+	ctx := context.Background()
+	data := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(data, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+
+	// a is read in chunks of 50 which are written to b in 5 chunks
+	// each, but b can only write 2 chunks at once
+	aFs, _ := newTeeChunkWriterFs(t, "a", 50, false)
+	bFs, bWriters := newTeeChunkWriterFsInfo(t, "b", fs.ChunkWriterInfo{ChunkSize: 10, Concurrency: 2}, false, 10*time.Millisecond)
+
+	_, err := MultiThreadTee(ctx, []fs.Fs{aFs, bFs}, remote, src, 2)
+	require.NoError(t, err)
+	require.Len(t, *bWriters, 1)
+	assert.Len(t, (*bWriters)[0].chunks, 10)
+	assert.Equal(t, 2, (*bWriters)[0].maxInFlight)
+}
@@ -0,0 +1,118 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// reopenAt opens the source again so the next Read returns the byte
+// at offset in the chunk
+func (r *chunkReader) reopenAt(offset int64) error {
+	r.retries += r.in.Retries()
+	_ = r.in.Close()
+	openOptions := append(r.mc.openOptions[:len(r.mc.openOptions):len(r.mc.openOptions)], &fs.RangeOption{Start: r.start + offset, End: r.end - 1})
+	in, err := Open(r.ctx, r.src, openOptions...)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to open source at offset %d: %w", r.start+offset, err)
+	}
+	r.in = in
+	r.offset = offset
+	return nil
+}
+
+// chunkWindowReader is an io.ReadSeeker over a chunk which only
+// buffers a window of it at once for --multi-thread-max-read-chunk.
+//
+// Reading straight through reads the source once. Seeking outside the
+// window opens the source again at the new position.
+type chunkWindowReader struct {
+	cr       *chunkReader    // the source positioned at cr.offset
+	in       io.Reader       // reads from cr
+	size     int64           // size of the chunk
+	buf      []byte          // the window, starting at bufStart
+	bufStart int64           // offset of buf in the chunk
+	pos      int64           // offset of the next Read in the chunk
+	account  func(int) error // if set, called with the bytes read
+}
+
+// newChunkWindowReader makes a chunkWindowReader reading size bytes
+// from in, which reads from cr, in windows of window bytes
+func newChunkWindowReader(cr *chunkReader, in io.Reader, size, window int64) *chunkWindowReader {
+	return &chunkWindowReader{
+		cr:   cr,
+		in:   in,
+		size: size,
+		buf:  make([]byte, 0, window),
+	}
+}
+
+// SetAccounting sets the function called with the bytes read
+func (r *chunkWindowReader) SetAccounting(account func(int) error) {
+	r.account = account
+}
+
+// fill reads the window starting at r.pos
+func (r *chunkWindowReader) fill() error {
+	if r.pos != r.cr.offset {
+		err := r.cr.reopenAt(r.pos)
+		if err != nil {
+			return err
+		}
+	}
+	n := int64(cap(r.buf))
+	if n > r.size-r.pos {
+		n = r.size - r.pos
+	}
+	r.buf = r.buf[:n]
+	r.bufStart = r.pos
+	read, err := io.ReadFull(r.in, r.buf)
+	r.buf = r.buf[:read]
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to read chunk: %w", err)
+	}
+	return nil
+}
+
+// Read reads from the window, filling it from the source as needed
+func (r *chunkWindowReader) Read(p []byte) (n int, err error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.pos < r.bufStart || r.pos >= r.bufStart+int64(len(r.buf)) {
+		err = r.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+	window := r.buf[r.pos-r.bufStart:]
+	if len(window) > len(p) {
+		window = window[:len(p)]
+	}
+	n = len(append(p[:0], window...))
+	r.pos += int64(n)
+	if r.account != nil {
+		err = r.account(n)
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next Read
+func (r *chunkWindowReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return r.pos, errors.New("multi-thread copy: invalid whence")
+	}
+	if offset < 0 || offset > r.size {
+		return r.pos, fmt.Errorf("multi-thread copy: seek to %d outside chunk of size %d", offset, r.size)
+	}
+	r.pos = offset
+	return offset, nil
+}
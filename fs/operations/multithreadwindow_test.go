@@ -0,0 +1,121 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seekChunkWriter reads each chunk twice, seeking back to the start
+// in between like a backend retrying a part
+type seekChunkWriter struct {
+	orderChunkWriter
+	chunksMu sync.Mutex
+	chunks   map[int][]byte
+}
+
+func (w *seekChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	_, err := io.CopyN(io.Discard, reader, 3)
+	if err != nil {
+		return 0, err
+	}
+	_, err = reader.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+	w.chunksMu.Lock()
+	w.chunks[chunkNumber] = data
+	w.chunksMu.Unlock()
+	w.mu.Lock()
+	w.written += int64(len(data))
+	w.mu.Unlock()
+	return int64(len(data)), nil
+}
+
+func TestChunkWindowReader(t *testing.T) {
+	ctx := context.Background()
+	content := []byte(random.String(100))
+	src := mockobject.New("file.txt").WithContent(content, mockobject.SeekModeNone)
+	mc := &multiThreadCopyState{ctx: ctx, src: src, size: 100, numChunks: 1}
+	const start, end = 10, 60
+	rc, err := Open(ctx, src, &fs.RangeOption{Start: start, End: end - 1})
+	require.NoError(t, err)
+	cr := &chunkReader{ctx: ctx, mc: mc, src: mc.src, in: rc, start: start, end: end}
+	defer func() {
+		assert.NoError(t, cr.in.Close())
+	}()
+	r := newChunkWindowReader(cr, cr, end-start, 8)
+	var read int
+	r.SetAccounting(func(n int) error {
+		read += n
+		return nil
+	})
+
+	// Read straight through
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, content[start:end], data)
+	assert.Equal(t, end-start, read)
+
+	// Seek back outside the window
+	pos, err := r.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), pos)
+	buf := make([]byte, 10)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, content[start+5:start+15], buf)
+
+	// Seek relative to the end
+	pos, err = r.Seek(-4, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(end-start-4), pos)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, content[end-4:end], data)
+
+	// Seek out of range
+	_, err = r.Seek(end-start+1, io.SeekStart)
+	assert.Error(t, err)
+	_, err = r.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
+
+func TestMultithreadCopyMaxReadChunk(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadMaxReadChunk = 10
+	const remote = "file.txt"
+	content := []byte(random.String(100))
+	src := mockobject.New(remote).WithContent(content, mockobject.SeekModeNone)
+	setTestSrcFs(ctx, t, src)
+	w := &seekChunkWriter{orderChunkWriter: orderChunkWriter{remote: remote}, chunks: map[int][]byte{}}
+	f := newTestChunkWriterFs(ctx, t, testChunkWriterInfo, w)
+
+	tr := accounting.Stats(ctx).NewTransfer(src, nil)
+	defer tr.Done(ctx, nil)
+	_, err := multiThreadCopy(ctx, f, remote, src, 4, tr)
+	require.NoError(t, err)
+
+	// The chunks keep the part size of the backend
+	require.Len(t, w.chunks, 4)
+	var got []byte
+	for chunk := 0; chunk < 4; chunk++ {
+		assert.Len(t, w.chunks[chunk], 25)
+		got = append(got, w.chunks[chunk]...)
+	}
+	assert.True(t, bytes.Equal(content, got))
+}
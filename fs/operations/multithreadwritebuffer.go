@@ -0,0 +1,104 @@
+package operations
+
+import "io"
+
+// adaptiveWriteBufferMin is the size the write buffer starts at with
+// --multi-thread-adaptive-buffer
+const adaptiveWriteBufferMin = 4 * 1024
+
+// adaptiveBufferWriter is a buffered writer for
+// --multi-thread-adaptive-buffer whose buffer starts small and
+// doubles each time it fills, up to max.
+//
+// Writes at least as big as the buffer skip it, as with a
+// bufio.Writer, so if the chunk is written in big blocks the buffer
+// never grows and costs little memory. If it is written in small
+// blocks the buffer fills and grows until the writes to the
+// underlying writer are max bytes, as with a fixed buffer.
+type adaptiveBufferWriter struct {
+	w   io.Writer
+	buf []byte // the buffer, of len the current size
+	n   int    // bytes buffered
+	max int    // largest size the buffer can grow to
+	err error  // sticky error from w
+}
+
+// newAdaptiveBufferWriter makes an adaptiveBufferWriter writing to w
+// with a buffer growing up to max bytes
+func newAdaptiveBufferWriter(w io.Writer, max int) *adaptiveBufferWriter {
+	size := adaptiveWriteBufferMin
+	if size > max {
+		size = max
+	}
+	return &adaptiveBufferWriter{
+		w:   w,
+		buf: make([]byte, size),
+		max: max,
+	}
+}
+
+// Write buffers p writing the buffer out when it is full
+func (b *adaptiveBufferWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 && b.err == nil {
+		// Write big blocks straight through
+		if b.n == 0 && len(p) >= len(b.buf) {
+			var nw int
+			nw, b.err = b.w.Write(p)
+			return n + nw, b.err
+		}
+		nc := len(b.buf) - b.n
+		if nc > len(p) {
+			nc = len(p)
+		}
+		// append doesn't reallocate as buf has the room
+		_ = append(b.buf[:b.n], p[:nc]...)
+		b.n += nc
+		n += nc
+		p = p[nc:]
+		if b.n == len(b.buf) {
+			b.err = b.Flush()
+			b.grow()
+		}
+	}
+	return n, b.err
+}
+
+// grow doubles the size of the empty buffer up to max
+func (b *adaptiveBufferWriter) grow() {
+	if b.n != 0 || len(b.buf) >= b.max {
+		return
+	}
+	size := 2 * len(b.buf)
+	if size > b.max {
+		size = b.max
+	}
+	b.buf = make([]byte, size)
+}
+
+// Flush writes any buffered data to the underlying writer
+func (b *adaptiveBufferWriter) Flush() error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.n == 0 {
+		return nil
+	}
+	n, err := b.w.Write(b.buf[:b.n])
+	if err == nil && n < b.n {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		// Keep the data not written so Flush can report it
+		_ = append(b.buf[:0], b.buf[n:b.n]...)
+		b.n -= n
+		b.err = err
+		return err
+	}
+	b.n = 0
+	return nil
+}
+
+// Size returns the current size of the buffer
+func (b *adaptiveBufferWriter) Size() int {
+	return len(b.buf)
+}
@@ -0,0 +1,147 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countWriterAt is a WriterAt which counts the calls to WriteAt
+type countWriterAt struct {
+	memWriterAt
+	writes atomic.Int64
+}
+
+func (w *countWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.writes.Add(1)
+	return w.memWriterAt.WriteAt(p, off)
+}
+
+// blockReader reads from r in blocks of at most size bytes
+type blockReader struct {
+	r    io.Reader
+	size int
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	if len(p) > r.size {
+		p = p[:r.size]
+	}
+	return r.r.Read(p)
+}
+
+// blockReadSeeker makes a ReadSeeker over data read in blocks of
+// blockSize, hiding the WriterTo of the bytes.Reader
+func blockReadSeeker(data []byte, blockSize int) io.ReadSeeker {
+	return struct {
+		io.Reader
+		io.Seeker
+	}{&blockReader{r: bytes.NewReader(data), size: blockSize}, nil}
+}
+
+func TestAdaptiveBufferWriter(t *testing.T) {
+	for _, blockSize := range []int{1, 100, 4096, 100000} {
+		t.Run(fmt.Sprintf("Block=%d", blockSize), func(t *testing.T) {
+			data := []byte(random.String(300000))
+			var out bytes.Buffer
+			w := newAdaptiveBufferWriter(&out, 64*1024)
+			n, err := io.Copy(w, &blockReader{r: bytes.NewReader(data), size: blockSize})
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			assert.Equal(t, int64(len(data)), n)
+			assert.Equal(t, data, out.Bytes())
+			if blockSize < adaptiveWriteBufferMin {
+				// Small writes grow the buffer to the max
+				assert.Equal(t, 64*1024, w.Size())
+			} else {
+				// Big writes skip the buffer so it stays small
+				assert.Equal(t, adaptiveWriteBufferMin, w.Size())
+			}
+		})
+	}
+
+	// The buffer doesn't start bigger than the max
+	w := newAdaptiveBufferWriter(io.Discard, 100)
+	assert.Equal(t, 100, w.Size())
+}
+
+// errWriter fails every write
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestAdaptiveBufferWriterError(t *testing.T) {
+	w := newAdaptiveBufferWriter(errWriter{}, 64*1024)
+	_, err := w.Write(make([]byte, 100))
+	require.NoError(t, err)
+	err = w.Flush()
+	assert.EqualError(t, err, "write failed")
+	// The error sticks
+	_, err = w.Write(make([]byte, 100))
+	assert.EqualError(t, err, "write failed")
+}
+
+func TestMultithreadWriterAtAdaptiveBuffer(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(random.String(200000))
+	writerAt := &countWriterAt{}
+	w := newTestWriterAtChunkWriter(int64(len(data)), int64(len(data)), writerAt)
+	w.writeBufferSize = 64 * 1024
+	w.adaptiveBuffer = true
+	n, err := w.WriteChunk(ctx, 0, blockReadSeeker(data, 512))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, data, writerAt.buf)
+	// 4k+8k+16k+32k then 64k writes
+	assert.Equal(t, int64(7), writerAt.writes.Load())
+}
+
+// BenchmarkMultithreadWriteBuffer compares the WriteAt calls (the
+// syscalls for a file) and memory used writing a chunk with the
+// fixed and adaptive write buffers for small and big reads from the
+// source.
+func BenchmarkMultithreadWriteBuffer(b *testing.B) {
+	const chunkSize = 4 << 20
+	data := []byte(random.String(chunkSize))
+	for _, blockSize := range []int{512, 32 * 1024, 1 << 20} {
+		for _, test := range []struct {
+			name       string
+			bufferSize int64
+			adaptive   bool
+		}{
+			{name: "None"},
+			{name: "Fixed4k", bufferSize: 4 * 1024},
+			{name: "Fixed1M", bufferSize: 1 << 20},
+			{name: "Adaptive1M", bufferSize: 1 << 20, adaptive: true},
+		} {
+			b.Run(fmt.Sprintf("Read=%d/%s", blockSize, test.name), func(b *testing.B) {
+				ctx := context.Background()
+				writerAt := &countWriterAt{}
+				writerAt.buf = make([]byte, chunkSize)
+				w := newTestWriterAtChunkWriter(chunkSize, chunkSize, writerAt)
+				w.writeBufferSize = test.bufferSize
+				w.adaptiveBuffer = test.adaptive
+				b.ReportAllocs()
+				b.SetBytes(chunkSize)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := w.WriteChunk(ctx, 0, blockReadSeeker(data, blockSize))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(writerAt.writes.Load())/float64(b.N), "writes/op")
+			})
+		}
+	}
+}
@@ -1738,7 +1738,11 @@ func NeedTransfer(ctx context.Context, dst, src fs.Object) bool {
 			return !equalFn(ctx, src, dst)
 		}
 		if Equal(ctx, src, dst) && !SameObject(src, dst) {
-			fs.Debugf(src, "Unchanged skipping")
+			if ci.MultiThreadResume && multiThreadResumeCompleted(ctx, dst, src) {
+				fs.Debugf(src, "Unchanged skipping - completed by an interrupted resumable multi-thread copy")
+			} else {
+				fs.Debugf(src, "Unchanged skipping")
+			}
 			return false
 		}
 	}
@@ -1767,6 +1771,18 @@ func RcatSize(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadClo
 		}
 
 		info := object.NewStaticObjectInfo(dstFileName, modTime, size, true, nil, fdst).WithMetadata(meta)
+		if doMultiThreadRcat(ctx, fdst, size) {
+			obj, err = multiThreadRcat(ctx, fdst, in, info)
+			if err == nil {
+				return obj, nil
+			}
+			if !errors.Is(err, fs.ErrorCantMultiThread) {
+				fs.Errorf(dstFileName, "Post request multi-thread rcat error: %v", err)
+				return nil, err
+			}
+			// Nothing has been read from in yet so fall back to Put
+			fs.Debugf(dstFileName, "multi-thread rcat: falling back to a single stream: %v", err)
+		}
 		obj, err = fdst.Put(ctx, in, info)
 		if err != nil {
 			fs.Errorf(dstFileName, "Post request put error: %v", err)
@@ -162,8 +162,16 @@ func init() {
 	for _, copy := range []bool{false, true} {
 		copy := copy
 		name := "Move"
+		help := ""
 		if copy {
 			name = "Copy"
+			help = `- manifest - boolean, set to true to return the checksums of the chunks of a multi-thread copy
+
+If manifest is set the result has a "manifest" with the chunks the
+multi-thread copy wrote with their offsets, lengths and checksums, as
+written by --multi-thread-manifest. It is null if the file was copied
+with a single stream.
+`
 		}
 		rc.Add(rc.Call{
 			Path:         "operations/" + strings.ToLower(name) + "file",
@@ -178,7 +186,7 @@ func init() {
 - srcRemote - a path within that remote e.g. "file.txt" for the source
 - dstFs - a remote name string e.g. "drive2:" for the destination, "/" for local filesystem
 - dstRemote - a path within that remote e.g. "file2.txt" for the destination
-`,
+` + help,
 		})
 	}
 }
@@ -193,7 +201,28 @@ func rcMoveOrCopyFile(ctx context.Context, in rc.Params, cp bool) (out rc.Params
 	if err != nil {
 		return nil, err
 	}
-	return nil, moveOrCopyFile(ctx, dstFs, srcFs, dstRemote, srcRemote, cp)
+	if !cp {
+		return nil, moveOrCopyFile(ctx, dstFs, srcFs, dstRemote, srcRemote, cp)
+	}
+	wantManifest, err := in.GetBool("manifest")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if !wantManifest {
+		return nil, moveOrCopyFile(ctx, dstFs, srcFs, dstRemote, srcRemote, cp)
+	}
+	ctx, getManifest := WithMultiThreadManifest(ctx)
+	err = moveOrCopyFile(ctx, dstFs, srcFs, dstRemote, srcRemote, cp)
+	if err != nil {
+		return nil, err
+	}
+	manifest := getManifest()
+	if manifest != nil {
+		// The copy may have been written to a partial name
+		// before being renamed into place
+		manifest.Remote = dstRemote
+	}
+	return rc.Params{"manifest": manifest}, nil
 }
 
 func init() {
@@ -2,6 +2,7 @@ package operations_test
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -20,6 +21,7 @@ import (
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/diskusage"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/rest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -95,6 +97,47 @@ func TestRcCopyfile(t *testing.T) {
 	r.CheckRemoteItems(t, file1)
 }
 
+func TestRcCopyfileManifest(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadCutoff = 1
+	ci.MultiThreadStreams = 2
+	ci.MultiThreadSet = true
+	ci.MultiThreadChunkSize = 25
+	r, call := rcNewRun(t, "operations/copyfile")
+	contents := random.String(100)
+	file1 := r.WriteFile("file1", contents, t1)
+	r.Mkdir(ctx, r.Fremote)
+
+	in := rc.Params{
+		"srcFs":     r.LocalName,
+		"srcRemote": "file1",
+		"dstFs":     r.FremoteName,
+		"dstRemote": "file1",
+		"manifest":  true,
+	}
+	out, err := call.Fn(ctx, in)
+	require.NoError(t, err)
+	manifest, ok := out["manifest"].(*operations.MultiThreadManifest)
+	require.True(t, ok, "manifest missing from %v", out)
+	assert.Equal(t, "file1", manifest.Remote)
+	assert.Equal(t, int64(100), manifest.Size)
+	assert.Equal(t, "md5", manifest.HashType)
+	require.Len(t, manifest.Chunks, 4)
+	for i, chunk := range manifest.Chunks {
+		assert.Equal(t, int64(i*25), chunk.Offset)
+		assert.Equal(t, int64(25), chunk.Length)
+		assert.Equal(t, fmt.Sprintf("%x", md5.Sum([]byte(contents[i*25:(i+1)*25]))), chunk.Hash)
+	}
+	r.CheckRemoteItems(t, file1)
+
+	// Nothing is returned unless asked for
+	delete(in, "manifest")
+	out, err = call.Fn(ctx, in)
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params(nil), out)
+}
+
 // operations/copyurl: Copy the URL to the object
 func TestRcCopyurl(t *testing.T) {
 	r, call := rcNewRun(t, "operations/copyurl")
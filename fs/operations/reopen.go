@@ -32,6 +32,7 @@ type ReOpen struct {
 	newOffset   int64           // if different to offset, reopen needed
 	maxTries    int             // maximum number of retries
 	tries       int             // number of retries we've had so far in this stream
+	retries     int             // number of times we've reopened after a read error
 	err         error           // if this is set then Read/Close calls will return it
 	opened      bool            // if set then rc is valid and needs closing
 	account     AccountFn       // account for a read
@@ -214,6 +215,7 @@ func (h *ReOpen) Read(p []byte) (n int, err error) {
 			h.err = err
 			if !fserrors.IsNoLowLevelRetryError(err) {
 				fs.Debugf(h.src, "Reopening on read failure after offset %d bytes: retry %d/%d: %v", h.offset, h.tries, h.maxTries, err)
+				h.retries++
 				if h.reopen() == nil {
 					err = nil
 				}
@@ -232,6 +234,14 @@ func (h *ReOpen) Read(p []byte) (n int, err error) {
 	return n, err
 }
 
+// Retries returns the number of times the stream has been reopened
+// because of a read error.
+func (h *ReOpen) Retries() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.retries
+}
+
 // Seek sets the offset for the next Read or Write to offset, interpreted
 // according to whence: SeekStart means relative to the start of the file,
 // SeekCurrent means relative to the current offset, and SeekEnd means relative
@@ -190,6 +190,7 @@ func TestReOpen(t *testing.T) {
 				got, err := io.ReadAll(h)
 				assert.NoError(t, err)
 				assert.Equal(t, expectedRead, got)
+				assert.Equal(t, 3, h.Retries())
 
 				// check close
 				assert.NoError(t, h.Close())
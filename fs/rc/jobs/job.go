@@ -36,9 +36,22 @@ type Job struct {
 	Success   bool      `json:"success"`
 	Duration  float64   `json:"duration"`
 	Output    rc.Params `json:"output"`
+	Paused    bool      `json:"paused"`
 	Stop      func()    `json:"-"`
 	listeners []*func()
 
+	// signalled when Paused is cleared, made on first use
+	resumed *sync.Cond
+
+	// cancel functions of the chunks which can be cancelled with
+	// job/cancelchunk
+	chunkCancels map[chunkKey]*func()
+
+	// events are only recorded once someone is watching them
+	eventsWatched bool
+	eventID       int64
+	events        []Event
+
 	// realErr is the Error before printing it as a string, it's used to return
 	// the real error to the upper application layers while still printing the
 	// string error message.
@@ -102,6 +115,165 @@ func (job *Job) OnFinish(fn func()) func() {
 	return func() { job.removeListener(&fn) }
 }
 
+// maxJobEvents is the maximum number of events kept for each job
+const maxJobEvents = 1000
+
+// Event describes something which happened while a job was running
+type Event struct {
+	ID   int64     `json:"id"`   // increasing ID of the event, starting at 1
+	Time time.Time `json:"time"` // time the event happened
+	Type string    `json:"type"` // type of the event, e.g. "chunk"
+	Data rc.Params `json:"data"` // data for the event
+}
+
+// EventsWatched returns true if anyone is watching the events for
+// this job.
+//
+// Use this to avoid the cost of making events nobody will read.
+func (job *Job) EventsWatched() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.eventsWatched
+}
+
+// AddEvent records an event of eventType with data for the job.
+//
+// The event is discarded unless the events are being watched with
+// job/events. Only the most recent maxJobEvents are kept.
+func (job *Job) AddEvent(eventType string, data rc.Params) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if !job.eventsWatched {
+		return
+	}
+	job.eventID++
+	job.events = append(job.events, Event{
+		ID:   job.eventID,
+		Time: time.Now(),
+		Type: eventType,
+		Data: data,
+	})
+	if len(job.events) > maxJobEvents {
+		job.events = append(job.events[:0], job.events[len(job.events)-maxJobEvents:]...)
+	}
+}
+
+// watchEvents starts watching the events for the job if necessary
+// and returns any events with an ID greater than since
+func (job *Job) watchEvents(since int64) (events []Event) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.eventsWatched = true
+	events = []Event{}
+	for _, event := range job.events {
+		if event.ID > since {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// cond returns the condition variable signalled on resume - call
+// with job.mu held
+func (job *Job) cond() *sync.Cond {
+	if job.resumed == nil {
+		job.resumed = sync.NewCond(&job.mu)
+	}
+	return job.resumed
+}
+
+// Pause marks the job as paused.
+//
+// Work is only paused where it calls WaitIfPaused.
+func (job *Job) Pause() error {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.Finished {
+		return errors.New("job has finished")
+	}
+	job.Paused = true
+	return nil
+}
+
+// Resume clears the paused state of the job, waking anything blocked
+// in WaitIfPaused.
+func (job *Job) Resume() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Paused = false
+	job.cond().Broadcast()
+}
+
+// WaitIfPaused blocks while the job is paused.
+//
+// It returns early with the error from ctx if ctx is cancelled.
+func (job *Job) WaitIfPaused(ctx context.Context) error {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if !job.Paused {
+		return nil
+	}
+	// Wake the Wait below if ctx is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			job.mu.Lock()
+			job.cond().Broadcast()
+			job.mu.Unlock()
+		case <-done:
+		}
+	}()
+	for job.Paused && ctx.Err() == nil {
+		job.cond().Wait()
+	}
+	return ctx.Err()
+}
+
+// chunkKey identifies a chunk of a multi-thread copy
+type chunkKey struct {
+	object string
+	chunk  int
+}
+
+// AddChunkCanceller registers cancel as the way of cancelling chunk
+// of the multi-thread copy of object with job/cancelchunk.
+//
+// Call the returned function to deregister it when the chunk can no
+// longer be cancelled. After it returns cancel won't be called.
+func (job *Job) AddChunkCanceller(object string, chunk int, cancel func()) (remove func()) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.chunkCancels == nil {
+		job.chunkCancels = make(map[chunkKey]*func())
+	}
+	key := chunkKey{object: object, chunk: chunk}
+	job.chunkCancels[key] = &cancel
+	return func() {
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		if job.chunkCancels[key] == &cancel {
+			delete(job.chunkCancels, key)
+		}
+	}
+}
+
+// CancelChunk cancels chunk of the multi-thread copy of object
+// registered with AddChunkCanceller.
+func (job *Job) CancelChunk(object string, chunk int) error {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	key := chunkKey{object: object, chunk: chunk}
+	cancel, ok := job.chunkCancels[key]
+	if !ok {
+		return fmt.Errorf("chunk %d of %q isn't being read", chunk, object)
+	}
+	delete(job.chunkCancels, key)
+	(*cancel)()
+	return nil
+}
+
 // run the job until completion writing the return status
 func (job *Job) run(ctx context.Context, fn rc.Func, in rc.Params) {
 	defer func() {
@@ -396,6 +568,59 @@ func rcJobStatus(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return out, nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:  "job/events",
+		Fn:    rcJobEvents,
+		Title: "Reads the events of the job ID",
+		Help: `Parameters:
+
+- jobid - id of the job (integer).
+- since - only return events with an id greater than this (integer, optional).
+
+Events are only recorded for a job after the first call to job/events
+for it, so call this once when the job starts then poll it passing the
+id of the last event received as since.
+
+Results:
+
+- events - array of events, each with
+    - id - increasing id of the event (integer)
+    - time - time the event happened
+    - type - type of the event, e.g. "chunk"
+    - data - parameters of the event
+
+Multi-thread transfers produce events of type "chunk" with data
+
+- object - name of the object being transferred
+- chunk - number of the chunk starting from 0
+- chunks - total number of chunks
+- state - one of "started", "finished", "failed" or "requeued"
+- bytes - bytes in the chunk
+- error - the error if the state is "failed"
+`,
+	})
+}
+
+// Returns the events of a job.
+func rcJobEvents(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	since, err := in.GetInt64("since")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	out = make(rc.Params)
+	out["events"] = job.watchEvents(since)
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:  "job/list",
@@ -448,6 +673,135 @@ func rcJobStop(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return out, nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:  "job/pause",
+		Fn:    rcJobPause,
+		Title: "Pause the running job",
+		Help: `Parameters:
+
+- jobid - id of the job (integer).
+
+This stops multi-thread copies in the job starting any new chunks.
+Chunks which are already being transferred are allowed to finish.
+Use job/resume to carry on with the remaining chunks.
+
+While the job is paused no connections are held open for the paused
+chunks so --timeout doesn't apply, however --max-duration keeps
+counting and the job will be stopped when it runs out. Some backends
+expire unfinished multipart uploads on the server, so pausing for a
+long time may cause the copy to fail when it resumes.
+
+job/stop can be used to stop a paused job.
+`,
+	})
+}
+
+// Pauses the running job.
+func rcJobPause(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	err = job.Pause()
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	return out, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "job/resume",
+		Fn:    rcJobResume,
+		Title: "Resume the paused job",
+		Help: `Parameters:
+
+- jobid - id of the job (integer).
+
+This resumes a job paused with job/pause.
+`,
+	})
+}
+
+// Resumes the paused job.
+func rcJobResume(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	job.Resume()
+	out = make(rc.Params)
+	return out, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "job/cancelchunk",
+		Fn:    rcJobCancelChunk,
+		Title: "Cancel a chunk of a multi-thread copy and copy it again later",
+		Help: `Parameters:
+
+- jobid - id of the job (integer).
+- object - name of the object being copied, as in the job/events "chunk" events (string).
+- chunk - number of the chunk starting from 0, as in the job/events "chunk" events (integer).
+
+This cancels a chunk of a multi-thread copy which is being read
+slowly, for example from a slow storage node, and queues it to be
+copied again after all the other chunks of the object have been
+dispatched. The rest of the copy carries on.
+
+A chunk can only be cancelled while it is being read from the source
+and before any of it has been written to the destination, as writing
+the same chunk twice could corrupt the destination for some backends.
+If the destination doesn't need the chunks buffered, such as ` + "`local`" + `,
+the chunks are written as they are read so they can only be cancelled
+while the source is being opened. An error is returned if the chunk
+isn't in progress or has started being written.
+
+The data read for a cancelled chunk is read again so it is counted
+twice in the stats. A job/events "chunk" event with state "requeued"
+is produced for the chunk. Chunks can be cancelled again when they
+are retried.
+`,
+	})
+}
+
+// Cancels a chunk of a multi-thread copy in the job.
+func rcJobCancelChunk(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	object, err := in.GetString("object")
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := in.GetInt64("chunk")
+	if err != nil {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	err = job.CancelChunk(object, int(chunk))
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:  "job/stopgroup",
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -365,6 +366,182 @@ func TestRcJobStatus(t *testing.T) {
 	assert.Contains(t, err.Error(), "Didn't find key")
 }
 
+func TestJobEvents(t *testing.T) {
+	job := &Job{}
+	assert.False(t, job.EventsWatched())
+	job.AddEvent("test", rc.Params{"n": 0})
+	assert.Equal(t, []Event{}, job.watchEvents(0))
+
+	assert.True(t, job.EventsWatched())
+	for i := 1; i <= maxJobEvents+10; i++ {
+		job.AddEvent("test", rc.Params{"n": i})
+	}
+	events := job.watchEvents(0)
+	require.Len(t, events, maxJobEvents)
+	assert.Equal(t, int64(11), events[0].ID)
+	assert.Equal(t, "test", events[0].Type)
+	assert.Equal(t, rc.Params{"n": 11}, events[0].Data)
+
+	events = job.watchEvents(maxJobEvents + 8)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(maxJobEvents+9), events[0].ID)
+	assert.Equal(t, int64(maxJobEvents+10), events[1].ID)
+}
+
+func TestRcJobEvents(t *testing.T) {
+	ctx := context.Background()
+	jobID.Store(0)
+	job, _, err := NewJob(ctx, longFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+
+	call := rc.Calls.Get("job/events")
+	require.NotNil(t, call)
+	out, err := call.Fn(context.Background(), rc.Params{"jobid": 1})
+	require.NoError(t, err)
+	assert.Equal(t, []Event{}, out["events"])
+
+	job.AddEvent("test", rc.Params{"potato": "jersey"})
+	out, err = call.Fn(context.Background(), rc.Params{"jobid": 1})
+	require.NoError(t, err)
+	events := out["events"].([]Event)
+	require.Len(t, events, 1)
+	assert.Equal(t, "test", events[0].Type)
+
+	out, err = call.Fn(context.Background(), rc.Params{"jobid": 1, "since": events[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, []Event{}, out["events"])
+
+	_, err = call.Fn(context.Background(), rc.Params{"jobid": 123123123})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "job not found")
+}
+
+func TestJobPause(t *testing.T) {
+	ctx := context.Background()
+	job := &Job{}
+
+	// Not paused so doesn't block
+	require.NoError(t, job.WaitIfPaused(ctx))
+
+	require.NoError(t, job.Pause())
+	assert.True(t, job.Paused)
+	var waited atomic.Bool
+	done := make(chan error)
+	go func() {
+		err := job.WaitIfPaused(ctx)
+		waited.Store(true)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, waited.Load())
+	job.Resume()
+	assert.False(t, job.Paused)
+	require.NoError(t, <-done)
+
+	// Cancelling the context stops the wait
+	require.NoError(t, job.Pause())
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		done <- job.WaitIfPaused(cancelCtx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// Can't pause a finished job
+	job.Finished = true
+	assert.Error(t, job.Pause())
+}
+
+func TestRcJobPause(t *testing.T) {
+	ctx := context.Background()
+	jobID.Store(0)
+	job, _, err := NewJob(ctx, longFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+
+	pause := rc.Calls.Get("job/pause")
+	require.NotNil(t, pause)
+	resume := rc.Calls.Get("job/resume")
+	require.NotNil(t, resume)
+
+	_, err = pause.Fn(context.Background(), rc.Params{"jobid": 1})
+	require.NoError(t, err)
+	out, err := rc.Calls.Get("job/status").Fn(context.Background(), rc.Params{"jobid": 1})
+	require.NoError(t, err)
+	assert.Equal(t, true, out["paused"])
+
+	_, err = resume.Fn(context.Background(), rc.Params{"jobid": 1})
+	require.NoError(t, err)
+	job.mu.Lock()
+	assert.False(t, job.Paused)
+	job.mu.Unlock()
+
+	for _, call := range []*rc.Call{pause, resume} {
+		_, err = call.Fn(context.Background(), rc.Params{"jobid": 123123123})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "job not found")
+	}
+}
+
+func TestJobCancelChunk(t *testing.T) {
+	job := &Job{}
+	var cancelled int
+	remove := job.AddChunkCanceller("file.txt", 1, func() { cancelled++ })
+
+	assert.Error(t, job.CancelChunk("file.txt", 2))
+	assert.Error(t, job.CancelChunk("other.txt", 1))
+	assert.Equal(t, 0, cancelled)
+
+	require.NoError(t, job.CancelChunk("file.txt", 1))
+	assert.Equal(t, 1, cancelled)
+
+	// Only cancelled once
+	assert.Error(t, job.CancelChunk("file.txt", 1))
+	assert.Equal(t, 1, cancelled)
+
+	// A stale remove doesn't remove a new registration
+	remove2 := job.AddChunkCanceller("file.txt", 1, func() { cancelled++ })
+	remove()
+	require.NoError(t, job.CancelChunk("file.txt", 1))
+	assert.Equal(t, 2, cancelled)
+
+	// Can't cancel once removed
+	job.AddChunkCanceller("file.txt", 1, func() { cancelled++ })
+	remove2()
+	remove3 := job.AddChunkCanceller("file.txt", 1, func() { cancelled++ })
+	remove3()
+	assert.Error(t, job.CancelChunk("file.txt", 1))
+	assert.Equal(t, 2, cancelled)
+}
+
+func TestRcJobCancelChunk(t *testing.T) {
+	ctx := context.Background()
+	jobID.Store(0)
+	job, _, err := NewJob(ctx, longFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+	var cancelled atomic.Bool
+	job.AddChunkCanceller("file.txt", 3, func() { cancelled.Store(true) })
+
+	call := rc.Calls.Get("job/cancelchunk")
+	require.NotNil(t, call)
+
+	_, err = call.Fn(context.Background(), rc.Params{"jobid": 1, "object": "file.txt", "chunk": 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't being read")
+	assert.False(t, cancelled.Load())
+
+	_, err = call.Fn(context.Background(), rc.Params{"jobid": 1, "object": "file.txt", "chunk": 3})
+	require.NoError(t, err)
+	assert.True(t, cancelled.Load())
+
+	_, err = call.Fn(context.Background(), rc.Params{"jobid": 1, "object": "file.txt"})
+	require.Error(t, err)
+
+	_, err = call.Fn(context.Background(), rc.Params{"jobid": 123123123, "object": "file.txt", "chunk": 3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "job not found")
+}
+
 func TestRcJobList(t *testing.T) {
 	ctx := context.Background()
 	jobID.Store(0)
@@ -926,6 +926,13 @@ func (s *syncCopyMove) run() error {
 		return nil
 	}
 
+	// Report what is left of an interrupted resumable copy
+	if s.ci.MultiThreadResume {
+		if completed, interrupted := operations.MultiThreadResumeSummary(s.fdst); completed+interrupted > 0 {
+			fs.Infof(s.fdst, "Resuming multi-thread copy: found %d completed and %d interrupted records", completed, interrupted)
+		}
+	}
+
 	// Start background checking and transferring pipeline
 	s.startCheckers()
 	s.startRenamers()
@@ -1019,6 +1026,11 @@ func (s *syncCopyMove) run() error {
 		fs.Infof(nil, "There was nothing to transfer")
 	}
 
+	// The copy is complete so it won't need resuming
+	if s.ci.MultiThreadResume && s.currentError() == nil {
+		operations.RemoveMultiThreadResumeDir(s.fdst)
+	}
+
 	// cancel the contexts to free resources
 	s.inCancel()
 	s.cancel()
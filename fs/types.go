@@ -209,6 +209,32 @@ type SetMetadataer interface {
 	SetMetadata(ctx context.Context, metadata Metadata) error
 }
 
+// OpenReaderAter is an optional interface for Object
+type OpenReaderAter interface {
+	// OpenReaderAt opens the Object for random reads with ReadAt
+	//
+	// It should return fs.ErrorNotImplemented if the object can't
+	// be read this way.
+	OpenReaderAt(ctx context.Context) (ReaderAtCloser, error)
+}
+
+// ReopenWriterAter is an optional interface for Object
+type ReopenWriterAter interface {
+	// ReopenWriterAt opens the existing Object for random writes
+	// with WriteAt without truncating it or changing its size
+	//
+	// It should return fs.ErrorNotImplemented if the object can't
+	// be written this way.
+	ReopenWriterAt(ctx context.Context) (WriterAtCloser, error)
+}
+
+// RangeHasher is an optional interface for Object
+type RangeHasher interface {
+	// RangeHash returns the checksum of type ty of the bytes from
+	// start to end inclusive of the Object, or "" if it isn't known
+	RangeHash(ctx context.Context, ty hash.Type, start, end int64) (string, error)
+}
+
 // SetModTimer is an optional interface for Directory.
 //
 // Object implements this as part of its requires set of interfaces.
@@ -337,6 +363,12 @@ type WriterAtCloser interface {
 	io.Closer
 }
 
+// ReaderAtCloser wraps ReadAt and Close
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
 type unknownFs struct{}
 
 // Name of the remote (as passed into NewFs)
@@ -0,0 +1,7 @@
+package file
+
+import "errors"
+
+// ErrCopyFileRangeUnsupported is returned from CopyFileRange if the
+// kernel or the filesystems don't support copying the data this way
+var ErrCopyFileRangeUnsupported = errors.New("copy_file_range: not supported")
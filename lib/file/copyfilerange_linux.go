@@ -0,0 +1,36 @@
+//go:build linux
+
+package file
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// CopyFileRangeImplemented is a constant indicating whether the
+// implementation of CopyFileRange actually does anything.
+const CopyFileRangeImplemented = true
+
+// CopyFileRange copies up to size bytes from in at offset to out at
+// the same offset in the kernel without the data passing through
+// user space.
+//
+// It returns the number of bytes copied which may be less than size
+// at the end of in. If the kernel or the filesystems don't support
+// this it returns ErrCopyFileRangeUnsupported.
+func CopyFileRange(out, in *os.File, offset int64, size int) (n int, err error) {
+	inOffset, outOffset := offset, offset
+	for {
+		n, err = unix.CopyFileRange(int(in.Fd()), &inOffset, int(out.Fd()), &outOffset, size, 0)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	switch err {
+	case unix.ENOSYS, unix.EXDEV, unix.EINVAL, unix.EOPNOTSUPP, unix.EPERM:
+		return 0, ErrCopyFileRangeUnsupported
+	}
+	return n, err
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package file
+
+import "os"
+
+// CopyFileRangeImplemented is a constant indicating whether the
+// implementation of CopyFileRange actually does anything.
+const CopyFileRangeImplemented = false
+
+// CopyFileRange copies data between files in the kernel - it isn't
+// supported on this OS so it always returns
+// ErrCopyFileRangeUnsupported.
+func CopyFileRange(out, in *os.File, offset int64, size int) (n int, err error) {
+	return 0, ErrCopyFileRangeUnsupported
+}
@@ -28,3 +28,8 @@ func (td *TokenDispenser) Get() {
 func (td *TokenDispenser) Put() {
 	td.tokens <- struct{}{}
 }
+
+// Free returns the number of tokens which can be got without waiting
+func (td *TokenDispenser) Free() int {
+	return len(td.tokens)
+}
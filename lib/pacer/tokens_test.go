@@ -9,8 +9,10 @@ import (
 func TestTokenDispenser(t *testing.T) {
 	td := NewTokenDispenser(5)
 	assert.Equal(t, 5, len(td.tokens))
+	assert.Equal(t, 5, td.Free())
 	td.Get()
 	assert.Equal(t, 4, len(td.tokens))
+	assert.Equal(t, 4, td.Free())
 	td.Put()
 	assert.Equal(t, 5, len(td.tokens))
 }